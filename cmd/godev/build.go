@@ -168,8 +168,10 @@ func compress(from, to, name string) {
 func compressZip(fi os.FileInfo, dst io.Writer, name string) (io.Writer, func()) {
 	zw := zip.NewWriter(dst)
 	h, err := zip.FileInfoHeader(fi)
-	h.Name = name
 	utils.E(err)
+	h.Name = name
+	// the binary built on Windows doesn't have the exec bits
+	h.SetMode(gos.ExecMode(fi.Mode(), true))
 	w, err := zw.CreateHeader(h)
 	utils.E(err)
 	return w, func() {
@@ -182,8 +184,9 @@ func compressGz(fi os.FileInfo, dst io.Writer, name string) (io.Writer, func())
 	tw := tar.NewWriter(gw)
 
 	h, err := tar.FileInfoHeader(fi, "")
-	h.Name = name
 	utils.E(err)
+	h.Name = name
+	h.Mode = int64(gos.ExecMode(fi.Mode().Perm(), true))
 	utils.E(tw.WriteHeader(h))
 
 	return tw, func() {
@@ -24,7 +24,7 @@ func main() {
 	}()
 
 	app := run.TasksNew("godev", "dev tool for common go project")
-	app.Version(utils.Version)
+	app.Version(utils.BuildInfo().String())
 
 	covPath = app.Flag("cov-path", "path for coverage output").Default("coverage.txt").String()
 
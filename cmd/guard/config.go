@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+
+	"github.com/ysmood/kit"
+	"gopkg.in/yaml.v3"
+)
+
+// the effective settings of a guard section after all the args are merged
+type config struct {
+	Command      []string `json:"command" yaml:"command"`
+	Dir          string   `json:"dir" yaml:"dir"`
+	Patterns     []string `json:"patterns" yaml:"patterns"`
+	WatchedFiles int      `json:"watchedFiles" yaml:"watchedFiles"`
+	Prefix       string   `json:"prefix" yaml:"prefix"`
+	Preset       string   `json:"preset,omitempty" yaml:"preset,omitempty"`
+	Encoding     string   `json:"outputEncoding,omitempty" yaml:"outputEncoding,omitempty"`
+	ClearScreen  bool     `json:"clearScreen" yaml:"clearScreen"`
+	ClearMode    string   `json:"clearMode,omitempty" yaml:"clearMode,omitempty"`
+	NoInitRun    bool     `json:"noInitRun" yaml:"noInitRun"`
+	Raw          bool     `json:"raw" yaml:"raw"`
+	TUI          bool     `json:"tui,omitempty" yaml:"tui,omitempty"`
+	SyncLines    bool     `json:"syncLines,omitempty" yaml:"syncLines,omitempty"`
+	Pane         bool     `json:"pane,omitempty" yaml:"pane,omitempty"`
+	Tail         int      `json:"tail,omitempty" yaml:"tail,omitempty"`
+	Container    bool     `json:"container" yaml:"container"`
+	SameDevice   bool     `json:"sameDevice,omitempty" yaml:"sameDevice,omitempty"`
+	FollowLinks  bool     `json:"followSymlinks,omitempty" yaml:"followSymlinks,omitempty"`
+	Poll         string   `json:"poll" yaml:"poll"`
+	Debounce     string   `json:"debounce" yaml:"debounce"`
+	Grace        string   `json:"grace,omitempty" yaml:"grace,omitempty"`
+	Batch        string   `json:"batch,omitempty" yaml:"batch,omitempty"`
+	TypingIdle   string   `json:"typingIdle,omitempty" yaml:"typingIdle,omitempty"`
+	Restart      string   `json:"restartOnExit,omitempty" yaml:"restartOnExit,omitempty"`
+	ExecDelay    string   `json:"execDelay,omitempty" yaml:"execDelay,omitempty"`
+	Queue        string   `json:"queue" yaml:"queue"`
+	After        []int    `json:"after,omitempty" yaml:"after,omitempty"`
+	NoKill       bool     `json:"noKill" yaml:"noKill"`
+	Concurrency  int      `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	MaxRuns      int      `json:"maxRuns,omitempty" yaml:"maxRuns,omitempty"`
+	MaxFailures  int      `json:"maxFailures,omitempty" yaml:"maxFailures,omitempty"`
+	Webhook      string   `json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	Profile      bool     `json:"profile,omitempty" yaml:"profile,omitempty"`
+	JSONLog      bool     `json:"jsonLog,omitempty" yaml:"jsonLog,omitempty"`
+	Xargs        bool     `json:"xargs,omitempty" yaml:"xargs,omitempty"`
+	DryRun       bool     `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+	NoGoWork     bool     `json:"noGoWork,omitempty" yaml:"noGoWork,omitempty"`
+	XargsMax     int      `json:"xargsMax,omitempty" yaml:"xargsMax,omitempty"`
+	MaxFiles     int      `json:"maxFiles,omitempty" yaml:"maxFiles,omitempty"`
+	PreSteps     []string `json:"preSteps,omitempty" yaml:"preSteps,omitempty"`
+	Steps        []string `json:"steps,omitempty" yaml:"steps,omitempty"`
+	Routes       []string `json:"routes,omitempty" yaml:"routes,omitempty"`
+	StopSignal   string   `json:"stopSignal,omitempty" yaml:"stopSignal,omitempty"`
+	KillTimeout  string   `json:"killTimeout,omitempty" yaml:"killTimeout,omitempty"`
+	Forward      []string `json:"forwardSignals,omitempty" yaml:"forwardSignals,omitempty"`
+	Priority     int      `json:"priority,omitempty" yaml:"priority,omitempty"`
+	FlakyReport  string   `json:"flakyReport,omitempty" yaml:"flakyReport,omitempty"`
+	HTTPTrigger  string   `json:"httpTrigger,omitempty" yaml:"httpTrigger,omitempty"`
+	LiveReload   string   `json:"liveReload,omitempty" yaml:"liveReload,omitempty"`
+	ReloadCSS    []string `json:"liveReloadCss,omitempty" yaml:"liveReloadCss,omitempty"`
+	LogFile      string   `json:"logFile,omitempty" yaml:"logFile,omitempty"`
+	LogMaxSize   string   `json:"logMaxSize,omitempty" yaml:"logMaxSize,omitempty"`
+	LogRotate    string   `json:"logRotate,omitempty" yaml:"logRotate,omitempty"`
+	LogBackups   int      `json:"logBackups,omitempty" yaml:"logBackups,omitempty"`
+	Summary      string   `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Every        string   `json:"every,omitempty" yaml:"every,omitempty"`
+	Cron         string   `json:"cron,omitempty" yaml:"cron,omitempty"`
+}
+
+func genConfig(opts *options) *config {
+	patterns := filterEmpty(*opts.patterns)
+	if len(patterns) == 0 {
+		patterns = kit.GuardDefaultPatterns()
+	}
+
+	walk := kit.Walk().Matcher(kit.NewMatcher(*opts.dir, patterns).IgnoreFile(kit.GuardIgnoreFile))
+	if *opts.sameDevice {
+		walk.SameDevice()
+	}
+	if *opts.followLinks {
+		walk.FollowSymbolicLinks()
+	}
+	list, _ := walk.List()
+
+	conf := &config{
+		Command:      opts.cmd,
+		Dir:          *opts.dir,
+		Patterns:     patterns,
+		WatchedFiles: len(list),
+		Prefix:       *opts.prefix,
+		Preset:       *opts.preset,
+		Encoding:     *opts.encoding,
+		ClearScreen:  *opts.clearScreen || *opts.clearMode != "",
+		ClearMode:    *opts.clearMode,
+		NoInitRun:    *opts.noInitRun,
+		Raw:          !*opts.tui, // the same as genGuard
+		TUI:          *opts.tui,
+		SyncLines:    *opts.syncLines || *opts.pane, // the pane implies it
+		Pane:         *opts.pane,
+		Tail:         tailLines(opts),
+		Summary:      *opts.summary,
+		Container:    *opts.container,
+		SameDevice:   *opts.sameDevice,
+		FollowLinks:  *opts.followLinks,
+		Poll:         opts.poll.String(),
+		Debounce:     opts.debounce.String(),
+		Cron:         *opts.cron,
+		NoKill:       *opts.noKill,
+		MaxRuns:      *opts.maxRuns,
+		MaxFailures:  *opts.maxFailures,
+		Webhook:      *opts.webhook,
+		Profile:      *opts.profile,
+		JSONLog:      *opts.jsonLog,
+		Xargs:        *opts.xargs,
+		DryRun:       *opts.dryRun,
+		NoGoWork:     *opts.noGoWork,
+		XargsMax:     *opts.xargsMax,
+		MaxFiles:     *opts.maxFiles,
+		PreSteps:     filterEmpty(*opts.preSteps),
+		Steps:        filterEmpty(*opts.steps),
+		Routes:       filterEmpty(*opts.routes),
+		StopSignal:   *opts.stopSignal,
+		Forward:      *opts.forward,
+		Priority:     *opts.priority,
+		HTTPTrigger:  *opts.httpTrigger,
+		LiveReload:   *opts.liveReload,
+		ReloadCSS:    filterEmpty(*opts.reloadCSS),
+		After:        *opts.after,
+		Queue:        *opts.queue,
+	}
+
+	if *opts.noKill {
+		conf.Concurrency = *opts.concurrency
+		if conf.Concurrency < 1 {
+			conf.Concurrency = runtime.NumCPU()
+		}
+	}
+
+	if *opts.grace > 0 {
+		conf.Grace = opts.grace.String()
+	}
+
+	if *opts.killTimeout > 0 {
+		conf.KillTimeout = opts.killTimeout.String()
+	}
+
+	if *opts.batch > 0 {
+		conf.Batch = opts.batch.String()
+	}
+
+	if *opts.typingIdle > 0 {
+		conf.TypingIdle = opts.typingIdle.String()
+	}
+
+	if *opts.logDir != "" {
+		conf.LogFile = logPath(opts)
+		conf.LogMaxSize = opts.logMaxSize.String()
+		conf.LogBackups = *opts.logBackups
+		if *opts.logRotate > 0 {
+			conf.LogRotate = opts.logRotate.String()
+		}
+	}
+
+	if *opts.restart > 0 {
+		conf.Restart = opts.restart.String()
+	}
+
+	if *opts.execDelay > 0 {
+		conf.ExecDelay = opts.execDelay.String()
+	}
+
+	if *opts.flakyReport > 0 {
+		conf.FlakyReport = opts.flakyReport.String()
+	}
+
+	if *opts.every > 0 {
+		conf.Every = opts.every.String()
+	}
+
+	return conf
+}
+
+func printConfig(format string, optsList []*options) {
+	list := []*config{}
+	for _, opts := range optsList {
+		list = append(list, genConfig(opts))
+	}
+
+	var out []byte
+	var err error
+	if format == "json" {
+		out, err = json.MarshalIndent(list, "", "  ")
+		out = append(out, '\n')
+	} else {
+		out, err = yaml.Marshal(list)
+	}
+	kit.E(err)
+
+	fmt.Print(string(out))
+}
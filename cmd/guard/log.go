@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ysmood/kit"
+)
+
+// the file of the section in the --log-dir, such as "go-test-1a2b3c4d.log",
+// the hash keeps the sections with the same command apart
+func logPath(opts *options) string {
+	h := fnv.New32a()
+	kit.E(h.Write([]byte(strings.Join(append(append([]string{*opts.dir}, opts.cmd...), *opts.patterns...), "\x00"))))
+
+	name := strings.Trim(logNameReg.ReplaceAllString(strings.Join(opts.cmd, "-"), "-"), "-")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+
+	return filepath.Join(*opts.logDir, fmt.Sprintf("%s-%08x.log", name, h.Sum32()))
+}
+
+var logNameReg = regexp.MustCompile(`[^\w.]+`)
+
+// the rotating file of the section, it's nil if --log-dir isn't set
+func genLogFile(opts *options) io.Writer {
+	if *opts.logDir == "" {
+		return nil
+	}
+
+	f := kit.RotateFile(logPath(opts)).
+		MaxSize(int64(*opts.logMaxSize)).
+		Interval(*opts.logRotate).
+		Backups(*opts.logBackups)
+
+	return plainLog{f}
+}
+
+// the colors are removed, so the files are greppable
+type plainLog struct {
+	w io.Writer
+}
+
+func (l plainLog) Write(p []byte) (int, error) {
+	_, err := l.w.Write([]byte(kit.StripANSI(string(p))))
+	return len(p), err
+}
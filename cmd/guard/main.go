@@ -3,12 +3,16 @@ package main
 import (
 	"fmt"
 	"hash/fnv"
+	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 	"github.com/ysmood/kit"
 )
 
@@ -18,49 +22,367 @@ type options struct {
 	cmd         []string
 	prefix      *string
 	clearScreen *bool
+	clearMode   *string
 	noInitRun   *bool
 	raw         *bool
 	poll        *time.Duration
 	debounce    *time.Duration
+	grace       *time.Duration
+	typingIdle  *time.Duration
+	restart     *time.Duration
+	execDelay   *time.Duration
+	queue       *string
+	after       *[]int
+	httpTrigger *string
+	liveReload  *string
+	dryRun      *bool
+	noGoWork    *bool
+	reloadCSS   *[]string
+	batch       *time.Duration
+	noKill      *bool
+	concurrency *int
+	maxRuns     *int
+	maxFailures *int
+	webhook     *string
+	profile     *bool
+	jsonLog     *bool
+	xargs       *bool
+	xargsMax    *int
+	maxFiles    *int
+	forward     *[]string
+	stopSignal  *string
+	killTimeout *time.Duration
+	preSteps    *[]string
+	steps       *[]string
+	routes      *[]string
+	priority    *int
+	every       *time.Duration
+	cron        *string
+	printConfig *string
+	explain     *bool
+	container   *bool
+	sameDevice  *bool
+	followLinks *bool
+	summary     *string
+	flakyReport *time.Duration
+	syncLines   *bool
+	encoding    *string
+	tail        *int
+	preset      *string
+	pane        *bool
+	tui         *bool
+	logDir      *string
+	logMaxSize  *units.Base2Bytes
+	logRotate   *time.Duration
+	logBackups  *int
+
+	logFile io.Writer // the rotating file in the --log-dir
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "test-pattern" {
+		testPattern(os.Args[2:])
+		return
+	}
+
 	optsList := []*options{}
 	for _, args := range split(argsFromConfigFile(os.Args[1:]), "---") {
 		optsList = append(optsList, genOptions(args))
 	}
 
-	fns := []func(){}
+	// the dashboard is shared by all the sections
+	for _, opts := range optsList {
+		if *opts.tui {
+			for _, o := range optsList {
+				*o.tui = true
+			}
+			break
+		}
+	}
+
+	for _, opts := range optsList {
+		if *opts.printConfig != "" {
+			printConfig(*opts.printConfig, optsList)
+			return
+		}
+	}
+
+	limiter := genLimiter(optsList)
+
+	guards := []*kit.GuardContext{}
+	for _, opts := range optsList {
+		guard := genGuard(opts)
+		if limiter != nil {
+			guard.Limiter(limiter, *opts.priority)
+		}
+		guards = append(guards, guard)
+	}
+
+	orderSections(optsList, guards)
+
 	for _, opts := range optsList {
-		fns = append(fns, func(opts *options) func() {
-			return func() {
-				guard :=
-					kit.Guard(opts.cmd...).
-						Patterns(filterEmpty(*opts.patterns)...).
-						Debounce(opts.debounce).
-						Interval(opts.poll).
-						ExecCtx(
-							kit.Exec().
-								Dir(*opts.dir).
-								Raw().
-								Prefix(genPrefix(*opts.prefix, opts.cmd)),
-						)
-
-				if *opts.clearScreen {
-					guard.ClearScreen()
-				}
-
-				if *opts.noInitRun {
-					guard.NoInitRun()
-				}
-
-				guard.MustDo()
+		if *opts.explain {
+			for _, g := range guards {
+				fmt.Println(g.MustExplain())
 			}
-		}(opts))
+			return
+		}
+	}
+
+	go watchReload(guards)
+
+	for i, opts := range optsList {
+		if *opts.flakyReport > 0 {
+			go reportFlaky(opts, guards[i])
+		}
+	}
+
+	if *optsList[0].tui {
+		runTUI(optsList, guards)
+		return
+	}
+
+	if sigs := exitSignals(optsList); len(sigs) > 0 {
+		go func() {
+			kit.WaitSignal(sigs...)
+			printSummary(optsList, guards)
+			os.Exit(0)
+		}()
+	}
+
+	fns := []func(){}
+	for _, guard := range guards {
+		fns = append(fns, guard.MustDo)
 	}
 	kit.All(fns...)()
 }
 
+// the initial run of a section waits for the sections set by its --after
+func orderSections(optsList []*options, guards []*kit.GuardContext) {
+	for i, opts := range optsList {
+		for _, n := range *opts.after {
+			if n < 1 || n > len(guards) || n == i+1 {
+				panic(fmt.Sprintf("invalid --after %d of the section %d, there are %d sections", n, i+1, len(guards)))
+			}
+			guards[i].After(guards[n-1])
+		}
+	}
+}
+
+// the sections share the cap of concurrent runs, setting the priority alone serializes the sections
+func genLimiter(optsList []*options) *kit.GuardLimiter {
+	limit := 0
+	enabled := false
+	for _, opts := range optsList {
+		if *opts.maxRuns > 0 {
+			limit = *opts.maxRuns
+			enabled = true
+		}
+		if *opts.priority != 0 {
+			enabled = true
+		}
+	}
+
+	if !enabled {
+		return nil
+	}
+	if limit == 0 {
+		limit = 1
+	}
+	return kit.NewGuardLimiter(limit)
+}
+
+// the lines of the output to show when the guard is paused by --max-failures
+const maxFailuresTail = 20
+
+// the lines of the output to keep, the --max-failures and --webhook need some of them by default
+func tailLines(opts *options) int {
+	if *opts.tail > 0 {
+		return *opts.tail
+	} else if *opts.maxFailures > 0 {
+		return maxFailuresTail
+	} else if *opts.webhook != "" {
+		return webhookTail
+	}
+	return 0
+}
+
+func genGuard(opts *options) *kit.GuardContext {
+	execCtx := kit.Exec().
+		Dir(*opts.dir).
+		Prefix(genPrefix(*opts.prefix, opts.cmd)).
+		AutoCI(kit.Stdout) // the --log-dir wraps the stdout
+
+	// the dashboard owns the terminal
+	if !*opts.tui {
+		execCtx.Raw()
+	}
+
+	if *opts.syncLines {
+		execCtx.SyncLines()
+	}
+
+	if *opts.encoding != "" {
+		execCtx.OutputEncoding(*opts.encoding)
+	}
+
+	if *opts.profile {
+		execCtx.Profile()
+	}
+
+	if len(*opts.forward) > 0 {
+		execCtx.ForwardSignals(signalsOf(*opts.forward)...)
+	}
+
+	if n := tailLines(opts); n > 0 {
+		execCtx.Tail(n)
+	}
+
+	if *opts.pane {
+		execCtx.PaneSeparator()
+	}
+
+	guard :=
+		kit.Guard(opts.cmd...).
+			Patterns(filterEmpty(*opts.patterns)...).
+			Debounce(opts.debounce).
+			Interval(opts.poll).
+			ExecCtx(execCtx)
+
+	if opts.logFile = genLogFile(opts); opts.logFile != nil {
+		guard.Stdout(io.MultiWriter(kit.Stdout, opts.logFile))
+	}
+
+	if *opts.clearMode != "" {
+		mode, err := kit.ParseClearMode(*opts.clearMode)
+		kit.E(err)
+		guard.ClearMode(mode)
+	} else if *opts.clearScreen {
+		guard.ClearScreen()
+	}
+
+	if *opts.noInitRun {
+		guard.NoInitRun()
+	}
+
+	if *opts.container {
+		guard.Container()
+	}
+
+	if *opts.sameDevice {
+		guard.SameDevice()
+	}
+
+	if *opts.followLinks {
+		guard.FollowSymlinks()
+	}
+
+	if *opts.maxFailures > 0 {
+		guard.MaxFailures(*opts.maxFailures)
+	}
+
+	if *opts.webhook != "" {
+		guard.OnDone(webhook(*opts.webhook, opts.cmd))
+	}
+
+	for _, step := range filterEmpty(*opts.preSteps) {
+		pattern, args := parsePatternCommand("--pre-step", step)
+		guard.PreStep(args, strings.Split(pattern, ";")...)
+	}
+
+	for _, route := range filterEmpty(*opts.routes) {
+		pattern, args := parsePatternCommand("--route", route)
+		guard.Route(pattern, kit.Exec(args...))
+	}
+
+	if steps := filterEmpty(*opts.steps); len(steps) > 0 {
+		list := []*kit.ExecContext{}
+		for _, step := range steps {
+			args, err := kit.SplitCommand(step)
+			kit.E(err)
+			list = append(list, kit.Exec(args...))
+		}
+		guard.Steps(append(list, execCtx.Args(opts.cmd))...)
+	}
+
+	if *opts.noKill {
+		guard.NoKill(*opts.concurrency)
+	}
+
+	if *opts.grace > 0 {
+		guard.StartupGrace(*opts.grace)
+	}
+
+	if *opts.stopSignal != "" {
+		guard.StopSignal(signals[*opts.stopSignal])
+	}
+
+	if *opts.killTimeout > 0 {
+		guard.KillTimeout(*opts.killTimeout)
+	}
+
+	if *opts.batch > 0 {
+		guard.Batch(*opts.batch)
+	}
+
+	if *opts.typingIdle > 0 {
+		guard.TypingIdle(*opts.typingIdle)
+	}
+
+	if *opts.restart > 0 {
+		guard.RestartOnExit(*opts.restart)
+	}
+
+	if *opts.execDelay > 0 {
+		guard.ExecDelay(*opts.execDelay)
+	}
+
+	if *opts.jsonLog {
+		guard.JSONLog()
+	}
+
+	if *opts.xargs {
+		guard.Xargs(*opts.xargsMax)
+	}
+
+	if *opts.dryRun {
+		guard.DryRun()
+	}
+
+	if *opts.noGoWork {
+		guard.NoGoWork()
+	}
+
+	if *opts.maxFiles > 0 {
+		guard.MaxFiles(*opts.maxFiles, nil)
+	}
+
+	switch *opts.queue {
+	case "one":
+		guard.Queue(kit.GuardQueueOne)
+	case "drop":
+		guard.Queue(kit.GuardQueueDrop)
+	}
+
+	if *opts.httpTrigger != "" {
+		guard.HTTPTrigger(*opts.httpTrigger)
+	}
+
+	if *opts.liveReload != "" {
+		guard.LiveReload(*opts.liveReload, filterEmpty(*opts.reloadCSS)...)
+	}
+
+	if *opts.every > 0 {
+		guard.Every(*opts.every)
+	}
+
+	if *opts.cron != "" {
+		guard.Cron(*opts.cron)
+	}
+
+	return guard
+}
+
 func genOptions(args []string) *options {
 	opts := &options{}
 
@@ -81,19 +403,106 @@ func genOptions(args []string) *options {
 		 # the below is the default patterns guard will use
 		 guard -w '**' -w '!g' -- echo changed
 
+		 # the .guardignore files in the watched dirs use the gitignore syntax,
+		 # they are merged with the patterns, so the exclusions can be committed with the code
+		 echo 'tmp/' > .guardignore && guard -- go test ./...
+
 		 # support go template
 		 guard -- echo {{op}} {{path}} {{file}}
 
+		 # lint the files changed within 500ms at once, {{paths}} expands to one arg per file
+		 guard --batch 500ms -w '**/*.js' -- eslint {{paths}}
+
+		 # format exactly the files that changed, they are appended to the args like xargs
+		 guard --xargs --batch 500ms -w '**/*.js' -- prettier --write
+
+		 # {{runDir}} is a fresh temp dir for each run, the output won't retrigger the run
+		 guard -- go test -coverprofile {{runDir}}/cover.out ./...
+
 		 # watch and sync current dir to another machine
 		 guard -n -- rsync {{path}} root@host:/home/me/app/{{path}}
+
+		 # {{dir}}, {{base}}, {{ext}} are the parts of {{path}}, {{env.NAME}} is an env variable
+		 guard -n -w 'assets/**' -- docker cp {{path}} '{{env.CONTAINER}}:/srv/{{dir}}/{{timestamp}}{{ext}}'
 		 guard -n -- docker cp {{path}} my-container:/app/{{path}}
 
+		 # preview the rendered commands for the changes without executing them
+		 guard --dry-run -n -- rsync {{path}} root@host:/home/me/app/{{path}}
+
+		 # in a go workspace, the member modules of the go.work outside the dir are also watched
+		 guard --no-go-work -- go run ./cmd/server
+
 		 # the patterns must be quoted
 		 guard -w '*.go' -w 'lib/**/*.go' -- go run main.go
 
 		 # the output will be prefix with red 'my-app | '
 		 guard -p 'my-app | @red' -- python test.py
 
+		 # let the editor rerun the tests by "curl -X POST -H X-Guard-Trigger:1 localhost:7000"
+		 guard --http-trigger localhost:7000 -- go test ./...
+
+		 # inject the changed css into the page without restarting the server, reload the page for other changes,
+		 # add <script src="http://localhost:35729/livereload.js"></script> to the page
+		 guard --live-reload localhost:35729 -- go run ./server
+
+		 # also rerun the command every night at 3am
+		 guard --cron '0 3 * * *' -- go generate ./api
+
+		 # check why a file is watched or ignored, see "guard test-pattern --help"
+		 guard test-pattern '**' -w '!g' main.go tmp/a.log
+
+		 # keep the history of each section in rotating files, such as "logs/make-api-1a2b3c4d.log"
+		 guard --log-dir logs -w 'api/**' -- make api --- --log-dir logs -w 'web/**' -- make web
+
+		 # print the merged settings of all sections for troubleshooting
+		 guard @guard.txt --print-config yaml
+
+		 # list why each file is watched or ignored without running the command, useful to debug the patterns
+		 guard -w '**' -w '!g' -w '!*.log' --explain -- go test ./...
+
+		 # silence the logs of guard itself, only keep the output of the command
+		 KIT_LOG_SILENCE='[guard]' guard -- node server.js
+
+		 # keep the output of the previous runs in the scrollback
+		 guard --clear-mode scrollback -- go test ./...
+
+		 # run "go mod download" before the command when go.mod or go.sum changes
+		 guard --pre-step '**/go.{mod,sum}=go mod download' -- go test ./...
+
+		 # generate and build before starting the app, a failed step skips the rest
+		 guard --step 'go generate ./...' --step 'go build -o app' -- ./app
+
+		 # regenerate the protobuf code when a proto file changes, otherwise run the tests
+		 guard --route '**/*.proto=make proto' -- go test ./...
+
+		 # don't restart the repl while typing in it, the autosave of the editor triggers too often
+		 guard --typing-idle 2s -- node
+
+		 # Ctrl-C stops the dev server instead of guard, guard restarts it on the next change
+		 guard --forward-signal int -- node server.js
+
+		 # post to a Slack or Discord webhook when the build fails and when it recovers
+		 guard --webhook https://hooks.slack.com/services/xxx -- make
+
+		 # don't kill the running tests, run them once more after they finish if files changed
+		 guard --queue one -- go test ./...
+
+		 # start the database first, then start the api server 2s after it
+		 guard -w 'db/**' -- ./db --- --after 1 --exec-delay 2s -w 'api/**' -- ./api
+
+		 # restart the server 1s after it crashes, give up after 5 failures in a row
+		 guard --restart-on-exit 1s --max-failures 5 -- ./server
+
+		 # let the server flush its state on SIGINT, kill it if it doesn't exit in 5s
+		 guard --stop-signal int --kill-timeout 5s -- ./server
+
+		 # build the backend before the frontend when a shared file changes
+		 guard --priority 1 -w 'api/**' -- make api --- -w 'web/**' -- make web
+
+		 # use a config file, each line is an arg,
+		 # send SIGHUP to guard to reload the commands and patterns of the file
+		 guard @guard.txt
+
 		 # use "---" as separator to guard multiple commands
 		 guard -w 'a/*' -- ls a --- -w 'b/*' -- ls b
 		`,
@@ -102,12 +511,68 @@ func genOptions(args []string) *options {
 	opts.dir = app.Flag("dir", "base dir path").Short('d').String()
 	opts.prefix = app.Flag("prefix", "prefix for command output").Short('p').Default("auto").String()
 	opts.clearScreen = app.Flag("clear-screen", "clear screen before each run").Short('c').Bool()
+	opts.clearMode = app.Flag("clear-mode", "how to clear the screen before each run, implies --clear-screen").
+		Enum(kit.ClearModeNames()...)
 	opts.noInitRun = app.Flag("no-init-run", "don't execute the cmd on startup").Short('n').Bool()
 	opts.poll = app.Flag("poll", "poll interval").Default("300ms").Duration()
-	opts.debounce = app.Flag("debounce", "suppress the frequency of the event").Default("300ms").Duration()
+	debounceSet := false
+	opts.debounce = app.Flag("debounce", "suppress the frequency of the event").Default("300ms").
+		IsSetByUser(&debounceSet).Duration()
+	opts.grace = app.Flag("grace", "don't kill the command within the duration after it starts, queue the events instead").Duration()
+	opts.batch = app.Flag("batch", "collect the changes within the window and run the command once, {{paths}} is the list of the changed files").Duration()
+	opts.maxFiles = app.Flag("max-files", "watch the dirs instead of each file when the patterns match more than n files, to keep the polling cheap").Int()
+	opts.dryRun = app.Flag("dry-run", "print the rendered commands of each run instead of executing them").Bool()
+	opts.noGoWork = app.Flag("no-go-work", "don't watch the member modules of the go.work that are outside the dir").Bool()
+	opts.xargs = app.Flag("xargs", "append the changed files to the args, the runs without any changed file are skipped").Bool()
+	opts.xargsMax = app.Flag("xargs-max", "the max number of the changed files for each command of --xargs, the command runs once per chunk").Int()
+	opts.typingIdle = app.Flag("typing-idle", "hold the runs until no keystroke is sent to the command within the duration").Duration()
+	opts.queue = app.Flag("queue", "what to do with the changes while the command is running: restart it, run it once more after it exits, or drop them").
+		Default("restart").Enum("restart", "one", "drop")
+	opts.execDelay = app.Flag("exec-delay", "delay the initial run, such as staggering the start of the sections").Duration()
+	opts.after = app.Flag("after", "hold the initial run until the initial run of the section n has started, the first section is 1, can set multiple").Ints()
+	opts.restart = app.Flag("restart-on-exit", "restart the command after the backoff if it exits on its own, such as a crash").Duration()
+	opts.noKill = app.Flag("no-kill", "run the command concurrently for each change without killing the previous one").Bool()
+	opts.concurrency = app.Flag("concurrency", "the max number of concurrent commands for --no-kill, default is the number of CPUs").Int()
+	opts.maxRuns = app.Flag("max-runs", "the max number of concurrent runs across all the sections").Int()
+	opts.priority = app.Flag("priority", "the queued runs of the section with higher priority start sooner, implies --max-runs 1 if not set").Int()
+	opts.maxFailures = app.Flag("max-failures", "pause after n consecutive failures, press r in --tui or send SIGHUP to resume").Int()
+	opts.webhook = app.Flag("webhook", "post a json payload to the url when a run fails and when it recovers, such as a Slack or Discord webhook").String()
+	opts.jsonLog = app.Flag("json-log", "write the logs of guard as json lines instead of the colored text, for the log pipelines").Bool()
+	opts.profile = app.Flag("profile", "log the wall time, cpu time, max rss, and page faults of each run").Bool()
+	opts.preSteps = app.Flag("pre-step", "run a command before the command when the matched files change, such as '**/go.mod=go mod download', can set multiple").Strings()
+	opts.steps = app.Flag("step", "run a command before the command on each run in order, a failed step skips the rest, can set multiple").Strings()
+	opts.routes = app.Flag("route", "run a command instead of the command when the matched files change, such as '**/*.proto=make proto', can set multiple").Strings()
+	opts.forward = app.Flag("forward-signal", "forward the signal to the command instead of stopping guard, can set multiple").
+		Enums(signalNames()...)
+	opts.stopSignal = app.Flag("stop-signal", "the signal to stop the command before the rerun, default is term").
+		Enum(signalNames()...)
+	opts.killTimeout = app.Flag("kill-timeout", "kill the command if it doesn't exit within the duration after the stop signal").Duration()
 	opts.raw = app.Flag("raw", "when you need to interact with the subprocess").Bool()
-
-	app.Version(kit.Version)
+	opts.liveReload = app.Flag("live-reload", "serve the live reload on the addr, the css changes are injected into the page without rerunning the command").String()
+	opts.reloadCSS = app.Flag("live-reload-css", "the pattern of the css files to inject for --live-reload, can set multiple, the default is '**/*.css'").Strings()
+	opts.httpTrigger = app.Flag("http-trigger", "listen on the addr, POST with the X-Guard-Trigger header to rerun the command, GET to get the status as json").String()
+	opts.every = app.Flag("every", "also rerun the command periodically").Duration()
+	opts.cron = app.Flag("cron", "also rerun the command by a cron spec, such as '0 3 * * *'").String()
+	opts.container = app.Flag("container", "detect changes by inode and content hash, for the docker bind mounts that miss the mtime changes").Bool()
+	opts.followLinks = app.Flag("follow-symlinks", "watch the dirs that the symlinks link to, such as the workspace links in node_modules").Short('L').Bool()
+	opts.sameDevice = app.Flag("same-device", "don't watch the dirs on other devices, such as a mounted NAS").Short('x').Bool()
+	opts.encoding = app.Flag("output-encoding", "the encoding of the output of the command, such as gbk or cp1252, it's converted to UTF-8").String()
+	opts.syncLines = app.Flag("sync-lines", "write each output line as a whole, so the output of sections won't interleave").Bool()
+	opts.pane = app.Flag("pane", "print a separator when the output switches between sections, implies --sync-lines").Bool()
+	opts.tui = app.Flag("tui", "render each section in its own pane with keyboard navigation").Bool()
+	opts.logDir = app.Flag("log-dir", "also write the output of each section to a rotating file in the dir").String()
+	opts.logMaxSize = app.Flag("log-max-size", "rotate the log file when it's larger than the size").Default("10MB").Bytes()
+	opts.logRotate = app.Flag("log-rotate", "rotate the log file periodically, such as 24h").Duration()
+	opts.logBackups = app.Flag("log-backups", "the number of the rotated log files to keep").Default("5").Int()
+	opts.tail = app.Flag("tail", "include the last n lines of the output in the summary").Int()
+	opts.flakyReport = app.Flag("flaky-report", "log the number of the runs that passed without any change after a failure periodically").Duration()
+	opts.summary = app.Flag("summary", "write the session summary as json to the file on exit").String()
+	opts.preset = app.Flag("preset", "the default patterns, debounce, and command for a stack, the command can be omitted").
+		Enum(presetNames()...)
+	opts.printConfig = app.Flag("print-config", "print the effective settings as yaml or json then exit").Enum("yaml", "json")
+	opts.explain = app.Flag("explain", "print why each file is watched or ignored by the patterns then exit").Bool()
+
+	app.Version(kit.BuildInfo().String())
 
 	args, cmdArgs := parseArgs(args)
 
@@ -118,15 +583,74 @@ func genOptions(args []string) *options {
 		panic(err)
 	}
 
-	if cmdArgs == nil {
+	opts.cmd = cmdArgs
+
+	applyPreset(opts, debounceSet)
+
+	if opts.cmd == nil {
 		panic("empty command")
 	}
 
-	opts.cmd = cmdArgs
-
 	return opts
 }
 
+// the format is "pattern=command", the command is split by SplitCommand
+func parsePatternCommand(flag, s string) (pattern string, args []string) {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		panic("invalid " + flag + ", the format should be 'pattern=command': " + s)
+	}
+
+	args, err := kit.SplitCommand(s[i+1:])
+	kit.E(err)
+	if len(args) == 0 {
+		panic("empty command of " + flag + ": " + s)
+	}
+
+	return s[:i], args
+}
+
+var signals = map[string]os.Signal{
+	"int":  os.Interrupt,
+	"term": syscall.SIGTERM,
+	"quit": syscall.SIGQUIT,
+}
+
+func signalNames() []string {
+	list := []string{}
+	for name := range signals {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func signalsOf(names []string) []os.Signal {
+	list := []os.Signal{}
+	for _, name := range names {
+		list = append(list, signals[name])
+	}
+	return list
+}
+
+// the signals that stop guard, the forwarded ones are excluded
+func exitSignals(optsList []*options) []os.Signal {
+	forwarded := map[string]bool{}
+	for _, opts := range optsList {
+		for _, name := range *opts.forward {
+			forwarded[name] = true
+		}
+	}
+
+	list := []os.Signal{}
+	for _, name := range []string{"int", "term"} {
+		if !forwarded[name] {
+			list = append(list, signals[name])
+		}
+	}
+	return list
+}
+
 func filterEmpty(list []string) []string {
 	newList := []string{}
 	for _, el := range list {
@@ -158,16 +682,45 @@ func indexOf(list []string, str string) int {
 }
 
 func argsFromConfigFile(args []string) []string {
+	file := configFile(args)
+	if file == "" {
+		return args
+	}
+
+	f, err := kit.ReadFile(file)
+	if err != nil {
+		return args
+	}
+	lines := filterEmpty(regexp.MustCompile(`[\n\r]+`).Split(string(f), -1))
+
+	// the other flags on the command line are kept, such as "guard @guard.txt --print-config yaml",
+	// they go before the lines of the file, the command after "--" goes after them
+	flags, cmd := []string{}, []string{}
+	for i, elem := range args {
+		if elem == "--" {
+			cmd = args[i:]
+			break
+		}
+		if elem != "@"+file {
+			flags = append(flags, elem)
+		}
+	}
+
+	return append(append(flags, lines...), cmd...)
+}
+
+// the path of the config file, such as "@guard.txt", the args of the command after "--" are skipped,
+// such as the "@types/node" of "guard -- npm i @types/node"
+func configFile(args []string) string {
 	for _, elem := range args {
+		if elem == "--" {
+			break
+		}
 		if len(elem) > 1 && elem[0] == '@' {
-			f, err := kit.ReadFile(elem[1:])
-			if err != nil {
-				return args
-			}
-			return regexp.MustCompile(`[\n\r]+`).Split(string(f), -1)
+			return elem[1:]
 		}
 	}
-	return args
+	return ""
 }
 
 func genPrefix(prefix string, args []string) string {
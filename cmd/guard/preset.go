@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+// the defaults for a common stack, the flags set by the user take precedence
+type preset struct {
+	patterns []string
+	debounce time.Duration
+	cmd      []string // used when the command is omitted
+	preSteps []string // the same format as --pre-step
+}
+
+var presets = map[string]preset{
+	"go": {
+		patterns: []string{"**/*.go", "**/go.mod", "**/go.sum", "!vendor/**", kit.WalkGitIgnore},
+		debounce: 300 * time.Millisecond,
+		cmd:      []string{"go", "run", "."},
+		preSteps: []string{"**/go.{mod,sum}=go mod download"},
+	},
+	"node": {
+		patterns: []string{
+			"**/*.js", "**/*.mjs", "**/*.ts", "**/*.jsx", "**/*.tsx", "**/*.json",
+			"!node_modules/**", "!dist/**", "!build/**", kit.WalkGitIgnore,
+		},
+		debounce: 500 * time.Millisecond,
+		cmd:      []string{"npm", "start"},
+	},
+	"python": {
+		patterns: []string{
+			"**/*.py", "**/requirements.txt", "**/pyproject.toml",
+			"!**/__pycache__/**", "!.venv/**", "!venv/**", kit.WalkGitIgnore,
+		},
+		debounce: 300 * time.Millisecond,
+		cmd:      []string{"python", "main.py"},
+	},
+	"rust": {
+		patterns: []string{"**/*.rs", "**/Cargo.toml", "**/Cargo.lock", "!target/**", kit.WalkGitIgnore},
+		debounce: time.Second,
+		cmd:      []string{"cargo", "run"},
+	},
+}
+
+func presetNames() []string {
+	list := []string{}
+	for name := range presets {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func applyPreset(opts *options, debounceSet bool) {
+	p, has := presets[*opts.preset]
+	if !has {
+		return
+	}
+
+	if len(filterEmpty(*opts.patterns)) == 0 {
+		*opts.patterns = p.patterns
+	}
+
+	if len(filterEmpty(*opts.preSteps)) == 0 {
+		*opts.preSteps = p.preSteps
+	}
+
+	if !debounceSet {
+		*opts.debounce = p.debounce
+	}
+
+	if opts.cmd == nil {
+		opts.cmd = p.cmd
+	}
+}
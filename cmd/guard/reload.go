@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ysmood/kit"
+)
+
+// reload the config file on SIGHUP, only the commands and patterns will be updated,
+// without a config file the commands will be restarted
+func watchReload(guards []*kit.GuardContext) {
+	file := configFile(os.Args[1:])
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	for range c {
+		if file == "" {
+			for _, guard := range guards {
+				guard.Restart()
+			}
+			continue
+		}
+		reload(file, guards)
+	}
+}
+
+func reload(file string, guards []*kit.GuardContext) {
+	prefix := kit.C("[guard]", "cyan")
+	kit.Log(prefix, "reload", file)
+
+	optsList := []*options{}
+	err := kit.Try(func() {
+		for _, args := range split(argsFromConfigFile(os.Args[1:]), "---") {
+			optsList = append(optsList, genOptions(args))
+		}
+	})
+	if err != nil {
+		kit.Log(prefix, kit.C(fmt.Sprint("failed to reload: ", err), "red"))
+		return
+	}
+
+	if len(optsList) != len(guards) {
+		kit.Log(prefix, kit.C("the number of sections changed, restart guard to apply it", "red"))
+		return
+	}
+
+	for i, guard := range guards {
+		opts := optsList[i]
+		guard.Reload(opts.cmd, filterEmpty(*opts.patterns)...)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+// print the summary of each section, and write it to the file if --summary is set
+func printSummary(optsList []*options, guards []*kit.GuardContext) {
+	for i, guard := range guards {
+		opts := optsList[i]
+		s := guard.Summary()
+
+		kit.Log(kit.C("[guard]", "cyan"), "summary of", kit.C(strings.Join(opts.cmd, " "), "green"))
+		kit.Log(s.String())
+
+		if *opts.summary != "" {
+			err := kit.OutputFile(*opts.summary, map[string]interface{}{
+				"command": opts.cmd,
+				"summary": s,
+			}, nil)
+			if err != nil {
+				kit.Err(err)
+			}
+		}
+	}
+}
+
+// log the number of the flaky runs periodically, a run is flaky if it passes without any change after a failure
+func reportFlaky(opts *options, guard *kit.GuardContext) {
+	t := time.NewTicker(*opts.flakyReport)
+	defer t.Stop()
+
+	for range t.C {
+		s := guard.Summary()
+		if s.Flaky == 0 {
+			continue
+		}
+		kit.Log(kit.C("[guard]", "cyan"), "flaky report of", kit.C(strings.Join(opts.cmd, " "), "green")+":",
+			kit.C(fmt.Sprintf("%d of %d runs passed without any change after a failure", s.Flaky, s.Runs), "yellow"))
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/ysmood/kit"
+)
+
+// guard test-pattern, reports whether each path would be watched with the same rules as guard
+func testPattern(args []string) {
+	app := kingpin.New("guard test-pattern", "report whether each path would be watched or ignored and why, "+
+		"the rules are applied the same way as guard, including the gitignore and "+kit.GuardIgnoreFile+" files\n\n"+
+		"example: guard test-pattern '**' -w '!g' -w '!**/*.log' main.go tmp/a.log vendor")
+	patterns := app.Flag("watch", "the patterns after the first one, the same as the --watch of guard").Short('w').Strings()
+	dir := app.Flag("dir", "base dir path").Short('d').String()
+	pattern := app.Arg("pattern", "the first pattern").Required().String()
+	paths := app.Arg("paths", "the paths to test, relative to the current dir").Required().Strings()
+
+	kingpin.MustParse(app.Parse(args))
+
+	abs := []string{}
+	for _, p := range *paths {
+		a, err := filepath.Abs(p)
+		kit.E(err)
+		abs = append(abs, a)
+	}
+
+	guard := kit.Guard().Dir(*dir).Patterns(append([]string{*pattern}, *patterns...)...)
+	fmt.Println(guard.MustExplain(abs...))
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ysmood/kit"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// the max number of lines each pane keeps
+const paneMaxLines = 1000
+
+var regEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// pane collects the output of a guard section
+type pane struct {
+	title  string
+	guard  *kit.GuardContext
+	lines  []string
+	last   string // the unfinished line
+	scroll int    // how many lines scrolled up from the bottom
+
+	lock   *sync.Mutex
+	update chan kit.Nil
+}
+
+func (p *pane) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := regEscape.ReplaceAllString(string(b), "")
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\t", "    ")
+
+	list := strings.Split(p.last+s, "\n")
+	p.last = list[len(list)-1]
+	p.lines = append(p.lines, list[:len(list)-1]...)
+
+	if len(p.lines) > paneMaxLines {
+		p.lines = p.lines[len(p.lines)-paneMaxLines:]
+	}
+
+	select {
+	case p.update <- kit.Nil{}:
+	default:
+	}
+
+	return len(b), nil
+}
+
+// the status text and its color
+func (p *pane) status() (string, string) {
+	s := p.guard.Summary()
+
+	status, color := "waiting", "yellow"
+	switch {
+	case s.Running:
+		status, color = "running", "green"
+	case s.Failed:
+		status, color = "failed", "red"
+	}
+
+	return fmt.Sprintf("%s, watching %d files", status, s.WatchedFiles), color
+}
+
+// tui renders each guard section in its own pane
+type tui struct {
+	panes []*pane
+	focus int
+	stdin *os.File
+
+	lock   sync.Mutex
+	update chan kit.Nil
+}
+
+// run the guards with the dashboard, the terminal will be restored on quit
+func runTUI(optsList []*options, guards []*kit.GuardContext) {
+	ui := &tui{update: make(chan kit.Nil, 1)}
+
+	for i, guard := range guards {
+		p := &pane{
+			title:  strings.Join(optsList[i].cmd, " "),
+			guard:  guard,
+			lock:   &ui.lock,
+			update: ui.update,
+		}
+		if optsList[i].logFile != nil {
+			guard.Stdout(io.MultiWriter(p, optsList[i].logFile))
+		} else {
+			guard.Stdout(p)
+		}
+		ui.panes = append(ui.panes, p)
+	}
+
+	// the subprocesses shouldn't steal the keys from the dashboard
+	ui.stdin = os.Stdin
+	devNull, err := os.Open(os.DevNull)
+	kit.E(err)
+	os.Stdin = devNull
+
+	fd := int(ui.stdin.Fd())
+	oldState, err := terminal.MakeRaw(fd)
+	kit.E(err)
+
+	once := sync.Once{}
+	quit := func() {
+		once.Do(func() {
+			fmt.Print("\x1b[?1049l\x1b[?25h")
+			_ = terminal.Restore(fd, oldState)
+			printSummary(optsList, guards)
+			os.Exit(0)
+		})
+	}
+
+	// switch to the alternate screen and hide the cursor
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+
+	go ui.render(fd)
+	go ui.input(quit)
+	go func() {
+		kit.WaitSignal(syscall.SIGTERM)
+		quit()
+	}()
+
+	fns := []func(){}
+	for _, guard := range guards {
+		fns = append(fns, guard.MustDo)
+	}
+	kit.All(fns...)()
+}
+
+func (ui *tui) render(fd int) {
+	tick := time.NewTicker(200 * time.Millisecond)
+	defer tick.Stop()
+
+	for {
+		w, h, err := terminal.GetSize(fd)
+		if err == nil {
+			fmt.Print(ui.draw(w, h))
+		}
+
+		select {
+		case <-tick.C:
+		case <-ui.update:
+		}
+	}
+}
+
+// draw the whole screen, each pane has a header line and the tail of its output
+func (ui *tui) draw(w, h int) string {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+
+	out := &strings.Builder{}
+	out.WriteString("\x1b[H")
+
+	help := " tab/n/p: switch  up/down/j/k: scroll  r: restart  q: quit"
+	height := (h - 1) / len(ui.panes)
+
+	for i, p := range ui.panes {
+		status, color := p.status()
+		header := fit(fmt.Sprintf(" [%d] %s | %s", i+1, p.title, status), w)
+		if i == ui.focus {
+			color += "+i"
+		}
+		out.WriteString(kit.C(header, color))
+		out.WriteString("\x1b[K\r\n")
+
+		rows := height - 1
+		if i == len(ui.panes)-1 {
+			rows = h - 1 - height*i - 1
+		}
+
+		lines := p.lines
+		if p.last != "" {
+			lines = append(lines[:len(lines):len(lines)], p.last)
+		}
+
+		if max := len(lines) - rows; p.scroll > max {
+			p.scroll = max
+		}
+		if p.scroll < 0 {
+			p.scroll = 0
+		}
+
+		end := len(lines) - p.scroll
+		start := end - rows
+		for j := start; j < end; j++ {
+			line := ""
+			if j >= 0 {
+				line = lines[j]
+			}
+			out.WriteString(fit(" "+line, w))
+			out.WriteString("\x1b[K\r\n")
+		}
+	}
+
+	out.WriteString(kit.C(fit(help, w), "black+h"))
+	out.WriteString("\x1b[K")
+
+	return out.String()
+}
+
+func (ui *tui) input(quit func()) {
+	buf := make([]byte, 16)
+	for {
+		n, err := ui.stdin.Read(buf)
+		if err != nil {
+			quit()
+			return
+		}
+
+		key := string(buf[:n])
+
+		if key == "q" || key == "\x03" {
+			quit()
+			return
+		}
+
+		ui.lock.Lock()
+		p := ui.panes[ui.focus]
+		switch key {
+		case "\t", "n":
+			ui.focus = (ui.focus + 1) % len(ui.panes)
+		case "p", "\x1b[Z":
+			ui.focus = (ui.focus + len(ui.panes) - 1) % len(ui.panes)
+		case "k", "\x1b[A":
+			p.scroll++
+		case "j", "\x1b[B":
+			p.scroll--
+		case "\x1b[5~":
+			p.scroll += 10
+		case "\x1b[6~":
+			p.scroll -= 10
+		}
+		ui.lock.Unlock()
+
+		if key == "r" {
+			go p.guard.Restart()
+		}
+
+		select {
+		case ui.update <- kit.Nil{}:
+		default:
+		}
+	}
+}
+
+// truncate the string to the max number of chars, the width of wide chars is ignored
+func fit(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+// the lines of the output to post when --webhook is set
+const webhookTail = 20
+
+// the max length of the message, the limit of Discord is 2000
+const webhookMaxText = 1900
+
+// the text and content fields are rendered by Slack and Discord, the rest are for custom receivers
+type webhookPayload struct {
+	Text     string `json:"text"`
+	Content  string `json:"content"`
+	Command  string `json:"command"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Changed  string `json:"changed,omitempty"`
+	Output   string `json:"output,omitempty"`
+}
+
+// post to the url after each failed run, and when the command passes after failures
+func webhook(url string, cmd []string) func(r *kit.GuardResult) {
+	lock := sync.Mutex{}
+	failed := false
+
+	return func(r *kit.GuardResult) {
+		lock.Lock()
+		status := ""
+		if r.Failed {
+			status = "failed"
+			failed = true
+		} else if r.Err == nil && failed {
+			status = "recovered"
+			failed = false
+		}
+		lock.Unlock()
+
+		if status == "" {
+			return
+		}
+
+		args := r.Args
+		if len(args) == 0 {
+			args = cmd
+		}
+
+		p := &webhookPayload{
+			Command:  strings.Join(args, " "),
+			Status:   status,
+			Duration: r.Duration.Round(time.Millisecond).String(),
+			Changed:  r.Path,
+			Output:   r.Output,
+		}
+		p.Text = webhookText(p)
+		p.Content = p.Text
+
+		go postWebhook(url, p)
+	}
+}
+
+func webhookText(p *webhookPayload) string {
+	text := fmt.Sprintf("[guard] `%s` %s in %s", p.Command, p.Status, p.Duration)
+	if p.Changed != "" {
+		text += ", changed: " + p.Changed
+	}
+
+	if out := strings.TrimSpace(p.Output); out != "" && p.Status == "failed" {
+		if max := webhookMaxText - len(text); max < 0 {
+			out = ""
+		} else if len(out) > max {
+			out = out[len(out)-max:]
+		}
+		text += "\n```\n" + out + "\n```"
+	}
+	return text
+}
+
+func postWebhook(url string, p *webhookPayload) {
+	res, err := kit.Req(url).Post().Timeout(10 * time.Second).JSONBody(p).Response()
+	if err == nil {
+		_ = res.Body.Close()
+		if res.StatusCode >= 300 {
+			err = fmt.Errorf("unexpected status %d", res.StatusCode)
+		}
+	}
+	if err != nil {
+		kit.Log(kit.C("[guard]", "cyan"), "webhook failed:", kit.C(err, "red"))
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 func main() {
 	app := run.TasksNew("dev", "dev tool for kit")
-	app.Version(utils.Version)
+	app.Version(utils.BuildInfo().String())
 
 	run.Tasks().App(app).Add(
 		run.Task("build", "").Init(cmdBuild),
@@ -20,10 +20,14 @@ func genReadme() {
 
 	guardHelp := run.Exec("go", "run", "./cmd/guard", "--help").MustString()
 	godevHelp := run.Exec("go", "run", "./cmd/godev", "--help").MustString()
+	walkHelp := run.Exec("go", "run", "./cmd/walk", "--help").MustString()
+	smokeHelp := run.Exec("go", "run", "./cmd/smoke", "--help").MustString()
 
 	list := []interface{}{
 		"GuardHelp", guardHelp,
 		"GodevHelp", godevHelp,
+		"WalkHelp", walkHelp,
+		"SmokeHelp", smokeHelp,
 	}
 
 	ast.Inspect(fast, func(n ast.Node) bool {
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/ysmood/kit"
+)
+
+func main() {
+	app := kingpin.New(
+		"smoke",
+		`run the http smoke tests declared in a yaml file in parallel, exit with non-zero if any fails
+
+		Example of the yaml file, the env vars in it will be expanded:
+
+		 base: http://localhost:8080
+		 timeout: 10s
+		 requests:
+		   - name: health
+		     url: /health
+		     expect:
+		       contains: [ok]
+		   - name: create user
+		     method: POST
+		     url: /users
+		     header:
+		       Authorization: Bearer $TOKEN
+		     json: {name: jack}
+		     expect:
+		       status: 201
+		       header:
+		         Content-Type: application/json; charset=utf-8
+		       json:
+		         name: jack
+		`,
+	)
+	file := app.Arg("file", "the yaml file of the requests").Default("smoke.yml").String()
+	base := app.Flag("base", "override the base url of the file").Short('b').String()
+	concurrency := app.Flag("concurrency", "the max number of concurrent requests, default is the number of CPUs").
+		Short('c').Int()
+	timeout := app.Flag("timeout", "the timeout of each request if the file doesn't set it").Default("10s").Duration()
+
+	app.Version(kit.BuildInfo().String())
+
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	s, err := readSpec(*file)
+	exitErr(err)
+
+	if *base != "" {
+		s.Base = *base
+	}
+	if s.Timeout == 0 {
+		s.Timeout = *timeout
+	}
+	if *concurrency < 1 {
+		*concurrency = runtime.NumCPU()
+	}
+
+	failed := run(s, *concurrency)
+
+	kit.Log("passed:", len(s.Requests)-failed, "failed:", failed)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// returns the number of failed requests
+func run(s *spec, concurrency int) int {
+	lock := sync.Mutex{}
+	failed := 0
+
+	sem := make(chan kit.Nil, concurrency)
+	wg := sync.WaitGroup{}
+
+	for _, r := range s.Requests {
+		wg.Add(1)
+		sem <- kit.Nil{}
+
+		go func(r *request) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			start := time.Now()
+			err := check(s, r)
+			latency := time.Since(start).Round(time.Millisecond)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err == nil {
+				kit.Log(kit.C("✔", "green"), r.Name, kit.C(latency, "240"))
+				return
+			}
+			failed++
+			kit.Log(kit.C("✘", "red"), r.Name, kit.C(latency, "240"))
+			fmt.Println(indent(err.Error()))
+		}(r)
+	}
+	wg.Wait()
+
+	return failed
+}
+
+func check(s *spec, r *request) error {
+	url := r.URL
+	if !strings.Contains(url, "://") {
+		url = strings.TrimRight(s.Base, "/") + "/" + strings.TrimLeft(url, "/")
+	}
+
+	// the deadline covers reading the body for the expectations
+	c, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	req := kit.Req(url).Method(strings.ToUpper(r.Method)).Context(c)
+
+	for k, v := range r.Header {
+		req.Header(k, v)
+	}
+
+	if r.JSON != nil {
+		req.JSONBody(r.JSON)
+	} else if r.Body != "" {
+		req.StringBody(r.Body)
+	}
+
+	res, err := req.Response()
+	if err != nil {
+		return err
+	}
+	// the body isn't read if no expectation needs it, close it so the connection is released
+	defer func() { _ = res.Body.Close() }()
+
+	e := r.Expect
+
+	if e.Status == 0 {
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return &kit.ExpectError{Kind: "status", Expected: "2xx", Actual: res.StatusCode}
+		}
+	} else if err := req.ExpectStatus(e.Status); err != nil {
+		return err
+	}
+
+	for _, k := range sortedKeys(e.Header) {
+		if err := req.ExpectHeader(k, e.Header[k]); err != nil {
+			return err
+		}
+	}
+
+	paths := []string{}
+	for p := range e.JSON {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if err := req.ExpectJSON(p, e.JSON[p]); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range e.Contains {
+		if err := req.ExpectBodyContains(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n  ")
+}
+
+func exitErr(err error) {
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			b, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Token", r.Header.Get("Authorization"))
+			w.WriteHeader(201)
+			_, _ = w.Write(b)
+		case "/slow":
+			w.WriteHeader(200)
+			w.(http.Flusher).Flush()
+			time.Sleep(time.Second)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+
+	s := &spec{Base: srv.URL + "/", Timeout: 3 * time.Second}
+
+	ok := &request{
+		Method: "post",
+		URL:    "/users",
+		Header: map[string]string{"Authorization": "token"},
+		JSON:   map[string]interface{}{"name": "jack"},
+		Expect: expect{
+			Status:   201,
+			Header:   map[string]string{"X-Token": "token"},
+			JSON:     map[string]interface{}{"name": "jack"},
+			Contains: []string{"jack"},
+		},
+	}
+	assert.Nil(t, check(s, ok))
+
+	// any 2xx by default, the body isn't read
+	assert.Nil(t, check(s, &request{Method: "POST", URL: srv.URL + "/users", Body: "{}"}))
+
+	var e *kit.ExpectError
+
+	err := check(s, &request{Method: "GET", URL: "/not-found"})
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "2xx", e.Expected)
+	assert.Equal(t, 404, e.Actual)
+
+	err = check(s, &request{Method: "POST", URL: "/users", Body: `{"name":"tom"}`,
+		Expect: expect{JSON: map[string]interface{}{"name": "jack"}}})
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "tom", e.Actual)
+
+	// the timeout covers reading the body
+	s.Timeout = 100 * time.Millisecond
+	err = check(s, &request{Method: "GET", URL: "/slow", Expect: expect{Contains: []string{"x"}}})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(500)
+		}
+	}))
+	defer srv.Close()
+
+	s := &spec{Base: srv.URL, Timeout: time.Second, Requests: []*request{
+		{Name: "a", Method: "GET", URL: "/ok"},
+		{Name: "b", Method: "GET", URL: "/err"},
+		{Name: "c", Method: "GET", URL: "/ok"},
+	}}
+
+	assert.Equal(t, 1, run(s, 2))
+}
+
+func TestIndent(t *testing.T) {
+	assert.Equal(t, "  a\n  b", indent("a\nb\n"))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ysmood/kit"
+	"gopkg.in/yaml.v3"
+)
+
+// the yaml file of the smoke tests, the env vars such as $TOKEN in it will be expanded
+type spec struct {
+	Base     string        `yaml:"base"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Requests []*request    `yaml:"requests"`
+}
+
+type request struct {
+	Name   string            `yaml:"name"`
+	Method string            `yaml:"method"`
+	URL    string            `yaml:"url"`
+	Header map[string]string `yaml:"header"`
+	Body   string            `yaml:"body"`
+	JSON   interface{}       `yaml:"json"`
+	Expect expect            `yaml:"expect"`
+}
+
+type expect struct {
+	// 0 means any 2xx status
+	Status   int                    `yaml:"status"`
+	Header   map[string]string      `yaml:"header"`
+	JSON     map[string]interface{} `yaml:"json"`
+	Contains []string               `yaml:"contains"`
+}
+
+func readSpec(path string) (*spec, error) {
+	b, err := kit.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spec{}
+	err = yaml.Unmarshal([]byte(os.ExpandEnv(string(b))), s)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, r := range s.Requests {
+		if r.Name == "" {
+			r.Name = r.URL
+		}
+		if r.Method == "" {
+			r.Method = "GET"
+		}
+		if r.URL == "" {
+			return nil, fmt.Errorf("the url of request #%d is empty", i+1)
+		}
+	}
+
+	return s, nil
+}
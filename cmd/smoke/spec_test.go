@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestReadSpec(t *testing.T) {
+	p := "tmp/" + kit.RandString(10) + ".yml"
+	kit.E(kit.OutputFile(p, `
+base: http://localhost:8080
+timeout: 3s
+requests:
+  - url: /health
+  - name: create
+    method: post
+    url: /users
+    header:
+      Authorization: Bearer $SMOKE_TEST_TOKEN
+    json: {name: jack}
+    expect:
+      status: 201
+      json:
+        name: jack
+      contains: [jack]
+`, nil))
+	kit.E(os.Setenv("SMOKE_TEST_TOKEN", "secret"))
+	defer func() { _ = os.Unsetenv("SMOKE_TEST_TOKEN") }()
+
+	s, err := readSpec(p)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "http://localhost:8080", s.Base)
+	assert.Equal(t, 3*time.Second, s.Timeout)
+	assert.Len(t, s.Requests, 2)
+
+	assert.Equal(t, "/health", s.Requests[0].Name)
+	assert.Equal(t, "GET", s.Requests[0].Method)
+
+	r := s.Requests[1]
+	assert.Equal(t, "create", r.Name)
+	assert.Equal(t, "post", r.Method)
+	assert.Equal(t, "Bearer secret", r.Header["Authorization"])
+	assert.Equal(t, map[string]interface{}{"name": "jack"}, r.JSON)
+	assert.Equal(t, 201, r.Expect.Status)
+	assert.Equal(t, "jack", r.Expect.JSON["name"])
+	assert.Equal(t, []string{"jack"}, r.Expect.Contains)
+}
+
+func TestReadSpecErr(t *testing.T) {
+	_, err := readSpec("tmp/not-exists.yml")
+	assert.Error(t, err)
+
+	p := "tmp/" + kit.RandString(10) + ".yml"
+	kit.E(kit.OutputFile(p, "requests: [{name: a}]", nil))
+	_, err = readSpec(p)
+	assert.EqualError(t, err, "the url of request #1 is empty")
+
+	kit.E(kit.OutputFile(p, "requests: {", nil))
+	_, err = readSpec(p)
+	assert.Error(t, err)
+}
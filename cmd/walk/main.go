@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/ysmood/kit"
+)
+
+func main() {
+	app := kingpin.New(
+		"walk",
+		`list the files that match the patterns, the patterns are the same as guard's
+
+		Examples:
+
+		 # list all the go files, but not the ones ignored by git
+		 walk '**/*.go' '!g'
+
+		 # find the duplicated files in the assets dir
+		 walk --dups 'assets/**'
+
+		 # check the binaries in the release archives
+		 walk -z 'dist/*.zip!/bin/*'
+
+		 # remove the build caches older than 7 days
+		 walk --older-than 168h --remove 'cache/**'
+		`,
+	)
+	patterns := app.Arg("pattern", "the patterns to match, default is '**' and '!g'").Strings()
+	dir := app.Flag("dir", "base dir path").Short('d').Default(".").String()
+	dups := app.Flag("dups", "group the files that have the same content").Bool()
+	olderThan := app.Flag("older-than", "only the files that are not modified within the duration").Duration()
+	largerThan := app.Flag("larger-than", "only the files that are larger than the bytes").Int64()
+	sameDevice := app.Flag("same-device", "don't descend into the dirs on other devices, such as network mounts").Short('x').Bool()
+	intoArchives := app.Flag("into-archives", "also list the files in the zip and tar archives, such as 'dist/app.zip!/bin/app'").Short('z').Bool()
+	long := app.Flag("long", "list the size and modification time of the files").Short('l').Bool()
+	remove := app.Flag("remove", "remove the matched files and print their paths, it requires a pattern or a filter").Bool()
+
+	app.Version(kit.BuildInfo().String())
+
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	if len(*patterns) == 0 {
+		// a bare "walk --remove" shouldn't remove everything
+		if *remove && *olderThan == 0 && *largerThan == 0 {
+			app.Fatalf("--remove requires a pattern, --older-than or --larger-than")
+		}
+		*patterns = []string{"**", kit.WalkGitIgnore}
+	}
+
+	walk := kit.Walk(*patterns...).Dir(*dir).Sort().OlderThan(*olderThan).LargerThan(*largerThan)
+
+	if *sameDevice {
+		walk.SameDevice()
+	}
+
+	if *intoArchives {
+		walk.IntoArchives()
+	}
+
+	if *remove {
+		exitErr(removeList(walk))
+		return
+	}
+
+	if *dups {
+		groups, err := walk.Duplicates()
+		exitErr(err)
+
+		for i, group := range groups {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(strings.Join(group, "\n"))
+		}
+		return
+	}
+
+	list, err := walk.List()
+	exitErr(err)
+
+	if *long {
+		fmt.Println(longList(list))
+		return
+	}
+
+	for _, p := range list {
+		fmt.Println(p)
+	}
+}
+
+// the same as the Remove of the walk, but prints the removed paths
+func removeList(walk *kit.WalkContext) error {
+	list, err := walk.List()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range list {
+		// the dirs and the files in archives are kept
+		if strings.Contains(p, kit.WalkArchiveSep) {
+			continue
+		}
+		info, err := os.Lstat(p)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		err = os.Remove(p)
+		if err != nil {
+			return err
+		}
+		fmt.Println(p)
+	}
+	return nil
+}
+
+func longList(list []string) string {
+	rows := [][]string{}
+	for _, p := range list {
+		info, err := os.Lstat(p)
+		if err != nil {
+			// the files in archives don't exist on the disk
+			if strings.Contains(p, kit.WalkArchiveSep) {
+				rows = append(rows, []string{kit.C("-", "240"), kit.C("-", "240"), p})
+			}
+			continue
+		}
+
+		size := kit.C("-", "240")
+		if !info.IsDir() {
+			size = strconv.FormatInt(info.Size(), 10)
+		}
+		rows = append(rows, []string{size, info.ModTime().Format("2006-01-02 15:04"), p})
+	}
+	return kit.Table([]string{"size", "modified", "path"}, rows)
+}
+
+func exitErr(err error) {
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -13,12 +13,36 @@ var All = utils.All
 // BackoffSleeper imported
 var BackoffSleeper = utils.BackoffSleeper
 
+// BuildInfo imported
+var BuildInfo = utils.BuildInfo
+
+// BuildMeta imported
+type BuildMeta = utils.BuildMeta
+
 // C imported
 var C = utils.C
 
+// ClearBanner imported
+var ClearBanner = utils.ClearBanner
+
+// ClearMode imported
+type ClearMode = utils.ClearMode
+
+// ClearModeNames imported
+var ClearModeNames = utils.ClearModeNames
+
 // ClearScreen imported
 var ClearScreen = utils.ClearScreen
 
+// ClearScreenTo imported
+var ClearScreenTo = utils.ClearScreenTo
+
+// ClearScrollback imported
+var ClearScrollback = utils.ClearScrollback
+
+// ClearWipe imported
+var ClearWipe = utils.ClearWipe
+
 // CountSleeper imported
 var CountSleeper = utils.CountSleeper
 
@@ -46,15 +70,33 @@ type ErrInjector = utils.ErrInjector
 // ErrMaxSleepCount imported
 var ErrMaxSleepCount = utils.ErrMaxSleepCount
 
+// Every imported
+var Every = utils.Every
+
 // JSON imported
 var JSON = utils.JSON
 
 // JSONResult imported
 type JSONResult = utils.JSONResult
 
+// KV imported
+var KV = utils.KV
+
 // Log imported
 var Log = utils.Log
 
+// LogRoute imported
+var LogRoute = utils.LogRoute
+
+// LogSilence imported
+var LogSilence = utils.LogSilence
+
+// LogSilenceEnv imported
+var LogSilenceEnv = utils.LogSilenceEnv
+
+// LogTo imported
+var LogTo = utils.LogTo
+
 // MergeSleepers imported
 var MergeSleepers = utils.MergeSleepers
 
@@ -64,12 +106,18 @@ var MustToJSON = utils.MustToJSON
 // MustToJSONBytes imported
 var MustToJSONBytes = utils.MustToJSONBytes
 
+// NanoID imported
+var NanoID = utils.NanoID
+
 // Nil imported
 type Nil = utils.Nil
 
 // Noop imported
 var Noop = utils.Noop
 
+// ParseClearMode imported
+var ParseClearMode = utils.ParseClearMode
+
 // Pause imported
 var Pause = utils.Pause
 
@@ -88,9 +136,15 @@ var S = utils.S
 // Sdump imported
 var Sdump = utils.Sdump
 
+// SeedRand imported
+var SeedRand = utils.SeedRand
+
 // Sleep imported
 var Sleep = utils.Sleep
 
+// SleepContext imported
+var SleepContext = utils.SleepContext
+
 // Sleeper imported
 type Sleeper = utils.Sleeper
 
@@ -100,60 +154,213 @@ var Stderr = utils.Stderr
 // Stdout imported
 var Stdout = utils.Stdout
 
+// StripANSI imported
+var StripANSI = utils.StripANSI
+
+// Table imported
+var Table = utils.Table
+
+// Timeout imported
+var Timeout = utils.Timeout
+
 // Try imported
 var Try = utils.Try
 
+// UUID imported
+var UUID = utils.UUID
+
 // Version imported
 var Version = utils.Version
 
+// BodyTooLargeError imported
+type BodyTooLargeError = http.BodyTooLargeError
+
+// ConnStats imported
+type ConnStats = http.ConnStats
+
+// DNSCache imported
+type DNSCache = http.DNSCache
+
+// DefaultAcceptEncoding imported
+var DefaultAcceptEncoding = http.DefaultAcceptEncoding
+
+// Download imported
+var Download = http.Download
+
+// DownloadContext imported
+type DownloadContext = http.DownloadContext
+
+// DownloadPages imported
+var DownloadPages = http.DownloadPages
+
+// DownloadPagesContext imported
+type DownloadPagesContext = http.DownloadPagesContext
+
+// ExpectError imported
+type ExpectError = http.ExpectError
+
 // GinContext imported
 type GinContext = http.GinContext
 
+// HostNotAllowedError imported
+type HostNotAllowedError = http.HostNotAllowedError
+
+// MustPing imported
+var MustPing = http.MustPing
+
 // MustServer imported
 var MustServer = http.MustServer
 
+// MustWaitOK imported
+var MustWaitOK = http.MustWaitOK
+
+// NewDNSCache imported
+var NewDNSCache = http.NewDNSCache
+
+// Ping imported
+var Ping = http.Ping
+
+// PingResult imported
+type PingResult = http.PingResult
+
 // Req imported
 var Req = http.Req
 
+// ReqBody imported
+type ReqBody = http.ReqBody
+
 // ReqContext imported
 type ReqContext = http.ReqContext
 
+// ReqIDHeader imported
+var ReqIDHeader = http.ReqIDHeader
+
+// ReqStats imported
+type ReqStats = http.ReqStats
+
+// Resolver imported
+type Resolver = http.Resolver
+
 // Server imported
 var Server = http.Server
 
 // ServerContext imported
 type ServerContext = http.ServerContext
 
+// StaticHosts imported
+var StaticHosts = http.StaticHosts
+
+// TraceFunc imported
+type TraceFunc = http.TraceFunc
+
+// WaitOK imported
+var WaitOK = http.WaitOK
+
+// AnyExists imported
+var AnyExists = os.AnyExists
+
 // CD imported
 var CD = os.CD
 
+// CacheDir imported
+var CacheDir = os.CacheDir
+
+// CheckExists imported
+var CheckExists = os.CheckExists
+
 // Chmod imported
 var Chmod = os.Chmod
 
+// ConfigDir imported
+var ConfigDir = os.ConfigDir
+
 // Copy imported
 var Copy = os.Copy
 
+// DataDir imported
+var DataDir = os.DataDir
+
 // DirExists imported
 var DirExists = os.DirExists
 
+// EnsureDir imported
+var EnsureDir = os.EnsureDir
+
+// EnsureFile imported
+var EnsureFile = os.EnsureFile
+
+// EnsureLine imported
+var EnsureLine = os.EnsureLine
+
+// EnsureSymlink imported
+var EnsureSymlink = os.EnsureSymlink
+
+// EnvRestore imported
+var EnvRestore = os.EnvRestore
+
+// EnvSnap imported
+type EnvSnap = os.EnvSnap
+
+// EnvSnapshot imported
+var EnvSnapshot = os.EnvSnapshot
+
 // Escape imported
 var Escape = os.Escape
 
+// ExecMode imported
+var ExecMode = os.ExecMode
+
 // ExecutableExt imported
 var ExecutableExt = os.ExecutableExt
 
 // Exists imported
 var Exists = os.Exists
 
+// ExistsAll imported
+var ExistsAll = os.ExistsAll
+
+// ExpandGlobs imported
+var ExpandGlobs = os.ExpandGlobs
+
+// ExpandHome imported
+var ExpandHome = os.ExpandHome
+
 // FileExists imported
 var FileExists = os.FileExists
 
 // HomeDir imported
 var HomeDir = os.HomeDir
 
+// InContainer imported
+var InContainer = os.InContainer
+
+// IsExecutable imported
+var IsExecutable = os.IsExecutable
+
+// IsHidden imported
+var IsHidden = os.IsHidden
+
+// IsReadOnly imported
+var IsReadOnly = os.IsReadOnly
+
+// IsSubPath imported
+var IsSubPath = os.IsSubPath
+
 // Matcher imported
 type Matcher = os.Matcher
 
+// Mirror imported
+var Mirror = os.Mirror
+
+// MirrorContext imported
+type MirrorContext = os.MirrorContext
+
+// MirrorFile imported
+type MirrorFile = os.MirrorFile
+
+// MissingError imported
+type MissingError = os.MissingError
+
 // Mkdir imported
 var Mkdir = os.Mkdir
 
@@ -163,9 +370,18 @@ type MkdirOptions = os.MkdirOptions
 // Move imported
 var Move = os.Move
 
+// MustAbs imported
+var MustAbs = os.MustAbs
+
+// MustCopy imported
+var MustCopy = os.MustCopy
+
 // NewMatcher imported
 var NewMatcher = os.NewMatcher
 
+// NormalizePath imported
+var NormalizePath = os.NormalizePath
+
 // OutputFile imported
 var OutputFile = os.OutputFile
 
@@ -181,6 +397,12 @@ var ReadJSON = os.ReadJSON
 // ReadString imported
 var ReadString = os.ReadString
 
+// RelTo imported
+var RelTo = os.RelTo
+
+// RelToWd imported
+var RelToWd = os.RelToWd
+
 // Remove imported
 var Remove = os.Remove
 
@@ -190,15 +412,30 @@ var RemoveWithDir = os.RemoveWithDir
 // RetryPanic imported
 var RetryPanic = os.RetryPanic
 
+// RotateFile imported
+var RotateFile = os.RotateFile
+
+// RotateWriter imported
+type RotateWriter = os.RotateWriter
+
 // SendSigInt imported
 var SendSigInt = os.SendSigInt
 
+// SetExecutable imported
+var SetExecutable = os.SetExecutable
+
+// SetReadOnly imported
+var SetReadOnly = os.SetReadOnly
+
 // WaitSignal imported
 var WaitSignal = os.WaitSignal
 
 // Walk imported
 var Walk = os.Walk
 
+// WalkArchiveSep imported
+var WalkArchiveSep = os.WalkArchiveSep
+
 // WalkContext imported
 type WalkContext = os.WalkContext
 
@@ -214,12 +451,51 @@ var WalkGitIgnore = os.WalkGitIgnore
 // WalkIgnoreHidden imported
 var WalkIgnoreHidden = os.WalkIgnoreHidden
 
+// WithEnv imported
+var WithEnv = os.WithEnv
+
+// ArgsTemplate imported
+var ArgsTemplate = run.ArgsTemplate
+
+// ErrTimeout imported
+var ErrTimeout = run.ErrTimeout
+
+// ErrUnterminatedQuote imported
+var ErrUnterminatedQuote = run.ErrUnterminatedQuote
+
 // Exec imported
 var Exec = run.Exec
 
+// ExecAll imported
+var ExecAll = run.ExecAll
+
+// ExecBin imported
+var ExecBin = run.ExecBin
+
 // ExecContext imported
 type ExecContext = run.ExecContext
 
+// ExecError imported
+type ExecError = run.ExecError
+
+// ExecErrors imported
+type ExecErrors = run.ExecErrors
+
+// ExecGroupContext imported
+type ExecGroupContext = run.ExecGroupContext
+
+// ExecPipe imported
+var ExecPipe = run.ExecPipe
+
+// ExecPipeContext imported
+type ExecPipeContext = run.ExecPipeContext
+
+// ExecProfile imported
+type ExecProfile = run.ExecProfile
+
+// ExecSeq imported
+var ExecSeq = run.ExecSeq
+
 // GoBin imported
 var GoBin = run.GoBin
 
@@ -235,6 +511,51 @@ type GuardContext = run.GuardContext
 // GuardDefaultPatterns imported
 var GuardDefaultPatterns = run.GuardDefaultPatterns
 
+// GuardEvent imported
+type GuardEvent = run.GuardEvent
+
+// GuardExplanation imported
+type GuardExplanation = run.GuardExplanation
+
+// GuardExplanations imported
+type GuardExplanations = run.GuardExplanations
+
+// GuardFileCount imported
+type GuardFileCount = run.GuardFileCount
+
+// GuardIgnoreFile imported
+var GuardIgnoreFile = run.GuardIgnoreFile
+
+// GuardLimiter imported
+type GuardLimiter = run.GuardLimiter
+
+// GuardMaxFilesError imported
+type GuardMaxFilesError = run.GuardMaxFilesError
+
+// GuardQueueDrop imported
+var GuardQueueDrop = run.GuardQueueDrop
+
+// GuardQueueOne imported
+var GuardQueueOne = run.GuardQueueOne
+
+// GuardQueuePolicy imported
+type GuardQueuePolicy = run.GuardQueuePolicy
+
+// GuardQueueRestart imported
+var GuardQueueRestart = run.GuardQueueRestart
+
+// GuardResult imported
+type GuardResult = run.GuardResult
+
+// GuardSummary imported
+type GuardSummary = run.GuardSummary
+
+// GuardTriggerHeader imported
+var GuardTriggerHeader = run.GuardTriggerHeader
+
+// GuardWatchEvent imported
+type GuardWatchEvent = run.GuardWatchEvent
+
 // KillTree imported
 var KillTree = run.KillTree
 
@@ -244,6 +565,18 @@ var LookPath = run.LookPath
 // MustGoTool imported
 var MustGoTool = run.MustGoTool
 
+// NewGuardLimiter imported
+var NewGuardLimiter = run.NewGuardLimiter
+
+// QuoteArgs imported
+var QuoteArgs = run.QuoteArgs
+
+// ResolveBin imported
+var ResolveBin = run.ResolveBin
+
+// SplitCommand imported
+var SplitCommand = run.SplitCommand
+
 // Task imported
 var Task = run.Task
 
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// BodyStruct sets the request body as a struct, the encoding is chosen by the Content-Type header.
+// If the Content-Type isn't set, it will be multipart if any field has the "file" tag,
+// form if any field has the "form" tag, json otherwise.
+// For form and multipart, the field name is from the "form" tag, then the "json" tag, then the field name,
+// use "-" to skip a field. The value of a string field with the "file" tag is the path of the file to upload,
+// the tag is the field name, such as:
+//
+//	struct {
+//	    Name   string `form:"name"`
+//	    Avatar string `file:"avatar"`
+//	}
+func (ctx *ReqContext) BodyStruct(v interface{}) *ReqContext {
+	ctx.structBody = v
+	return ctx
+}
+
+type structField struct {
+	name  string
+	value reflect.Value
+	file  bool
+}
+
+func (ctx *ReqContext) getStructBody() (io.Reader, error) {
+	contentType := ctx.header.Get("Content-Type")
+
+	fields, hasForm, hasFile, err := structFields(ctx.structBody)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasPrefix(contentType, "multipart/form-data"),
+		contentType == "" && hasFile:
+		return ctx.multipartBody(fields)
+
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"),
+		contentType == "" && hasForm:
+		form := url.Values{}
+		for _, f := range fields {
+			if f.file {
+				return nil, fmt.Errorf("file field %q requires multipart encoding", f.name)
+			}
+			form[f.name] = append(form[f.name], fieldValues(f.value)...)
+		}
+		ctx.header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		return strings.NewReader(form.Encode()), nil
+
+	default:
+		body, err := json.Marshal(ctx.structBody)
+		if err != nil {
+			return nil, err
+		}
+		if contentType == "" {
+			ctx.header.Set("Content-Type", "application/json; charset=utf-8")
+		}
+		return bytes.NewReader(body), nil
+	}
+}
+
+func (ctx *ReqContext) multipartBody(fields []structField) (io.Reader, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	for _, f := range fields {
+		if !f.file {
+			for _, v := range fieldValues(f.value) {
+				if err := w.WriteField(f.name, v); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		p := f.value.String()
+		if p == "" {
+			continue
+		}
+		err := writeFile(w, f.name, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.header.Set("Content-Type", w.FormDataContentType())
+	return buf, nil
+}
+
+func writeFile(w *multipart.Writer, name, p string) error {
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	part, err := w.CreateFormFile(name, filepath.Base(p))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, file)
+	return err
+}
+
+// returns the fields, whether any field has the form tag, whether any field has the file tag
+func structFields(v interface{}) ([]structField, bool, bool, error) {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil, false, false, fmt.Errorf("BodyStruct requires a struct, got %T", v)
+	}
+
+	list := []structField{}
+	hasForm, hasFile := false, false
+
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+
+		if name, ok := sf.Tag.Lookup("file"); ok {
+			if sf.Type.Kind() != reflect.String {
+				return nil, false, false, fmt.Errorf("file field %s should be a string path", sf.Name)
+			}
+			hasFile = true
+			list = append(list, structField{name, val.Field(i), true})
+			continue
+		}
+
+		name := sf.Name
+		if tag, ok := sf.Tag.Lookup("form"); ok {
+			hasForm = true
+			name = strings.Split(tag, ",")[0]
+		} else if tag, ok := sf.Tag.Lookup("json"); ok {
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		if name == "-" {
+			continue
+		}
+
+		list = append(list, structField{name, val.Field(i), false})
+	}
+
+	return list, hasForm, hasFile, nil
+}
+
+// a slice field becomes multiple values
+func fieldValues(v reflect.Value) []string {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		list := []string{}
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return list
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
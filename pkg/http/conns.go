@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+// ConnStats counts the new and reused connections of the requests that share it, it helps to tune
+// the concurrency and the idle pool of the client, such as:
+//
+//	stats := &kit.ConnStats{}
+//	kit.Req(url).Client(client).ConnStats(stats).MustDo()
+//	fmt.Println(stats)
+type ConnStats struct {
+	lock   sync.Mutex
+	new    int
+	reused int
+}
+
+// ConnStats counts the connection of the request to the stats
+func (ctx *ReqContext) ConnStats(s *ConnStats) *ReqContext {
+	ctx.connStats = s
+	return ctx
+}
+
+func (s *ConnStats) add(reused bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if reused {
+		s.reused++
+	} else {
+		s.new++
+	}
+}
+
+// New the number of the requests that opened a new connection
+func (s *ConnStats) New() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.new
+}
+
+// Reused the number of the requests that reused an idle connection
+func (s *ConnStats) Reused() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.reused
+}
+
+// ReuseRate the ratio of the reused connections, 0 if there's no request
+func (s *ConnStats) ReuseRate() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.new+s.reused == 0 {
+		return 0
+	}
+	return float64(s.reused) / float64(s.new+s.reused)
+}
+
+func (s *ConnStats) String() string {
+	n, r := s.New(), s.Reused()
+	return fmt.Sprintf("%d requests, %d reused, %d new connections, reuse rate %.0f%%", n+r, r, n, s.ReuseRate()*100)
+}
+
+// Warmup opens n connections to the host of the request with concurrent HEAD requests and puts them
+// into the idle pool of the client, so that the following requests with the same client can reuse them.
+// The status of the responses is ignored. It returns an error if the transport can't keep n idle
+// connections for the host, set a larger MaxIdleConnsPerHost for it. The request itself isn't sent.
+// It doesn't work with Proxy, each request with it has its own transport.
+func (ctx *ReqContext) Warmup(n int) error {
+	if ctx.proxy != "" {
+		return errors.New("warmup doesn't work with the proxy")
+	}
+
+	if t, ok := ctx.transport().(*http.Transport); ok {
+		max := t.MaxIdleConnsPerHost
+		if max == 0 {
+			max = http.DefaultMaxIdleConnsPerHost
+		}
+		if n > max {
+			return fmt.Errorf("the transport can only keep %d idle connections per host, warmup %d connections", max, n)
+		}
+	}
+
+	c := ctx.context
+	if c == nil {
+		c = context.Background()
+	}
+	if ctx.timeout != 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, ctx.timeout)
+		defer cancel()
+	}
+
+	// the requests hold their connections until all of them have got one, so that none of them
+	// reuses the connection of another
+	all := sync.WaitGroup{}
+	all.Add(n)
+	allDone := make(chan struct{})
+	go func() {
+		all.Wait()
+		close(allDone)
+	}()
+
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			once := sync.Once{}
+			got := func() { once.Do(all.Done) }
+			defer got()
+
+			trace := &httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) {
+				got()
+				select {
+				case <-allDone:
+				case <-c.Done():
+				}
+			}}
+
+			errs <- ctx.warmupReq(httptrace.WithClientTrace(c, trace))
+		}()
+	}
+
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return ctx.wrapErr(err)
+}
+
+// a HEAD request with the settings of the ctx
+func (ctx *ReqContext) warmupReq(c context.Context) error {
+	r := *ctx
+	r.context = c
+	r.timeout = 0
+	r.timeoutCancel = nil
+	r.method = http.MethodHead
+	r.header = ctx.header.Clone()
+	r.body = nil
+	r.stringBody = ""
+	r.jsonBody = nil
+	r.structBody = nil
+	r.request = nil
+	r.response = nil
+	r.connStats = nil
+
+	req, err := r.Request()
+	if err != nil {
+		return err
+	}
+	err = r.send(req)
+	if err != nil {
+		return err
+	}
+	return r.response.Body.Close()
+}
+
+// the transport the request will use, the transport of a Resolver is cloned from it
+func (ctx *ReqContext) transport() http.RoundTripper {
+	if ctx.client != nil && ctx.client.Transport != nil {
+		return ctx.client.Transport
+	}
+	return http.DefaultTransport
+}
@@ -0,0 +1,54 @@
+package http_test
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestWarmup() {
+	// the suite server disables the keep-alive
+	var conns int32
+	server := kit.MustServer("127.0.0.1:0")
+	server.Set(&http.Server{ConnState: func(_ net.Conn, st http.ConnState) {
+		if st == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}})
+	server.Engine.Any("/", func(c kit.GinContext) { c.String(200, "ok") })
+	go func() { _ = server.Do() }()
+	defer func() { _ = server.Listener.Close() }()
+
+	url := "http://" + server.Listener.Addr().String()
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 5}}
+	defer client.CloseIdleConnections()
+
+	s.Nil(kit.Req(url).Client(client).Warmup(3))
+	s.EqualValues(3, atomic.LoadInt32(&conns))
+
+	stats := &kit.ConnStats{}
+	for i := 0; i < 3; i++ {
+		s.Equal("ok", kit.Req(url).Client(client).ConnStats(stats).MustString())
+	}
+	s.Equal(3, stats.Reused())
+	s.Equal(0, stats.New())
+	s.Equal(1.0, stats.ReuseRate())
+	s.Equal("3 requests, 3 reused, 0 new connections, reuse rate 100%", stats.String())
+	s.EqualValues(3, atomic.LoadInt32(&conns))
+
+	s.EqualError(kit.Req(url).Warmup(3), "the transport can only keep 2 idle connections per host, warmup 3 connections")
+}
+
+func (s *RequestSuite) TestConnStats() {
+	_, url := s.path()
+
+	stats := &kit.ConnStats{}
+	s.Equal(0.0, stats.ReuseRate())
+
+	kit.Req(url).ConnStats(stats).MustDo()
+	kit.Req(url).ConnStats(stats).MustDo()
+	s.Equal(2, stats.New()) // the keep-alive of the suite server is disabled
+	s.Equal(0, stats.Reused())
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// DownloadContext the download context
+type DownloadContext struct {
+	context context.Context
+	url     string
+	path    string
+	parts   int
+	retries int
+}
+
+// Download the url to a file, by default the file is named after the last segment of the url
+func Download(url string) *DownloadContext {
+	return &DownloadContext{
+		url:     url,
+		parts:   1,
+		retries: 3,
+	}
+}
+
+// Context sets the context of the download
+func (ctx *DownloadContext) Context(c context.Context) *DownloadContext {
+	ctx.context = c
+	return ctx
+}
+
+// To sets the path of the file to save
+func (ctx *DownloadContext) To(path string) *DownloadContext {
+	ctx.path = path
+	return ctx
+}
+
+// Parts downloads the file via n parallel Range requests. If the server doesn't support
+// Range requests or doesn't report the size, it falls back to a single request.
+func (ctx *DownloadContext) Parts(n int) *DownloadContext {
+	ctx.parts = n
+	return ctx
+}
+
+// Retry sets how many times to retry a failed part, default is 3.
+// A retry resumes from where the part stopped.
+func (ctx *DownloadContext) Retry(n int) *DownloadContext {
+	ctx.retries = n
+	return ctx
+}
+
+// Do the download, the file is written to a ".download" file first,
+// then renamed to the path when all the parts are done.
+func (ctx *DownloadContext) Do() error {
+	if ctx.context == nil {
+		ctx.context = context.Background()
+	}
+
+	p := ctx.path
+	if p == "" {
+		u, err := url.Parse(ctx.url)
+		if err != nil {
+			return err
+		}
+		p = path.Base(u.Path)
+		if p == "/" || p == "." {
+			return fmt.Errorf("can't get the file name from the url: %s", ctx.url)
+		}
+	}
+
+	size := int64(-1)
+	if ctx.parts > 1 {
+		size = ctx.rangeSize()
+	}
+
+	tmp := p + ".download"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if size > 0 {
+		err = ctx.parallel(f, size)
+	} else {
+		err = ctx.part(f, 0, -1)
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, p)
+}
+
+// MustDo panic version of Do
+func (ctx *DownloadContext) MustDo() {
+	utils.E(ctx.Do())
+}
+
+// rangeSize returns the size of the file, or -1 if the server doesn't support Range requests
+func (ctx *DownloadContext) rangeSize() int64 {
+	res, err := Req(ctx.url).Context(ctx.context).Method(http.MethodHead).Response()
+	if err != nil {
+		return -1
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || res.Header.Get("Accept-Ranges") != "bytes" {
+		return -1
+	}
+	return res.ContentLength
+}
+
+func (ctx *DownloadContext) parallel(f *os.File, size int64) error {
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+
+	n := int64(ctx.parts)
+	if n > size {
+		n = size
+	}
+	partSize := size / n
+
+	c, cancel := context.WithCancel(ctx.context)
+	defer cancel()
+	sub := *ctx
+	sub.context = c
+
+	var once sync.Once
+	var firstErr error
+	wg := sync.WaitGroup{}
+
+	for i := int64(0); i < n; i++ {
+		start := i * partSize
+		end := start + partSize - 1
+		if i == n-1 {
+			end = size - 1
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sub.part(f, start, end); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// part downloads the bytes from start to end into f, end -1 means the whole file
+func (ctx *DownloadContext) part(f *os.File, start, end int64) error {
+	offset := start
+	count := 0
+
+	return utils.Retry(ctx.context, utils.BackoffSleeper(100*time.Millisecond, 3*time.Second, nil), func() (bool, error) {
+		n, err := ctx.fetch(f, offset, end)
+		offset += n
+		if err == nil || ctx.context.Err() != nil || count >= ctx.retries {
+			return true, err
+		}
+
+		// the whole file can't resume, start over
+		if end == -1 {
+			offset = 0
+		}
+		count++
+		return false, nil
+	})
+}
+
+func (ctx *DownloadContext) fetch(f *os.File, offset, end int64) (int64, error) {
+	req := Req(ctx.url).Context(ctx.context)
+	expected := http.StatusOK
+	if end >= 0 {
+		req.Header("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+		expected = http.StatusPartialContent
+	}
+
+	res, err := req.Response()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != expected {
+		return 0, fmt.Errorf("download %s: unexpected status %d", ctx.url, res.StatusCode)
+	}
+
+	return io.Copy(io.NewOffsetWriter(f, offset), res.Body)
+}
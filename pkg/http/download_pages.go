@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/tidwall/gjson"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// DownloadPagesContext the context to download the pages of a paginated api
+type DownloadPagesContext struct {
+	context  context.Context
+	url      *template.Template
+	dest     *template.Template
+	tmplErr  error
+	start    int
+	maxPages int
+	items    string
+	cursor   string
+	retries  int
+}
+
+// DownloadPages downloads each page of a paginated api to a file, such as backing up an api.
+// The urlTemplate and destPattern are go templates, {{page}} is the page number, {{cursor}} is the cursor
+// of the page set by Cursor, it's empty for the first page, query-escaped in the url and path-escaped in the destPattern,
+// such as DownloadPages("https://a.com/items?page={{page}}", "backup/{{page}}.json").
+// A cursor that makes the file path leave the dir of the destPattern is an error.
+// The pages stop at the first page that has no items, see Items.
+// The existing files are skipped, so a failed download can resume by running it again.
+func DownloadPages(urlTemplate, destPattern string) *DownloadPagesContext {
+	ctx := &DownloadPagesContext{start: 1, retries: 3}
+
+	funcs := template.FuncMap{"page": func() int { return 0 }, "cursor": func() string { return "" }}
+	ctx.url, ctx.tmplErr = template.New("url").Funcs(funcs).Parse(urlTemplate)
+	if ctx.tmplErr == nil {
+		ctx.dest, ctx.tmplErr = template.New("dest").Funcs(funcs).Parse(destPattern)
+	}
+	return ctx
+}
+
+// Context sets the context of the download
+func (ctx *DownloadPagesContext) Context(c context.Context) *DownloadPagesContext {
+	ctx.context = c
+	return ctx
+}
+
+// Start sets the number of the first page, default is 1
+func (ctx *DownloadPagesContext) Start(page int) *DownloadPagesContext {
+	ctx.start = page
+	return ctx
+}
+
+// MaxPages stops after n pages, default is 0, which means no limit
+func (ctx *DownloadPagesContext) MaxPages(n int) *DownloadPagesContext {
+	ctx.maxPages = n
+	return ctx
+}
+
+// Items sets the gjson path of the items in the page, such as "data.items".
+// The default is the whole page, which means an empty page or an empty json array has no items.
+func (ctx *DownloadPagesContext) Items(path string) *DownloadPagesContext {
+	ctx.items = path
+	return ctx
+}
+
+// Cursor sets the gjson path of the cursor of the next page in the page, such as "meta.next",
+// the pages also stop when the cursor is empty.
+func (ctx *DownloadPagesContext) Cursor(path string) *DownloadPagesContext {
+	ctx.cursor = path
+	return ctx
+}
+
+// Retry sets how many times to retry a failed page, default is 3
+func (ctx *DownloadPagesContext) Retry(n int) *DownloadPagesContext {
+	ctx.retries = n
+	return ctx
+}
+
+// Do the download, each page is written the same way as Download
+func (ctx *DownloadPagesContext) Do() error {
+	if ctx.tmplErr != nil {
+		return ctx.tmplErr
+	}
+	if ctx.context == nil {
+		ctx.context = context.Background()
+	}
+
+	cursor := ""
+	for page := ctx.start; ctx.maxPages <= 0 || page < ctx.start+ctx.maxPages; page++ {
+		dest, err := ctx.renderDest(page, cursor)
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(dest); err != nil {
+			u, err := renderPage(ctx.url, page, url.QueryEscape(cursor))
+			if err != nil {
+				return err
+			}
+
+			if err := os.MkdirAll(filepath.Dir(dest), 0775); err != nil {
+				return err
+			}
+
+			err = Download(u).Context(ctx.context).To(dest).Retry(ctx.retries).Do()
+			if err != nil {
+				return err
+			}
+		}
+
+		body, err := os.ReadFile(dest)
+		if err != nil {
+			return err
+		}
+
+		if !ctx.hasItems(body) {
+			// the last page may get new items later, so don't keep it for the resume
+			return os.Remove(dest)
+		}
+
+		if ctx.cursor != "" {
+			cursor = gjson.GetBytes(body, ctx.cursor).String()
+			if cursor == "" {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// MustDo panic version of Do
+func (ctx *DownloadPagesContext) MustDo() {
+	utils.E(ctx.Do())
+}
+
+func (ctx *DownloadPagesContext) hasItems(body []byte) bool {
+	if ctx.items == "" {
+		s := strings.TrimSpace(string(body))
+		return s != "" && s != "[]" && s != "null"
+	}
+
+	items := gjson.GetBytes(body, ctx.items)
+	return items.Exists() && !(items.IsArray() && len(items.Array()) == 0)
+}
+
+// the cursor comes from the remote body, it can't be trusted as a part of the file path
+func (ctx *DownloadPagesContext) renderDest(page int, cursor string) (string, error) {
+	dest, err := renderPage(ctx.dest, page, url.PathEscape(cursor))
+	if err != nil || cursor == "" {
+		return dest, err
+	}
+
+	base, err := renderPage(ctx.dest, page, "")
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.Dir(base), dest)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the cursor %q leaves the dir of the destination: %s", cursor, dest)
+	}
+	return dest, nil
+}
+
+func renderPage(t *template.Template, page int, cursor string) (string, error) {
+	var b strings.Builder
+	err := t.Funcs(template.FuncMap{
+		"page":   func() string { return strconv.Itoa(page) },
+		"cursor": func() string { return cursor },
+	}).Execute(&b, nil)
+	return b.String(), err
+}
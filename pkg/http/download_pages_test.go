@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestDownloadPages() {
+	path, url := s.path()
+	var requests int32
+	s.router.GET(path, func(c kit.GinContext) {
+		atomic.AddInt32(&requests, 1)
+		page, _ := strconv.Atoi(c.Query("page"))
+		items := []int{}
+		if page <= 3 {
+			items = append(items, page)
+		}
+		c.JSON(200, map[string]interface{}{"items": items})
+	})
+
+	dir := s.T().TempDir()
+	kit.DownloadPages(url+"?page={{page}}", dir+"/{{page}}.json").Items("items").MustDo()
+	s.EqualValues(4, atomic.LoadInt32(&requests))
+	s.Equal(`{"items":[2]}`, kit.E(kit.ReadString(filepath.Join(dir, "2.json")))[0])
+	s.False(kit.Exists(filepath.Join(dir, "4.json")))
+
+	// resume, only the last empty page is requested again
+	kit.DownloadPages(url+"?page={{page}}", dir+"/{{page}}.json").Items("items").MustDo()
+	s.EqualValues(5, atomic.LoadInt32(&requests))
+}
+
+func (s *RequestSuite) TestDownloadPagesCursor() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		next := map[string]string{"": "a b", "a b": "c"}[c.Query("cursor")]
+		b, _ := json.Marshal(map[string]string{"cursor": c.Query("cursor"), "next": next})
+		c.String(200, string(b))
+	})
+
+	dir := s.T().TempDir()
+	kit.DownloadPages(url+"?cursor={{cursor}}", dir+"/{{page}}.json").Cursor("next").MustDo()
+
+	s.Equal(`{"cursor":"a b","next":"c"}`, kit.E(kit.ReadString(filepath.Join(dir, "2.json")))[0])
+	s.Equal(`{"cursor":"c","next":""}`, kit.E(kit.ReadString(filepath.Join(dir, "3.json")))[0])
+	s.False(kit.Exists(filepath.Join(dir, "4.json")))
+
+	s.Error(kit.DownloadPages("{{", "").Do())
+}
+
+func (s *RequestSuite) TestDownloadPagesCursorPath() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		next := map[string]string{"": "../x", "../x": ".."}[c.Query("cursor")]
+		c.String(200, `{"next":"`+next+`"}`)
+	})
+
+	dir := s.T().TempDir()
+	dest := filepath.Join(dir, "pages")
+
+	// the slash in the cursor is escaped
+	kit.DownloadPages(url+"?cursor={{cursor}}", dest+"/{{cursor}}.json").Cursor("next").MustDo()
+	s.True(kit.Exists(filepath.Join(dest, "..%2Fx.json")))
+	s.True(kit.Exists(filepath.Join(dest, "...json")))
+	s.False(kit.Exists(filepath.Join(dir, "x.json")))
+
+	// the ".." can't be escaped, it's rejected
+	err := kit.DownloadPages(url+"?cursor={{cursor}}", dest+"/{{cursor}}/p.json").Cursor("next").Do()
+	s.ErrorContains(err, "leaves the dir of the destination")
+	s.False(kit.Exists(filepath.Join(dir, "p.json")))
+}
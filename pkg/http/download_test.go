@@ -0,0 +1,70 @@
+package http_test
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestDownloadParts() {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+
+	lock := sync.Mutex{}
+	ranges := []string{}
+	failed := false
+
+	path, url := s.path()
+	s.router.Any(path, func(c kit.GinContext) {
+		lock.Lock()
+		r := c.GetHeader("Range")
+		if r != "" {
+			ranges = append(ranges, r)
+		}
+		// fail the last part once to test the retry
+		fail := r == "bytes=750-999" && !failed
+		failed = failed || fail
+		lock.Unlock()
+
+		if fail {
+			c.Status(500)
+			return
+		}
+		http.ServeContent(c.Writer, c.Request, "", time.Time{}, bytes.NewReader(data))
+	})
+
+	file := filepath.Join(s.T().TempDir(), "out")
+	kit.Download(url).To(file).Parts(4).MustDo()
+
+	s.Equal(data, kit.E(kit.ReadFile(file))[0].([]byte))
+	s.Len(ranges, 5)
+	s.Contains(ranges, "bytes=0-249")
+}
+
+func (s *RequestSuite) TestDownloadNoRange() {
+	path, url := s.path()
+	s.router.Any(path, func(c kit.GinContext) {
+		s.Empty(c.GetHeader("Range"))
+		c.String(200, "ok")
+	})
+
+	file := filepath.Join(s.T().TempDir(), "out")
+	kit.Download(url).To(file).Parts(4).MustDo()
+
+	s.Equal("ok", string(kit.E(kit.ReadFile(file))[0].([]byte)))
+}
+
+func (s *RequestSuite) TestDownloadErr() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.Status(404)
+	})
+
+	file := filepath.Join(s.T().TempDir(), "out")
+	s.EqualError(kit.Download(url).To(file).Retry(0).Do(), "download "+url+": unexpected status 404")
+	s.False(kit.FileExists(file))
+	s.False(kit.FileExists(file + ".download"))
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultAcceptEncoding the encodings that Bytes can decode
+const DefaultAcceptEncoding = "br, zstd, gzip, deflate"
+
+// AcceptEncoding sets the Accept-Encoding header, DefaultAcceptEncoding will be used if encodings is empty.
+// The response body is decoded by its Content-Encoding when reading it via Bytes, String, or JSON.
+// Once the header is set, the go transport won't decode gzip for us, so it's also handled.
+func (ctx *ReqContext) AcceptEncoding(encodings ...string) *ReqContext {
+	if len(encodings) == 0 {
+		ctx.header.Set("Accept-Encoding", DefaultAcceptEncoding)
+	} else {
+		ctx.header.Set("Accept-Encoding", strings.Join(encodings, ", "))
+	}
+	return ctx
+}
+
+// decodeBody wraps the body with the decoders of the Content-Encoding of the response
+func decodeBody(res *http.Response) (io.ReadCloser, error) {
+	body := res.Body
+
+	list := strings.Split(res.Header.Get("Content-Encoding"), ",")
+
+	// the encodings are listed in the order they were applied
+	for i := len(list) - 1; i >= 0; i-- {
+		var r io.Reader
+		var err error
+
+		switch strings.ToLower(strings.TrimSpace(list[i])) {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(body)
+		case "deflate":
+			r = flate.NewReader(body)
+		case "br":
+			r = brotli.NewReader(body)
+		case "zstd":
+			var d *zstd.Decoder
+			d, err = zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+			if err == nil {
+				r = d.IOReadCloser()
+			}
+		default:
+			err = fmt.Errorf("unsupported content encoding %q", list[i])
+		}
+
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+
+		body = &decodedBody{r, body}
+	}
+
+	return body, nil
+}
+
+// decodedBody closes the decoder and the underlying body
+type decodedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	if c, ok := b.Reader.(io.Closer); ok {
+		_ = c.Close()
+	}
+	return b.body.Close()
+}
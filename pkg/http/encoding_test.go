@@ -0,0 +1,48 @@
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestContentEncoding() {
+	encoders := map[string]func(w io.Writer) io.WriteCloser{
+		"br": func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+		"zstd": func(w io.Writer) io.WriteCloser {
+			e, _ := zstd.NewWriter(w)
+			return e
+		},
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	}
+
+	for name, encoder := range encoders {
+		buf := &bytes.Buffer{}
+		w := encoder(buf)
+		_, _ = w.Write([]byte(`{"a": "ok"}`))
+		kit.E(w.Close())
+		data := buf.Bytes()
+
+		path, url := s.path()
+		s.router.GET(path, func(c kit.GinContext) {
+			s.Equal(kit.DefaultAcceptEncoding, c.GetHeader("Accept-Encoding"))
+			c.Header("Content-Encoding", name)
+			c.Data(200, "application/json", data)
+		})
+
+		s.Equal("ok", kit.Req(url).AcceptEncoding().MustJSON().Get("a").String(), name)
+	}
+
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		s.Equal("x", c.GetHeader("Accept-Encoding"))
+		c.Header("Content-Encoding", "x")
+		c.String(200, "ok")
+	})
+	_, err := kit.Req(url).AcceptEncoding("x").String()
+	s.EqualError(err, `unsupported content encoding "x"`)
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/tidwall/gjson"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// ExpectError the response doesn't meet the expectation
+type ExpectError struct {
+	// Kind is one of "status", "json", "header", "body"
+	Kind string
+	// Key is the json path or the header name
+	Key      string
+	Expected interface{}
+	Actual   interface{}
+}
+
+func (e *ExpectError) Error() string {
+	title := "expect " + e.Kind
+	if e.Key != "" {
+		title += " " + e.Key
+	}
+
+	expected, actual := formatExpect(e.Expected), formatExpect(e.Actual)
+
+	if !strings.Contains(expected, "\n") && !strings.Contains(actual, "\n") {
+		return fmt.Sprintf("%s: expected %s, got %s", title, expected, actual)
+	}
+
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(expected),
+		B:        difflib.SplitLines(actual),
+		FromFile: "expected",
+		ToFile:   "actual",
+		Context:  2,
+	})
+	return title + ":\n" + diff
+}
+
+func formatExpect(v interface{}) string {
+	if s, ok := v.(string); ok && strings.Contains(s, "\n") {
+		return s
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// ExpectStatus checks the status code of the response
+func (ctx *ReqContext) ExpectStatus(code int) error {
+	res, err := ctx.Response()
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != code {
+		return &ExpectError{Kind: "status", Expected: code, Actual: res.StatusCode}
+	}
+	return nil
+}
+
+// MustExpectStatus ...
+func (ctx *ReqContext) MustExpectStatus(code int) *ReqContext {
+	utils.E(ctx.ExpectStatus(code))
+	return ctx
+}
+
+// ExpectJSON checks the value of the path in the json body, the path syntax is the same as JSON.
+// The value is compared as json, so 1 equals 1.0, and a struct equals the object with the same fields.
+func (ctx *ReqContext) ExpectJSON(path string, value interface{}) error {
+	b, err := ctx.Bytes()
+	if err != nil {
+		return err
+	}
+
+	expected, err := normalizeJSON(value)
+	if err != nil {
+		return err
+	}
+
+	r := gjson.GetBytes(b, path)
+	var actual interface{}
+	if r.Exists() {
+		actual = r.Value()
+	}
+
+	if !r.Exists() || !reflect.DeepEqual(expected, actual) {
+		return &ExpectError{Kind: "json", Key: path, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// MustExpectJSON ...
+func (ctx *ReqContext) MustExpectJSON(path string, value interface{}) *ReqContext {
+	utils.E(ctx.ExpectJSON(path, value))
+	return ctx
+}
+
+// ExpectHeader checks the value of the response header
+func (ctx *ReqContext) ExpectHeader(key, value string) error {
+	res, err := ctx.Response()
+	if err != nil {
+		return err
+	}
+	if actual := res.Header.Get(key); actual != value {
+		return &ExpectError{Kind: "header", Key: key, Expected: value, Actual: actual}
+	}
+	return nil
+}
+
+// MustExpectHeader ...
+func (ctx *ReqContext) MustExpectHeader(key, value string) *ReqContext {
+	utils.E(ctx.ExpectHeader(key, value))
+	return ctx
+}
+
+// ExpectBodyContains checks if the body contains the s
+func (ctx *ReqContext) ExpectBodyContains(s string) error {
+	body, err := ctx.String()
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(body, s) {
+		return &ExpectError{Kind: "body", Expected: s, Actual: body}
+	}
+	return nil
+}
+
+// MustExpectBodyContains ...
+func (ctx *ReqContext) MustExpectBodyContains(s string) *ReqContext {
+	utils.E(ctx.ExpectBodyContains(s))
+	return ctx
+}
+
+// convert the value to the types that gjson uses, such as float64 for numbers
+func normalizeJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return gjson.ParseBytes(b).Value(), nil
+}
@@ -0,0 +1,47 @@
+package http_test
+
+import (
+	"errors"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestExpect() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.Header("X-A", "ok")
+		c.JSON(201, map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": []string{"x", "y"}}})
+	})
+
+	req := kit.Req(url).
+		MustExpectStatus(201).
+		MustExpectHeader("X-A", "ok").
+		MustExpectJSON("a", 1).
+		MustExpectJSON("b", struct {
+			C []string `json:"c"`
+		}{[]string{"x", "y"}}).
+		MustExpectBodyContains(`"a":1`)
+
+	var e *kit.ExpectError
+
+	err := req.ExpectStatus(200)
+	s.True(errors.As(err, &e))
+	s.Equal("status", e.Kind)
+	s.EqualError(err, "expect status: expected 200, got 201")
+
+	s.EqualError(req.ExpectHeader("X-A", "no"), `expect header X-A: expected "no", got "ok"`)
+	s.EqualError(req.ExpectJSON("x", 1), "expect json x: expected 1, got null")
+	s.EqualError(req.ExpectJSON("b.c", []string{"x", "z"}), `expect json b.c:
+--- expected
++++ actual
+@@ -1,4 +1,4 @@
+ [
+   "x",
+-  "z"
++  "y"
+ ]
+`)
+	s.EqualError(req.ExpectBodyContains("no"), `expect body: expected "no", got "{\"a\":1,\"b\":{\"c\":[\"x\",\"y\"]}}"`)
+
+	s.Error(kit.Req("http://127.0.0.1:1").ExpectStatus(200))
+}
@@ -0,0 +1,188 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// URLs sets the base urls of the replicated services, the url of Req is joined to them, such as
+// Req("/users?id=1").URLs("http://a.internal", "http://b.internal"). When the request fails to connect or
+// the response is 5xx, the next base url is tried. A host that fails 3 times in a row is skipped for 30s
+// unless all the others are skipped too, the failures are counted per host and shared by all the requests
+// of the process. The response of the last try is kept if all of them return 5xx.
+func (ctx *ReqContext) URLs(primary string, fallbacks ...string) *ReqContext {
+	ctx.urls = append([]string{primary}, fallbacks...)
+	return ctx
+}
+
+// the url to request, the ctx.url is relative to the base url if URLs is set
+func (ctx *ReqContext) fullURL() string {
+	if ctx.urls == nil {
+		return ctx.url
+	}
+
+	base := ctx.base
+	if base == "" {
+		base = ctx.urls[0]
+	}
+
+	rel := ctx.url
+	if u, err := url.Parse(rel); err == nil && u.Host != "" {
+		rel = u.RequestURI()
+	}
+	if rel == "" || strings.HasPrefix(rel, "?") {
+		return base + rel
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(rel, "/")
+}
+
+func (ctx *ReqContext) failover() error {
+	// the tries use the context with one timeout for all of them, the ones of the caller are restored after
+	parent, timeout := ctx.context, ctx.timeout
+	defer func() { ctx.context, ctx.timeout = parent, timeout }()
+
+	c := parent
+	if c == nil {
+		c = context.Background()
+	}
+	var cancel context.CancelFunc = func() {}
+	if timeout != 0 {
+		c, cancel = context.WithTimeout(c, timeout)
+	}
+	ctx.context, ctx.timeout = c, 0
+
+	// the body is read once and replayed for each try
+	var raw []byte
+	if ctx.body != nil && ctx.stringBody == "" && ctx.jsonBody == nil && ctx.structBody == nil {
+		var err error
+		if raw, err = ioutil.ReadAll(ctx.body); err != nil {
+			cancel()
+			return ctx.wrapErr(err)
+		}
+	}
+
+	list := breakers.order(ctx.urls)
+
+	var err error
+	for i, base := range list {
+		ctx.base = base
+		ctx.request = nil
+		ctx.response = nil
+		if raw != nil {
+			ctx.body = bytes.NewReader(raw)
+		}
+
+		var req *http.Request
+		req, err = ctx.Request()
+		if err != nil {
+			cancel()
+			return ctx.wrapErr(err)
+		}
+
+		err = ctx.send(req)
+
+		var notAllowed *HostNotAllowedError
+		if c.Err() != nil || errors.As(err, &notAllowed) {
+			cancel()
+			return ctx.wrapErr(err)
+		}
+
+		if err == nil && ctx.response.StatusCode < 500 {
+			breakers.succeed(base)
+			break
+		}
+
+		breakers.fail(base)
+
+		if err == nil && i < len(list)-1 {
+			_ = ctx.response.Body.Close()
+		}
+	}
+
+	if err != nil {
+		cancel()
+		return ctx.wrapErr(err)
+	}
+
+	// the timeout covers reading the body
+	ctx.timeoutCancel = cancel
+	ctx.releaseOnClose()
+	return nil
+}
+
+// the failures of each host across the requests
+var breakers = &hostBreakers{list: map[string]*hostBreaker{}}
+
+const (
+	breakerFailures = 3
+	breakerCooldown = 30 * time.Second
+)
+
+type hostBreakers struct {
+	lock sync.Mutex
+	list map[string]*hostBreaker
+}
+
+type hostBreaker struct {
+	failures int
+	until    time.Time // the host is skipped until it
+}
+
+func breakerKey(base string) string {
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return base
+}
+
+// the bases with the open circuit are skipped, unless all of them are open
+func (b *hostBreakers) order(bases []string) []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	ok := []string{}
+	open := []string{}
+	for _, base := range bases {
+		if h := b.list[breakerKey(base)]; h != nil && now.Before(h.until) {
+			open = append(open, base)
+		} else {
+			ok = append(ok, base)
+		}
+	}
+	if len(ok) == 0 {
+		return open
+	}
+	return ok
+}
+
+func (b *hostBreakers) fail(base string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := breakerKey(base)
+	h := b.list[key]
+	if h == nil {
+		h = &hostBreaker{}
+		b.list[key] = h
+	}
+	h.failures++
+	if h.failures >= breakerFailures {
+		h.until = time.Now().Add(breakerCooldown)
+	}
+}
+
+func (b *hostBreakers) succeed(base string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.list, breakerKey(base))
+}
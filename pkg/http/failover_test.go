@@ -0,0 +1,71 @@
+package http_test
+
+import (
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestURLs() {
+	path, url := s.path()
+	base := strings.TrimSuffix(url, path)
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, c.Query("id"))
+	})
+	s.router.POST(path, func(c kit.GinContext) {
+		b, _ := ioutil.ReadAll(c.Request.Body)
+		c.String(200, string(b))
+	})
+
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	dead := "http://" + l.Addr().String()
+	_ = l.Close()
+
+	s.Equal("1", kit.Req(path+"?id=1").URLs(dead, base).MustString())
+	s.Equal("2", kit.Req(path).Query("id", "2").URLs(dead, base+"/").MustString())
+	s.Equal("3", kit.Req(url+"?id=3").Timeout(time.Second).URLs(dead, base).MustString())
+
+	// the body is replayed for the fallback
+	s.Equal("4", kit.Req(path).Post().Body(strings.NewReader("4")).URLs(dead, base).MustString())
+}
+
+func (s *RequestSuite) TestURLsCircuitBreaker() {
+	path, url := s.path()
+	base := strings.TrimSuffix(url, path)
+	local := strings.Replace(base, "127.0.0.1", "localhost", 1)
+
+	var failed, ok int32
+	s.router.GET(path, func(c kit.GinContext) {
+		if strings.HasPrefix(c.Request.Host, "localhost") {
+			atomic.AddInt32(&failed, 1)
+			c.String(500, "err")
+			return
+		}
+		atomic.AddInt32(&ok, 1)
+		c.String(200, "ok")
+	})
+
+	for i := 0; i < 5; i++ {
+		s.Equal("ok", kit.Req(path).URLs(local, base).MustString())
+	}
+
+	// the localhost is skipped after 3 failures in a row
+	s.EqualValues(3, failed)
+	s.EqualValues(5, ok)
+
+	// the response of the last try is kept
+	res := kit.Req(path).URLs(local).MustResponse()
+	s.Equal(500, res.StatusCode)
+	s.EqualValues(4, failed)
+
+	// the open host isn't tried when the others fail
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	dead := "http://" + l.Addr().String()
+	_ = l.Close()
+	s.Error(kit.Req(path).URLs(local, dead).Do())
+	s.EqualValues(4, failed)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// AllowHosts only allows the request and its redirects to the hosts that match one of the patterns,
+// such as "example.com", "*.example.com", or "localhost:8080" to also match the port.
+// The syntax of the pattern is the same as path.Match, the match is case-insensitive.
+// It protects the requests with the urls from external data against SSRF,
+// the blocked request fails with HostNotAllowedError before it's sent.
+func (ctx *ReqContext) AllowHosts(patterns ...string) *ReqContext {
+	ctx.allowHosts = patterns
+	return ctx
+}
+
+// HostNotAllowedError the host of the request doesn't match the AllowHosts
+type HostNotAllowedError struct {
+	Host string
+}
+
+func (e *HostNotAllowedError) Error() string {
+	return fmt.Sprintf("host %s is not allowed", e.Host)
+}
+
+func (ctx *ReqContext) checkHost(u *url.URL) error {
+	if ctx.allowHosts == nil {
+		return nil
+	}
+
+	for _, pattern := range ctx.allowHosts {
+		host := u.Hostname()
+		if strings.Contains(pattern, ":") {
+			host = u.Host
+		}
+
+		matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(host))
+		if err != nil {
+			return err
+		}
+		if matched {
+			return nil
+		}
+	}
+
+	return &HostNotAllowedError{u.Host}
+}
@@ -0,0 +1,34 @@
+package http_test
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestAllowHosts() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		if c.Query("r") != "" {
+			c.Redirect(302, strings.Replace(url, "127.0.0.1", "localhost", 1))
+			return
+		}
+		c.String(200, "ok")
+	})
+
+	s.Equal("ok", kit.Req(url).AllowHosts("example.com", "127.0.0.*").MustString())
+	s.Equal("ok", kit.Req(url).AllowHosts("LOCALHOST", "127.0.0.1:*").MustString())
+
+	_, err := kit.Req(url).AllowHosts("example.com", "127.0.0.1:1").String()
+	var e *kit.HostNotAllowedError
+	s.True(errors.As(err, &e))
+	s.Equal(strings.TrimPrefix(strings.TrimSuffix(url, path), "http://"), e.Host)
+
+	// the redirect to another host is blocked
+	_, err = kit.Req(url + "?r=1").AllowHosts("127.0.0.1").String()
+	s.True(errors.As(err, &e))
+	s.True(strings.HasPrefix(e.Host, "localhost:"))
+
+	s.Equal("ok", kit.Req(url+"?r=1").AllowHosts("127.0.0.1", "localhost").MustString())
+}
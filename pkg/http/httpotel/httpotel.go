@@ -0,0 +1,86 @@
+// Package httpotel traces the requests of kit with OpenTelemetry, it's a separate package
+// so that the kit doesn't depend on otel.
+package httpotel
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	khttp "github.com/ysmood/kit/pkg/http"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace emits a client span for the request with the tracer, the span has the method, url,
+// and status of the request, the userinfo and query values of the url are redacted.
+// The span context will be injected into the request header with the global propagator of otel,
+// so the server can continue the trace. Such as:
+//
+//	kit.Req(u).Trace(httpotel.Trace(tracer)).Do()
+func Trace(tracer trace.Tracer) khttp.TraceFunc {
+	return func(ctx *khttp.ReqContext, req *http.Request) (*http.Request, func(*http.Response, error)) {
+		method := req.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		c, span := tracer.Start(req.Context(), "HTTP "+method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("url.full", redact(req.URL)),
+				attribute.String("server.address", req.URL.Hostname()),
+			),
+		)
+
+		if id := ctx.ID(); id != "" {
+			span.SetAttributes(attribute.String("http.request.id", id))
+		}
+
+		req = req.WithContext(c)
+		otel.GetTextMapPropagator().Inject(c, propagation.HeaderCarrier(req.Header))
+
+		return req, func(res *http.Response, err error) {
+			endSpan(span, res, err)
+		}
+	}
+}
+
+func endSpan(span trace.Span, res *http.Response, err error) {
+	defer span.End()
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= 400 {
+		span.SetStatus(codes.Error, res.Status)
+	}
+}
+
+// the url may carry the credentials, such as the password or the token in the query
+func redact(u *url.URL) string {
+	r := *u
+	if r.User != nil {
+		r.User = url.UserPassword("REDACTED", "REDACTED")
+	}
+
+	if r.RawQuery != "" {
+		pairs := strings.Split(r.RawQuery, "&")
+		for i, p := range pairs {
+			if k, _, ok := strings.Cut(p, "="); ok {
+				pairs[i] = k + "=REDACTED"
+			}
+		}
+		r.RawQuery = strings.Join(pairs, "&")
+	}
+
+	return r.String()
+}
@@ -0,0 +1,83 @@
+package httpotel_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/kit/pkg/http/httpotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type testTracer struct {
+	noop.Tracer
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(c context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	span := &testSpan{name: name, kind: cfg.SpanKind(), attrs: cfg.Attributes()}
+	t.spans = append(t.spans, span)
+	return c, span
+}
+
+type testSpan struct {
+	noop.Span
+	name   string
+	kind   trace.SpanKind
+	attrs  []attribute.KeyValue
+	status codes.Code
+	ended  bool
+}
+
+func (s *testSpan) SetAttributes(kv ...attribute.KeyValue) { s.attrs = append(s.attrs, kv...) }
+
+func (s *testSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *testSpan) End(...trace.SpanEndOption) { s.ended = true }
+
+func (s *testSpan) attr(key string) string {
+	for _, kv := range s.attrs {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTrace(t *testing.T) {
+	server := kit.MustServer(":0")
+	server.Engine.POST("/a", func(c kit.GinContext) {
+		c.String(500, "")
+	})
+	go server.MustDo()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	host := "127.0.0.1:" + port
+
+	tracer := &testTracer{}
+	kit.Req("http://u:secret@" + host + "/a?token=secret&b").Post().RequestID("").
+		Trace(httpotel.Trace(tracer)).MustDo()
+
+	assert.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "HTTP POST", span.name)
+	assert.Equal(t, trace.SpanKindClient, span.kind)
+	assert.Equal(t, "POST", span.attr("http.request.method"))
+	assert.Equal(t, "http://REDACTED:REDACTED@"+host+"/a?token=REDACTED&b", span.attr("url.full"))
+	assert.Equal(t, "127.0.0.1", span.attr("server.address"))
+	assert.NotEmpty(t, span.attr("http.request.id"))
+	assert.Equal(t, "500", span.attr("http.response.status_code"))
+	assert.Equal(t, codes.Error, span.status)
+	assert.True(t, span.ended)
+
+	err := kit.Req("http://127.0.0.1:1").Trace(httpotel.Trace(tracer)).Do()
+	assert.Error(t, err)
+	assert.Equal(t, "GET", tracer.spans[1].attr("http.request.method"))
+	assert.Equal(t, codes.Error, tracer.spans[1].status)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/tidwall/gjson"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// JSONStream sends request and parses the body item by item without buffering the whole body,
+// the body can be NDJSON or a top-level array. Return false in fn to stop reading.
+// The MaxBodySize still applies.
+func (ctx *ReqContext) JSONStream(fn func(item utils.JSONResult) bool) error {
+	res, err := ctx.Response()
+	if err != nil {
+		return err
+	}
+	defer ctx.cancelTimeout()
+
+	body, err := decodeBody(res)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = body.Close() }()
+
+	var r io.Reader = body
+	if ctx.maxBodySize > 0 {
+		r = &bodyLimiter{r: body, limit: ctx.maxBodySize}
+	}
+	br := bufio.NewReader(r)
+
+	array, err := isJSONArray(br)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(br)
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for !array || dec.More() {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF && !array {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		item := gjson.ParseBytes(raw)
+		if !fn(&item) {
+			return nil
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// MustJSONStream panic version of JSONStream
+func (ctx *ReqContext) MustJSONStream(fn func(item utils.JSONResult) bool) {
+	utils.E(ctx.JSONStream(fn))
+}
+
+// peek the first non-space byte
+func isJSONArray(r *bufio.Reader) (bool, error) {
+	for {
+		b, err := r.Peek(1)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = r.ReadByte()
+		default:
+			return b[0] == '[', nil
+		}
+	}
+}
+
+// returns BodyTooLargeError when more than limit bytes are read
+type bodyLimiter struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *bodyLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.limit {
+		return n, &BodyTooLargeError{l.limit}
+	}
+	return n, err
+}
@@ -0,0 +1,61 @@
+package http_test
+
+import (
+	"errors"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestJSONStreamNDJSON() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, "{\"id\": 1}\n{\"id\": 2}\n\n{\"id\": 3}\n")
+	})
+
+	ids := []int64{}
+	kit.Req(url).MustJSONStream(func(item kit.JSONResult) bool {
+		ids = append(ids, item.Get("id").Int())
+		return true
+	})
+	s.Equal([]int64{1, 2, 3}, ids)
+}
+
+func (s *RequestSuite) TestJSONStreamArray() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, ` [{"id": 1}, {"id": 2}, 3]`)
+	})
+
+	items := []string{}
+	kit.Req(url).MustJSONStream(func(item kit.JSONResult) bool {
+		items = append(items, item.Raw)
+		return true
+	})
+	s.Equal([]string{`{"id": 1}`, `{"id": 2}`, `3`}, items)
+
+	count := 0
+	kit.Req(url).MustJSONStream(func(item kit.JSONResult) bool {
+		count++
+		return false
+	})
+	s.Equal(1, count)
+}
+
+func (s *RequestSuite) TestJSONStreamErr() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, `[{"id": 1}, {"id": `)
+	})
+
+	count := 0
+	err := kit.Req(url).JSONStream(func(item kit.JSONResult) bool {
+		count++
+		return true
+	})
+	s.Error(err)
+	s.Equal(1, count)
+
+	err = kit.Req(url).MaxBodySize(5).JSONStream(func(item kit.JSONResult) bool { return true })
+	var e *kit.BodyTooLargeError
+	s.True(errors.As(err, &e))
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// PingResult the result of Ping
+type PingResult struct {
+	Status  int // the http status code
+	Latency time.Duration
+}
+
+// OK the status is 2xx or 3xx
+func (r *PingResult) OK() bool {
+	return r.Status >= 200 && r.Status < 400
+}
+
+// Class the class of the status, such as 2 for 2xx, 5 for 5xx
+func (r *PingResult) Class() int {
+	return r.Status / 100
+}
+
+// Ping checks the url with a HEAD request, if the server doesn't support HEAD it falls back to GET.
+// The error is only for the network failures, use PingResult.OK to check the status.
+func Ping(url string, timeout time.Duration) (*PingResult, error) {
+	c, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	status, err := pingStatus(c, http.MethodHead, url)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = pingStatus(c, http.MethodGet, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &PingResult{Status: status, Latency: time.Since(start)}, nil
+}
+
+func pingStatus(c context.Context, method, url string) (int, error) {
+	res, err := Req(url).Context(c).Method(method).Response()
+	if err != nil {
+		return 0, err
+	}
+	_ = res.Body.Close()
+	return res.StatusCode, nil
+}
+
+// MustPing panic version of Ping
+func MustPing(url string, timeout time.Duration) *PingResult {
+	return utils.E(Ping(url, timeout))[0].(*PingResult)
+}
+
+// WaitOK pings the url until the status is OK or the timeout is reached.
+// It's useful to wait for a service to be ready.
+func WaitOK(url string, timeout time.Duration) error {
+	c, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	interval := 100 * time.Millisecond
+	last := "none"
+
+	err := utils.Retry(c, utils.BackoffSleeper(interval, interval, nil), func() (bool, error) {
+		deadline, _ := c.Deadline()
+		res, err := Ping(url, time.Until(deadline))
+		if err != nil {
+			last = err.Error()
+			return false, nil
+		}
+		last = fmt.Sprint("status ", res.Status)
+		return res.OK(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s is not ok after %v, last result: %s", url, timeout, last)
+	}
+	return nil
+}
+
+// MustWaitOK panic version of WaitOK
+func MustWaitOK(url string, timeout time.Duration) {
+	utils.E(WaitOK(url, timeout))
+}
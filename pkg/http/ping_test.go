@@ -0,0 +1,68 @@
+package http_test
+
+import (
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestPing() {
+	path, url := s.path()
+
+	s.router.HEAD(path, func(c kit.GinContext) {
+		c.Status(204)
+	})
+
+	res := kit.MustPing(url, time.Second)
+	s.Equal(204, res.Status)
+	s.Equal(2, res.Class())
+	s.True(res.OK())
+}
+
+func (s *RequestSuite) TestPingFallback() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(503, "")
+	})
+	s.router.HEAD(path, func(c kit.GinContext) {
+		c.Status(405)
+	})
+
+	res := kit.MustPing(url, time.Second)
+	s.Equal(503, res.Status)
+	s.False(res.OK())
+}
+
+func (s *RequestSuite) TestPingErr() {
+	_, err := kit.Ping("http://127.0.0.1:1", time.Second)
+	s.Error(err)
+}
+
+func (s *RequestSuite) TestWaitOK() {
+	path, url := s.path()
+
+	count := 0
+	s.router.HEAD(path, func(c kit.GinContext) {
+		count++
+		if count < 3 {
+			c.Status(503)
+			return
+		}
+		c.Status(200)
+	})
+
+	kit.MustWaitOK(url, time.Minute)
+	s.Equal(3, count)
+}
+
+func (s *RequestSuite) TestWaitOKTimeout() {
+	path, url := s.path()
+
+	s.router.HEAD(path, func(c kit.GinContext) {
+		c.Status(500)
+	})
+
+	err := kit.WaitOK(url, 300*time.Millisecond)
+	s.EqualError(err, url+" is not ok after 300ms, last result: status 500")
+}
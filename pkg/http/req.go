@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -33,20 +34,43 @@ type ReqContext struct {
 	host       string
 	header     http.Header
 	jsonBody   interface{}
+	structBody interface{}
 	stringBody string
 	body       io.Reader
 	resBytes   []byte
 	proxy      string
 
+	maxBodySize int64
+	spool       int64
+	spooled     ReqBody
+
+	reqIDHeader string
+	reqID       string
+	trace       TraceFunc
+
+	jar       http.CookieJar
+	noCookies bool
+	resolver  Resolver
+
+	allowHosts   []string
+	urls         []string
+	base         string // the base url of the current try of the URLs
+	maxRedirects int    // negative means the default policy of the client
+	onRedirect   func(req *http.Request, via []*http.Request) error
+
 	timeout       time.Duration
 	timeoutCancel func()
+
+	stats     *reqStats
+	connStats *ConnStats
 }
 
 // Req creates http request instance
 func Req(url string) *ReqContext {
 	return &ReqContext{
-		header: http.Header{},
-		url:    url,
+		header:       http.Header{},
+		url:          url,
+		maxRedirects: -1,
 	}
 }
 
@@ -56,7 +80,9 @@ func (ctx *ReqContext) Context(c context.Context) *ReqContext {
 	return ctx
 }
 
-// Timeout sets the timeout of the request, it will inherit the Context
+// Timeout sets the timeout of the request, it will inherit the Context.
+// The timeout covers reading the body, including the body of the Response, so read it before the deadline.
+// The timer is released when the body is closed.
 func (ctx *ReqContext) Timeout(d time.Duration) *ReqContext {
 	ctx.timeout = d
 	return ctx
@@ -80,6 +106,102 @@ func (ctx *ReqContext) Proxy(url string) *ReqContext {
 	return ctx
 }
 
+// Jar sets the cookie jar, share a jar across requests to keep the session between them.
+// By default each request has its own jar, so the cookies only live through its redirects.
+func (ctx *ReqContext) Jar(jar http.CookieJar) *ReqContext {
+	ctx.jar = jar
+	ctx.noCookies = false
+	return ctx
+}
+
+// NoCookies disables the cookie handling, the cookies set by the server will be ignored
+func (ctx *ReqContext) NoCookies() *ReqContext {
+	ctx.jar = nil
+	ctx.noCookies = true
+	return ctx
+}
+
+// MaxBodySize limits the size of the response body, reading more than n bytes will abort
+// with BodyTooLargeError. The limit applies to the decompressed body, so it also guards
+// against decompression bombs.
+func (ctx *ReqContext) MaxBodySize(n int64) *ReqContext {
+	ctx.maxBodySize = n
+	return ctx
+}
+
+// NoRedirect don't follow redirects, the redirect response itself will be returned
+func (ctx *ReqContext) NoRedirect() *ReqContext {
+	return ctx.MaxRedirects(0)
+}
+
+// MaxRedirects follows at most n redirects, following more will fail with an error.
+// If n is 0, the redirect response itself will be returned.
+func (ctx *ReqContext) MaxRedirects(n int) *ReqContext {
+	ctx.maxRedirects = n
+	return ctx
+}
+
+// OnRedirect sets the hook called before following each redirect, req is the upcoming request
+// and via are the requests made so far, the oldest first.
+// The hook can modify req, such as restoring the Authorization header the client drops across hosts.
+// Return an error to stop, return http.ErrUseLastResponse to stop and use the redirect response.
+func (ctx *ReqContext) OnRedirect(fn func(req *http.Request, via []*http.Request) error) *ReqContext {
+	ctx.onRedirect = fn
+	return ctx
+}
+
+func (ctx *ReqContext) checkRedirect(req *http.Request, via []*http.Request) error {
+	if ctx.maxRedirects == 0 {
+		return http.ErrUseLastResponse
+	}
+
+	if err := ctx.checkHost(req.URL); err != nil {
+		return err
+	}
+
+	limit := ctx.maxRedirects
+	if limit < 0 {
+		limit = 10 // the same as the default client
+	}
+	if len(via) > limit {
+		return fmt.Errorf("stopped after %d redirects", limit)
+	}
+
+	if ctx.onRedirect != nil {
+		return ctx.onRedirect(req, via)
+	}
+	return nil
+}
+
+// BodyTooLargeError the response body exceeds the MaxBodySize
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds the limit of %d bytes", e.Limit)
+}
+
+// ReqIDHeader the default header name of RequestID
+const ReqIDHeader = "X-Request-ID"
+
+// RequestID injects a generated id into the header of the request, so the request can be traced
+// in the server logs. The default header is ReqIDHeader if header is empty.
+// If the header is already set, its value will be used as the id.
+// The errors of the request will contain the id, use ID to get it.
+func (ctx *ReqContext) RequestID(header string) *ReqContext {
+	if header == "" {
+		header = ReqIDHeader
+	}
+	ctx.reqIDHeader = header
+	return ctx
+}
+
+// ID returns the id injected by RequestID, it's available after the request is created
+func (ctx *ReqContext) ID() string {
+	return ctx.reqID
+}
+
 // Post sets the request method to POST
 func (ctx *ReqContext) Post() *ReqContext {
 	return ctx.Method(http.MethodPost)
@@ -176,28 +298,90 @@ func (ctx *ReqContext) getBody() (io.Reader, error) {
 		return bytes.NewReader(body), nil
 	}
 
+	if ctx.structBody != nil {
+		return ctx.getStructBody()
+	}
+
 	return ctx.body, nil
 }
 
 // Do the request
 func (ctx *ReqContext) Do() error {
+	if ctx.urls != nil {
+		return ctx.failover()
+	}
+
 	req, err := ctx.Request()
 	if err != nil {
+		return ctx.wrapErr(err)
+	}
+
+	err = ctx.send(req)
+	if err != nil {
+		ctx.cancelTimeout()
+		return ctx.wrapErr(err)
+	}
+	ctx.releaseOnClose()
+	return nil
+}
+
+func (ctx *ReqContext) send(req *http.Request) error {
+	var end func(*http.Response, error)
+	if ctx.trace != nil {
+		req, end = ctx.trace(ctx, req)
+	}
+
+	if err := ctx.checkHost(req.URL); err != nil {
 		return err
 	}
 
+	ctx.stats = &reqStats{conns: ctx.connStats}
+	req = ctx.stats.trace(req)
+
 	res, err := ctx.client.Do(req)
+	if end != nil {
+		end(res, err)
+	}
 	if err != nil {
 		return err
 	}
-	if ctx.timeout != 0 {
-		ctx.timeoutCancel()
-	}
+	ctx.stats.countResponse(res)
 	ctx.response = res
 
 	return nil
 }
 
+// the timeout covers reading the body, so it's canceled after the body is read
+func (ctx *ReqContext) cancelTimeout() {
+	if ctx.timeoutCancel != nil {
+		ctx.timeoutCancel()
+	}
+}
+
+// release the timer of the Timeout when the caller of the Response closes the body
+func (ctx *ReqContext) releaseOnClose() {
+	if ctx.timeoutCancel != nil && ctx.response != nil {
+		ctx.response.Body = &timeoutBody{ctx.response.Body, ctx.timeoutCancel}
+	}
+}
+
+type timeoutBody struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *timeoutBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func (ctx *ReqContext) wrapErr(err error) error {
+	if ctx.reqID == "" {
+		return err
+	}
+	return fmt.Errorf("request id %s: %w", ctx.reqID, err)
+}
+
 // MustDo send request, panic if request fails
 func (ctx *ReqContext) MustDo() {
 	utils.E(ctx.Do())
@@ -220,10 +404,23 @@ func (ctx *ReqContext) Request() (*http.Request, error) {
 	}
 
 	if ctx.client == nil {
-		cookie, _ := cookiejar.New(nil)
 		c := *http.DefaultClient // clone
 		ctx.client = &c
-		ctx.client.Jar = cookie
+		if ctx.jar == nil && !ctx.noCookies {
+			ctx.client.Jar, _ = cookiejar.New(nil)
+		}
+	}
+
+	if ctx.jar != nil || ctx.noCookies {
+		c := *ctx.client // clone, don't change the client passed by the user
+		ctx.client = &c
+		ctx.client.Jar = ctx.jar
+	}
+
+	if ctx.maxRedirects >= 0 || ctx.onRedirect != nil || ctx.allowHosts != nil {
+		c := *ctx.client // clone, don't change the client passed by the user
+		ctx.client = &c
+		ctx.client.CheckRedirect = ctx.checkRedirect
 	}
 
 	if ctx.proxy != "" {
@@ -232,6 +429,19 @@ func (ctx *ReqContext) Request() (*http.Request, error) {
 			return nil, err
 		}
 		transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+		if ctx.resolver != nil {
+			transport.DialContext = resolverDialer(ctx.resolver)
+		}
+		ctx.client.Transport = transport
+	}
+
+	if ctx.resolver != nil && ctx.proxy == "" {
+		transport, err := resolverTransport(ctx.client.Transport, ctx.resolver)
+		if err != nil {
+			return nil, err
+		}
+		c := *ctx.client // clone, don't change the client passed by the user
+		ctx.client = &c
 		ctx.client.Transport = transport
 	}
 
@@ -240,11 +450,19 @@ func (ctx *ReqContext) Request() (*http.Request, error) {
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx.context, ctx.method, ctx.url, body)
+	req, err := http.NewRequestWithContext(ctx.context, ctx.method, ctx.fullURL(), body)
 	if err != nil {
 		return nil, err
 	}
 
+	if ctx.reqIDHeader != "" {
+		ctx.reqID = ctx.header.Get(ctx.reqIDHeader)
+		if ctx.reqID == "" {
+			ctx.reqID = utils.RandString(8)
+			ctx.header.Set(ctx.reqIDHeader, ctx.reqID)
+		}
+	}
+
 	req.Header = ctx.header
 	req.Host = ctx.host
 
@@ -253,7 +471,7 @@ func (ctx *ReqContext) Request() (*http.Request, error) {
 	return ctx.request, nil
 }
 
-// Response sends request, get response
+// Response sends request, get response. Close the body of it after use, the Timeout covers reading the body.
 func (ctx *ReqContext) Response() (*http.Response, error) {
 	if ctx.response != nil {
 		return ctx.response, nil
@@ -278,10 +496,28 @@ func (ctx *ReqContext) Bytes() ([]byte, error) {
 		return nil, err
 	}
 
-	if ctx.resBytes == nil {
-		ctx.resBytes, err = readBody(res.Body)
+	if ctx.resBytes == nil && ctx.spooled != nil {
+		body := ctx.spooled
+		if _, err = body.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		ctx.resBytes, err = ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	} else if ctx.resBytes == nil {
+		body, err := decodeBody(res)
+		if err != nil {
+			ctx.cancelTimeout()
+			return nil, err
+		}
+		ctx.resBytes, err = readBody(body, ctx.maxBodySize)
+		ctx.cancelTimeout()
+		if err != nil {
+			return nil, err
+		}
 	}
-	return ctx.resBytes, err
+	return ctx.resBytes, nil
 }
 
 // MustBytes panic version of Bytes()
@@ -289,9 +525,16 @@ func (ctx *ReqContext) MustBytes() []byte {
 	return utils.E(ctx.Bytes())[0].([]byte)
 }
 
-func readBody(b io.ReadCloser) ([]byte, error) {
-	body, err := ioutil.ReadAll(b)
+// readBody reads all the body, if limit is greater than 0 the body size will be limited
+func readBody(b io.ReadCloser, limit int64) ([]byte, error) {
+	var r io.Reader = b
+	if limit > 0 {
+		r = io.LimitReader(b, limit+1)
+	}
+
+	body, err := ioutil.ReadAll(r)
 	if err != nil {
+		_ = b.Close()
 		return nil, err
 	}
 
@@ -300,6 +543,10 @@ func readBody(b io.ReadCloser) ([]byte, error) {
 		return nil, err
 	}
 
+	if limit > 0 && int64(len(body)) > limit {
+		return nil, &BodyTooLargeError{limit}
+	}
+
 	return body, nil
 }
 
@@ -405,3 +652,63 @@ func headerToArr(header http.Header) [][]string {
 	sort.Slice(headers, func(a, b int) bool { return headers[a][0] < headers[b][0] })
 	return headers
 }
+
+// PollUntil sends the request repeatedly until the predicate returns true or the timeout is reached.
+// The error of each attempt won't stop the polling, the last result will be returned,
+// if the last attempt failed its error will be returned instead of the timeout.
+// The body set by Body can only be sent once, use StringBody or JSONBody instead.
+func (ctx *ReqContext) PollUntil(
+	interval, timeout time.Duration, predicate func(utils.JSONResult) bool,
+) (utils.JSONResult, error) {
+	parent := ctx.context
+	if parent == nil {
+		parent = context.Background()
+	}
+	c, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	// the attempts use the poll context, the one of the caller is restored after
+	defer func(c context.Context) {
+		ctx.context = c
+		ctx.request = nil
+	}(ctx.context)
+
+	var res utils.JSONResult
+	var last error
+
+	err := utils.Retry(c, utils.BackoffSleeper(interval, interval, nil), func() (bool, error) {
+		ctx.context = c
+		ctx.request = nil
+		ctx.response = nil
+		ctx.resBytes = nil
+		if ctx.spooled != nil {
+			_ = ctx.spooled.Close()
+			ctx.spooled = nil
+		}
+
+		r, err := ctx.JSON()
+		if err != nil {
+			// the attempt interrupted by the timeout isn't the cause
+			if c.Err() == nil {
+				last = err
+			}
+			return false, nil
+		}
+		last = nil
+
+		res = r
+		return predicate(r), nil
+	})
+
+	if err != nil && last != nil {
+		return res, last
+	}
+	return res, err
+}
+
+// MustPollUntil panic version of PollUntil
+func (ctx *ReqContext) MustPollUntil(
+	interval, timeout time.Duration, predicate func(utils.JSONResult) bool,
+) utils.JSONResult {
+	return utils.E(ctx.PollUntil(interval, timeout, predicate))[0].(utils.JSONResult)
+}
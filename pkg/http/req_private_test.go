@@ -1,9 +1,14 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type ErrReader struct {
@@ -28,7 +33,7 @@ func TestReadBodyReadErr(t *testing.T) {
 		readErr: errors.New("err"),
 	}
 
-	_, err := readBody(obj)
+	_, err := readBody(obj, 0)
 
 	if err.Error() != "err" {
 		panic(err)
@@ -40,9 +45,47 @@ func TestReadBodyCloseErr(t *testing.T) {
 		closeErr: errors.New("err"),
 	}
 
-	_, err := readBody(obj)
+	_, err := readBody(obj, 0)
 
 	if err.Error() != "err" {
 		panic(err)
 	}
 }
+
+func TestResolverTransport(t *testing.T) {
+	hosts := StaticHosts(map[string]string{"api.test": "127.0.0.1"})
+
+	a, _ := resolverTransport(nil, hosts)
+	b, _ := resolverTransport(nil, hosts)
+	if a != b {
+		t.Fatal("the requests with the same resolver should share the transport")
+	}
+
+	other, _ := resolverTransport(nil, StaticHosts(nil))
+	if other == a {
+		t.Fatal("the transport belongs to the resolver")
+	}
+
+	r := &net.Resolver{}
+	c, _ := resolverTransport(nil, r)
+	if !c.DisableKeepAlives {
+		t.Fatal("the transport of an unknown resolver shouldn't keep the connections")
+	}
+}
+
+func TestFailoverKeepTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx := Req("/").Context(c).Timeout(time.Minute).URLs(srv.URL)
+	if err := ctx.Do(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.context != c || ctx.timeout != time.Minute {
+		t.Fatal("the context and timeout of the caller should be kept")
+	}
+}
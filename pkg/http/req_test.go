@@ -1,8 +1,15 @@
 package http_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -274,6 +281,45 @@ func (s *RequestSuite) TestReuseCookie() {
 	s.Equal("b", header)
 }
 
+func (s *RequestSuite) TestJar() {
+	path, url := s.path()
+
+	var cookie string
+
+	s.router.GET(path, func(c kit.GinContext) {
+		cookie, _ = c.Cookie("t")
+		c.SetCookie("t", "val", 3600, "", "", false, true)
+	})
+
+	jar, _ := cookiejar.New(nil)
+	kit.Req(url).Jar(jar).MustDo()
+	kit.Req(url).Jar(jar).MustDo()
+
+	s.Equal("val", cookie)
+}
+
+func (s *RequestSuite) TestNoCookies() {
+	path, url := s.path()
+
+	var cookie string
+	redirected := false
+
+	s.router.GET(path, func(c kit.GinContext) {
+		if redirected {
+			cookie, _ = c.Cookie("t")
+			return
+		}
+		redirected = true
+		c.SetCookie("t", "val", 3600, "", "", false, true)
+		c.Redirect(http.StatusFound, url)
+	})
+
+	kit.Req(url).NoCookies().MustDo()
+
+	s.True(redirected)
+	s.Empty(cookie)
+}
+
 func (s *RequestSuite) TestMustCurl() {
 	path, url := s.path()
 
@@ -340,3 +386,202 @@ func (s *RequestSuite) TestProxy() {
 	c = kit.Req(target).Proxy("0://abc.com")
 	s.Error(c.Do())
 }
+
+func (s *RequestSuite) TestMaxBodySize() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, "0123456789")
+	})
+
+	_, err := kit.Req(url).MaxBodySize(5).Bytes()
+
+	var e *kit.BodyTooLargeError
+	s.True(errors.As(err, &e))
+	s.Equal(int64(5), e.Limit)
+	s.EqualError(err, "response body exceeds the limit of 5 bytes")
+
+	s.Equal("0123456789", kit.Req(url).MaxBodySize(10).MustString())
+}
+
+func (s *RequestSuite) TestPollUntil() {
+	path, url := s.path()
+
+	count := 0
+	s.router.GET(path, func(c kit.GinContext) {
+		count++
+		c.JSON(200, map[string]int{"count": count})
+	})
+
+	res := kit.Req(url).MustPollUntil(time.Millisecond, time.Minute, func(r kit.JSONResult) bool {
+		return r.Get("count").Int() == 3
+	})
+
+	s.Equal(int64(3), res.Get("count").Int())
+}
+
+func (s *RequestSuite) TestPollUntilTimeout() {
+	_, url := s.path()
+
+	res, err := kit.Req(url).PollUntil(time.Millisecond, 50*time.Millisecond, func(r kit.JSONResult) bool {
+		return false
+	})
+
+	s.Equal(context.DeadlineExceeded, err)
+	s.NotNil(res)
+
+	req := kit.Req("")
+	res, err = req.PollUntil(time.Millisecond, 50*time.Millisecond, func(r kit.JSONResult) bool {
+		return true
+	})
+	s.EqualError(err, `Get "": unsupported protocol scheme ""`)
+	s.Nil(res)
+
+	// the poll context won't be left on the request
+	r, _ := req.Request()
+	s.Nil(r.Context().Err())
+}
+
+func (s *RequestSuite) TestPollUntilSpool() {
+	path, url := s.path()
+
+	count := 0
+	s.router.GET(path, func(c kit.GinContext) {
+		count++
+		c.JSON(200, map[string]int{"count": count})
+	})
+
+	req := kit.Req(url).SpoolToDisk(1)
+	req.MustBodyReader()
+
+	// each attempt reads its own body, not the spooled one of the first response
+	res := req.MustPollUntil(time.Millisecond, time.Minute, func(r kit.JSONResult) bool {
+		return r.Get("count").Int() == 3
+	})
+	s.Equal(int64(3), res.Get("count").Int())
+}
+
+func (s *RequestSuite) TestPollUntilURLsTimeout() {
+	// the handlers are blocked until the test ends, the server waits for them on Close,
+	// so nothing outlives the test
+	block := make(chan kit.Nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	// every attempt has the timeout, not only the first one
+	req := kit.Req("/poll").URLs(srv.URL).Timeout(20 * time.Millisecond)
+	_, err := req.PollUntil(time.Millisecond, 300*time.Millisecond, func(r kit.JSONResult) bool {
+		return true
+	})
+	s.ErrorIs(err, context.DeadlineExceeded)
+
+	_, err = req.Bytes()
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *RequestSuite) TestRedirect() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		n, _ := strconv.Atoi(c.Query("n"))
+		if n == 3 {
+			c.String(200, c.GetHeader("Authorization"))
+			return
+		}
+		c.Redirect(302, fmt.Sprintf("%s?n=%d", path, n+1))
+	})
+
+	res := kit.Req(url).NoRedirect().MustResponse()
+	s.Equal(302, res.StatusCode)
+
+	_, err := kit.Req(url).MaxRedirects(2).Bytes()
+	s.EqualError(err, `Get "`+path+`?n=3": stopped after 2 redirects`)
+
+	s.Equal("", kit.Req(url).MaxRedirects(3).MustString())
+
+	count := 0
+	s.Equal("token", kit.Req(url).Header("Authorization", "token").OnRedirect(
+		func(req *http.Request, via []*http.Request) error {
+			count++
+			req.Header.Set("Authorization", via[0].Header.Get("Authorization"))
+			return nil
+		},
+	).MustString())
+	s.Equal(3, count)
+
+	res = kit.Req(url).OnRedirect(func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}).MustResponse()
+	s.Equal(302, res.StatusCode)
+}
+
+func (s *RequestSuite) TestBodyStruct() {
+	path, url := s.path()
+
+	s.router.POST(path, func(c kit.GinContext) {
+		ct := c.ContentType()
+		switch ct {
+		case "application/json":
+			data, _ := c.GetRawData()
+			c.String(200, ct+" "+string(data))
+		case "application/x-www-form-urlencoded":
+			_ = c.Request.ParseForm()
+			c.String(200, ct+" "+c.Request.PostForm.Encode())
+		case "multipart/form-data":
+			f, _ := c.FormFile("file")
+			r, _ := f.Open()
+			data, _ := io.ReadAll(r)
+			c.String(200, ct+" "+c.PostForm("name")+" "+f.Filename+" "+string(data))
+		}
+	})
+
+	s.Equal(`application/json {"name":"a","id":1}`, kit.Req(url).Post().BodyStruct(struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}{"a", 1}).MustString())
+
+	s.Equal(`application/x-www-form-urlencoded id=1&name=a&tag=x&tag=y`, kit.Req(url).Post().BodyStruct(&struct {
+		Name string   `form:"name"`
+		ID   int      `json:"id"`
+		Tags []string `form:"tag"`
+		Skip string   `form:"-"`
+	}{"a", 1, []string{"x", "y"}, "s"}).MustString())
+
+	p := "tmp/" + kit.RandString(5) + "/f.txt"
+	kit.E(kit.OutputFile(p, "content", nil))
+
+	s.Equal(`multipart/form-data a f.txt content`, kit.Req(url).Post().BodyStruct(struct {
+		Name string `json:"name"`
+		File string `file:"file"`
+	}{"a", p}).MustString())
+
+	s.Equal(`application/x-www-form-urlencoded name=a`, kit.Req(url).Post().
+		Header("Content-Type", "application/x-www-form-urlencoded").
+		BodyStruct(struct {
+			Name string `json:"name"`
+		}{"a"}).MustString())
+
+	s.EqualError(kit.Req(url).Post().BodyStruct("a").Do(), "BodyStruct requires a struct, got string")
+}
+
+func (s *RequestSuite) TestRequestID() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, c.GetHeader(kit.ReqIDHeader)+c.GetHeader("Trace"))
+	})
+
+	req := kit.Req(url).RequestID("")
+	id := req.MustString()
+	s.Len(id, 16)
+	s.Equal(id, req.ID())
+
+	s.Equal("abc", kit.Req(url).RequestID("Trace").Header("Trace", "abc").MustString())
+
+	req = kit.Req("http://127.0.0.1:1").RequestID("")
+	err := req.Do()
+	s.Contains(err.Error(), "request id "+req.ID()+": ")
+}
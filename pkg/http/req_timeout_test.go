@@ -0,0 +1,81 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestTimeoutBody() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.Status(200)
+		c.Writer.Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = c.Writer.WriteString("ok")
+	})
+
+	s.Equal("ok", kit.Req(url).Timeout(time.Hour).MustString())
+}
+
+func (s *RequestSuite) TestTimeoutResponse() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.Status(200)
+		c.Writer.Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = c.Writer.WriteString("ok")
+	})
+
+	r := kit.Req(url).Timeout(time.Hour)
+	res := r.MustResponse()
+	req, _ := r.Request()
+
+	// the body can still be read after the Response returns
+	b, err := io.ReadAll(res.Body)
+	s.Nil(err)
+	s.Equal("ok", string(b))
+	s.Nil(req.Context().Err())
+
+	// the timer is released with the body
+	s.Nil(res.Body.Close())
+	s.Equal(context.Canceled, req.Context().Err())
+}
+
+func (s *RequestSuite) TestTimeoutBodyExpired() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.Status(200)
+		c.Writer.Flush()
+		time.Sleep(time.Second)
+		_, _ = c.Writer.WriteString("ok")
+	})
+
+	// the headers arrive before the deadline, but the body doesn't
+	_, err := kit.Req(url).Timeout(100 * time.Millisecond).String()
+	s.True(errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func (s *RequestSuite) TestTimeoutJSON() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.Status(200)
+		c.Writer.Flush()
+		time.Sleep(100 * time.Millisecond)
+		_, _ = c.Writer.WriteString(`{"a": 1}`)
+	})
+
+	r := kit.Req(url).Timeout(time.Hour)
+	s.EqualValues(1, r.MustJSON().Get("a").Int())
+
+	// the timer is released once the body is read
+	req, _ := r.Request()
+	s.Equal(context.Canceled, req.Context().Err())
+}
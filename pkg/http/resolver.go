@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Resolver resolves the host to ip addresses, *net.Resolver implements it
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+}
+
+// DNSCache caches the lookups of the resolver in memory, the failed lookups are not cached
+type DNSCache struct {
+	ttl      time.Duration
+	resolver Resolver
+
+	lock    sync.Mutex
+	entries map[string]dnsEntry
+
+	transports transportCache
+}
+
+type dnsEntry struct {
+	addrs  []string
+	expire time.Time
+}
+
+// NewDNSCache creates a cache that keeps each lookup for ttl, if resolver is nil net.DefaultResolver will be used
+func NewDNSCache(ttl time.Duration, resolver Resolver) *DNSCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSCache{
+		ttl:      ttl,
+		resolver: resolver,
+		entries:  map[string]dnsEntry{},
+	}
+}
+
+// LookupHost returns the cached addresses if they are not expired
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.lock.Lock()
+	e, has := c.entries[host]
+	c.lock.Unlock()
+
+	if has && time.Now().Before(e.expire) {
+		return e.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	c.entries[host] = dnsEntry{addrs, time.Now().Add(c.ttl)}
+	c.lock.Unlock()
+
+	return addrs, nil
+}
+
+type staticHosts struct {
+	hosts      map[string]string
+	transports transportCache
+}
+
+// StaticHosts resolves the hosts by the map like the hosts file, such as {"api.test": "127.0.0.1"},
+// the hosts not in the map fail to resolve, so the tests won't reach the network by accident
+func StaticHosts(hosts map[string]string) Resolver {
+	return &staticHosts{hosts: hosts}
+}
+
+func (s *staticHosts) LookupHost(_ context.Context, host string) ([]string, error) {
+	if ip, has := s.hosts[host]; has {
+		return []string{ip}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// Resolver sets the resolver to look up the hosts, such as a DNSCache or StaticHosts.
+// The requests with the same DNSCache or StaticHosts share the connection pool, it's released with them.
+// Other resolvers don't keep the connections, wrap them with NewDNSCache to reuse the connections.
+// It doesn't work with a custom transport of the Client that isn't *http.Transport.
+func (ctx *ReqContext) Resolver(r Resolver) *ReqContext {
+	ctx.resolver = r
+	return ctx
+}
+
+// the transports that dial with the resolver, they live as long as the resolver
+type transportCache struct {
+	lock sync.Mutex
+	list map[*http.Transport]*http.Transport // the key is the base transport
+}
+
+func (c *transportCache) get(base *http.Transport, r Resolver) *http.Transport {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if t, has := c.list[base]; has {
+		return t
+	}
+
+	if c.list == nil {
+		c.list = map[*http.Transport]*http.Transport{}
+	}
+	t := base.Clone()
+	t.DialContext = resolverDialer(r)
+	c.list[base] = t
+	return t
+}
+
+func resolverTransport(rt http.RoundTripper, r Resolver) (*http.Transport, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("the resolver requires *http.Transport, got %T", rt)
+	}
+
+	switch r := r.(type) {
+	case *DNSCache:
+		return r.transports.get(base, r), nil
+	case *staticHosts:
+		return r.transports.get(base, r), nil
+	}
+
+	// nothing owns the transport, so it shouldn't keep the idle connections
+	t := base.Clone()
+	t.DialContext = resolverDialer(r)
+	t.DisableKeepAlives = true
+	return t, nil
+}
+
+func resolverDialer(r Resolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if net.ParseIP(host) != nil {
+			return d.DialContext(ctx, network, addr)
+		}
+
+		ips, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+
+		// try each address until one connects
+		for _, ip := range ips {
+			var conn net.Conn
+			conn, err = d.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
@@ -0,0 +1,59 @@
+package http_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+type countResolver struct {
+	lock  sync.Mutex
+	count int
+}
+
+func (r *countResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.count++
+	return []string{"127.0.0.1"}, nil
+}
+
+func (s *RequestSuite) TestStaticHosts() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, "ok")
+	})
+
+	url = strings.Replace(url, "127.0.0.1", "api.test", 1)
+	hosts := kit.StaticHosts(map[string]string{"api.test": "127.0.0.1"})
+
+	s.Equal("ok", kit.Req(url).Resolver(hosts).MustString())
+
+	_, err := kit.Req(strings.Replace(url, "api.test", "other.test", 1)).Resolver(hosts).String()
+	var dnsErr *net.DNSError
+	s.ErrorAs(err, &dnsErr)
+}
+
+func (s *RequestSuite) TestDNSCache() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, "ok")
+	})
+
+	url = strings.Replace(url, "127.0.0.1", "api.test", 1)
+	r := &countResolver{}
+	cache := kit.NewDNSCache(time.Minute, r)
+
+	s.Equal("ok", kit.Req(url).Resolver(cache).MustString())
+	s.Equal("ok", kit.Req(url).Resolver(cache).MustString())
+	s.Equal(1, r.count)
+
+	expired := kit.NewDNSCache(0, r)
+	s.Equal("ok", kit.Req(url).Resolver(expired).MustString())
+	s.Equal("ok", kit.Req(url).Resolver(expired).MustString())
+	s.Equal(3, r.count)
+}
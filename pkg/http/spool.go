@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// SpoolToDisk writes the decoded body that is larger than the threshold to a temp file instead of memory,
+// use BodyReader to read it with bounded memory. The Bytes, String, and JSON called after BodyReader
+// read the spooled body, they still hold the whole body in memory.
+func (ctx *ReqContext) SpoolToDisk(threshold int64) *ReqContext {
+	ctx.spool = threshold
+	return ctx
+}
+
+// ReqBody the decoded response body that can be read many times, Close removes the temp file if it's spooled
+type ReqBody interface {
+	io.ReadSeeker
+	io.Closer
+
+	// Size of the decoded body
+	Size() int64
+
+	// Spooled is true if the body is in a temp file
+	Spooled() bool
+}
+
+// BodyReader sends request and returns the decoded body, it's spooled to a temp file if SpoolToDisk is set
+// and the body is larger than the threshold. The MaxBodySize still applies.
+// Each call returns the same body, seek to the start to read it again.
+func (ctx *ReqContext) BodyReader() (ReqBody, error) {
+	if ctx.spooled != nil {
+		return ctx.spooled, nil
+	}
+	if ctx.resBytes != nil {
+		ctx.spooled = &memBody{bytes.NewReader(ctx.resBytes)}
+		return ctx.spooled, nil
+	}
+
+	res, err := ctx.Response()
+	if err != nil {
+		return nil, err
+	}
+	defer ctx.cancelTimeout()
+
+	body, err := decodeBody(res)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	var r io.Reader = body
+	if ctx.maxBodySize > 0 {
+		r = &bodyLimiter{r: body, limit: ctx.maxBodySize}
+	}
+
+	ctx.spooled, err = spool(r, ctx.spool)
+	return ctx.spooled, err
+}
+
+// MustBodyReader panic version of BodyReader
+func (ctx *ReqContext) MustBodyReader() ReqBody {
+	return utils.E(ctx.BodyReader())[0].(ReqBody)
+}
+
+// keep the body in memory if it's not larger than the threshold, a threshold less than 1 means no limit
+func spool(r io.Reader, threshold int64) (ReqBody, error) {
+	if threshold < 1 {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return &memBody{bytes.NewReader(b)}, nil
+	}
+
+	head, err := ioutil.ReadAll(io.LimitReader(r, threshold+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(head)) <= threshold {
+		return &memBody{bytes.NewReader(head)}, nil
+	}
+
+	f, err := ioutil.TempFile("", "kit-spool-")
+	if err != nil {
+		return nil, err
+	}
+	fb := &fileBody{f}
+
+	_, err = io.Copy(f, io.MultiReader(bytes.NewReader(head), r))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = fb.Close()
+		return nil, err
+	}
+	return fb, nil
+}
+
+type memBody struct {
+	*bytes.Reader
+}
+
+func (b *memBody) Close() error { return nil }
+
+func (b *memBody) Spooled() bool { return false }
+
+type fileBody struct {
+	*os.File
+}
+
+func (b *fileBody) Size() int64 {
+	info, err := b.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (b *fileBody) Close() error {
+	_ = b.File.Close()
+	return os.Remove(b.Name())
+}
+
+func (b *fileBody) Spooled() bool { return true }
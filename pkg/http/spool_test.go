@@ -0,0 +1,43 @@
+package http_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestSpoolToDisk() {
+	path, url := s.path()
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, `{"a": "`+strings.Repeat("x", 100)+`"}`)
+	})
+
+	req := kit.Req(url).SpoolToDisk(10)
+	body := req.MustBodyReader()
+	s.True(body.Spooled())
+	s.Equal(int64(109), body.Size())
+
+	b, _ := ioutil.ReadAll(body)
+	s.Len(b, 109)
+
+	// read the spooled body again
+	s.Equal(100, len(req.MustJSON().Get("a").String()))
+
+	f := body.(interface{ Name() string }).Name()
+	s.FileExists(f)
+	s.Nil(body.Close())
+	_, err := os.Stat(f)
+	s.True(os.IsNotExist(err))
+
+	body = kit.Req(url).SpoolToDisk(1000).MustBodyReader()
+	s.False(body.Spooled())
+	_, _ = body.Seek(106, io.SeekStart)
+	b, _ = ioutil.ReadAll(body)
+	s.Equal(`x"}`, string(b))
+
+	_, err = kit.Req(url).SpoolToDisk(10).MaxBodySize(50).BodyReader()
+	s.Error(err)
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ReqStats the timing and size of a request, the durations of the phases that didn't happen are 0,
+// such as the DNS and Connect of a reused connection
+type ReqStats struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // from the start of the request to the first byte of the response
+	Total   time.Duration // from the start of the request to the end of the body, or until now if the body isn't fully read
+
+	BytesSent     int64 // the size of the request body
+	BytesReceived int64 // the size of the response body read so far, before it's decoded
+	Reused        bool  // the connection is reused
+}
+
+// Stats returns the timing and size of the request after Do, it's safe to call it while reading the body
+func (ctx *ReqContext) Stats() ReqStats {
+	if ctx.stats == nil {
+		return ReqStats{}
+	}
+	return ctx.stats.get()
+}
+
+type reqStats struct {
+	lock  sync.Mutex
+	stats ReqStats
+	conns *ConnStats
+
+	start, dnsStart, connStart, tlsStart, done time.Time
+}
+
+func (s *reqStats) get() ReqStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	st := s.stats
+	if s.done.IsZero() {
+		st.Total = time.Since(s.start)
+	} else {
+		st.Total = s.done.Sub(s.start)
+	}
+	return st
+}
+
+func (s *reqStats) update(fn func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fn()
+}
+
+// trace the phases of the req and count the bytes of the bodies
+func (s *reqStats) trace(req *http.Request) *http.Request {
+	s.start = time.Now()
+
+	t := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { s.update(func() { s.dnsStart = time.Now() }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			s.update(func() { s.stats.DNS = time.Since(s.dnsStart) })
+		},
+		ConnectStart: func(_, _ string) { s.update(func() { s.connStart = time.Now() }) },
+		ConnectDone: func(_, _ string, _ error) {
+			s.update(func() { s.stats.Connect = time.Since(s.connStart) })
+		},
+		TLSHandshakeStart: func() { s.update(func() { s.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			s.update(func() { s.stats.TLS = time.Since(s.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			s.update(func() { s.stats.Reused = info.Reused })
+			if s.conns != nil {
+				s.conns.add(info.Reused)
+			}
+		},
+		GotFirstResponseByte: func() {
+			s.update(func() { s.stats.TTFB = time.Since(s.start) })
+		},
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), t))
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countBody{ReadCloser: req.Body, count: func(n int) {
+			s.update(func() { s.stats.BytesSent += int64(n) })
+		}}
+	}
+	return req
+}
+
+func (s *reqStats) countResponse(res *http.Response) {
+	res.Body = &countBody{
+		ReadCloser: res.Body,
+		count:      func(n int) { s.update(func() { s.stats.BytesReceived += int64(n) }) },
+		done: func() {
+			s.update(func() {
+				if s.done.IsZero() {
+					s.done = time.Now()
+				}
+			})
+		},
+	}
+}
+
+// the done is called when the body reaches EOF or is closed
+type countBody struct {
+	io.ReadCloser
+	count func(n int)
+	done  func()
+}
+
+func (b *countBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.count(n)
+	if err == io.EOF && b.done != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *countBody) Close() error {
+	if b.done != nil {
+		b.done()
+	}
+	return b.ReadCloser.Close()
+}
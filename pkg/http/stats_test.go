@@ -0,0 +1,29 @@
+package http_test
+
+import (
+	"io"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestStats() {
+	path, url := s.path()
+	s.router.POST(path, func(c kit.GinContext) {
+		_, _ = io.ReadAll(c.Request.Body)
+		time.Sleep(10 * time.Millisecond)
+		c.String(200, "0123456789")
+	})
+
+	req := kit.Req(url).Post().StringBody("abc")
+	s.Equal(kit.ReqStats{}, req.Stats())
+
+	s.Equal("0123456789", req.MustString())
+
+	st := req.Stats()
+	s.Equal(int64(3), st.BytesSent)
+	s.Equal(int64(10), st.BytesReceived)
+	s.GreaterOrEqual(st.TTFB, 10*time.Millisecond)
+	s.GreaterOrEqual(st.Total, st.TTFB)
+	s.Equal(st.Total, req.Stats().Total) // the body is fully read
+}
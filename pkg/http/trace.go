@@ -0,0 +1,16 @@
+package http
+
+import (
+	"net/http"
+)
+
+// TraceFunc is called before each request is sent, the returned req is sent instead,
+// the end is called with the response or the error of the request.
+type TraceFunc func(ctx *ReqContext, req *http.Request) (_ *http.Request, end func(res *http.Response, err error))
+
+// Trace sets the hook to trace the requests, such as emitting the spans of a tracing system.
+// Use the package github.com/ysmood/kit/pkg/http/httpotel for OpenTelemetry.
+func (ctx *ReqContext) Trace(fn TraceFunc) *ReqContext {
+	ctx.trace = fn
+	return ctx
+}
@@ -0,0 +1,34 @@
+package http_test
+
+import (
+	"net/http"
+
+	"github.com/ysmood/kit"
+)
+
+func (s *RequestSuite) TestTrace() {
+	path, url := s.path()
+
+	s.router.GET(path, func(c kit.GinContext) {
+		c.String(200, c.GetHeader("X-Trace"))
+	})
+
+	var status int
+	trace := func(ctx *kit.ReqContext, req *http.Request) (*http.Request, func(*http.Response, error)) {
+		req.Header.Set("X-Trace", "ok")
+		return req, func(res *http.Response, err error) {
+			s.Nil(err)
+			status = res.StatusCode
+		}
+	}
+
+	s.Equal("ok", kit.Req(url).Trace(trace).MustString())
+	s.Equal(200, status)
+
+	var traceErr error
+	err := kit.Req("http://127.0.0.1:1").Trace(func(ctx *kit.ReqContext, req *http.Request) (*http.Request, func(*http.Response, error)) {
+		return req, func(_ *http.Response, err error) { traceErr = err }
+	}).Do()
+	s.Error(err)
+	s.Error(traceErr)
+}
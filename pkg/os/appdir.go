@@ -0,0 +1,68 @@
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ConfigDir returns the dir for the config files of the app, such as ~/.config/app on Linux,
+// ~/Library/Application Support/app on macOS, and %APPDATA%\app on Windows.
+// The XDG_CONFIG_HOME is respected on Linux. The dir won't be created.
+func ConfigDir(app string) string {
+	return appDir(runtime.GOOS, "config", app)
+}
+
+// CacheDir returns the dir for the cache files of the app, such as ~/.cache/app on Linux,
+// ~/Library/Caches/app on macOS, and %LOCALAPPDATA%\app\cache on Windows.
+// The XDG_CACHE_HOME is respected on Linux. The dir won't be created.
+func CacheDir(app string) string {
+	return appDir(runtime.GOOS, "cache", app)
+}
+
+// DataDir returns the dir for the state and data files of the app, such as ~/.local/share/app on Linux,
+// ~/Library/Application Support/app on macOS, and %LOCALAPPDATA%\app on Windows.
+// The XDG_DATA_HOME is respected on Linux. The dir won't be created.
+func DataDir(app string) string {
+	return appDir(runtime.GOOS, "data", app)
+}
+
+func appDir(goos, kind, app string) string {
+	home := HomeDir()
+
+	switch goos {
+	case "windows":
+		switch kind {
+		case "config":
+			return filepath.Join(envDir("APPDATA", home, "AppData", "Roaming"), app)
+		case "cache":
+			return filepath.Join(envDir("LOCALAPPDATA", home, "AppData", "Local"), app, "cache")
+		default:
+			return filepath.Join(envDir("LOCALAPPDATA", home, "AppData", "Local"), app)
+		}
+
+	case "darwin", "ios":
+		if kind == "cache" {
+			return filepath.Join(home, "Library", "Caches", app)
+		}
+		return filepath.Join(home, "Library", "Application Support", app)
+
+	default:
+		switch kind {
+		case "config":
+			return filepath.Join(envDir("XDG_CONFIG_HOME", home, ".config"), app)
+		case "cache":
+			return filepath.Join(envDir("XDG_CACHE_HOME", home, ".cache"), app)
+		default:
+			return filepath.Join(envDir("XDG_DATA_HOME", home, ".local", "share"), app)
+		}
+	}
+}
+
+// the dir from the env, the fallback will be used if it's not an absolute path
+func envDir(env string, fallback ...string) string {
+	if p := os.Getenv(env); filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(fallback...)
+}
@@ -0,0 +1,34 @@
+package os
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppDir(t *testing.T) {
+	home := HomeDir()
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "relative")
+	t.Setenv("XDG_DATA_HOME", filepath.FromSlash("/data"))
+
+	assert.Equal(t, filepath.Join(home, ".config", "app"), appDir("linux", "config", "app"))
+	assert.Equal(t, filepath.Join(home, ".cache", "app"), appDir("linux", "cache", "app"))
+	assert.Equal(t, filepath.Join(filepath.FromSlash("/data"), "app"), appDir("linux", "data", "app"))
+
+	assert.Equal(t, filepath.Join(home, "Library", "Application Support", "app"), appDir("darwin", "config", "app"))
+	assert.Equal(t, filepath.Join(home, "Library", "Caches", "app"), appDir("darwin", "cache", "app"))
+
+	t.Setenv("APPDATA", "")
+	t.Setenv("LOCALAPPDATA", "")
+	assert.Equal(t, filepath.Join(home, "AppData", "Roaming", "app"), appDir("windows", "config", "app"))
+	assert.Equal(t, filepath.Join(home, "AppData", "Local", "app", "cache"), appDir("windows", "cache", "app"))
+	assert.Equal(t, filepath.Join(home, "AppData", "Local", "app"), appDir("windows", "data", "app"))
+
+	assert.Equal(t, appDir(runtime.GOOS, "config", "app"), ConfigDir("app"))
+	assert.Equal(t, appDir(runtime.GOOS, "cache", "app"), CacheDir("app"))
+	assert.Equal(t, appDir(runtime.GOOS, "data", "app"), DataDir("app"))
+}
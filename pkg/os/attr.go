@@ -0,0 +1,48 @@
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsReadOnly checks if the file is not writable by its owner
+func IsReadOnly(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return info.Mode().Perm()&0200 == 0, nil
+}
+
+// SetReadOnly removes all the write bits or adds the owner write bit back.
+// On Windows it toggles the read-only attribute.
+func SetReadOnly(path string, readOnly bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	mode := info.Mode().Perm()
+	if readOnly {
+		mode &^= 0222
+	} else {
+		mode |= 0200
+	}
+
+	return os.Chmod(path, mode)
+}
+
+// ExecMode adds or removes the exec bits of the mode
+func ExecMode(mode os.FileMode, executable bool) os.FileMode {
+	if executable {
+		return mode | 0111
+	}
+	return mode &^ 0111
+}
+
+// the names that start with a dot are hidden, except the "." and ".." that refer to dirs
+func isDotFile(path string) bool {
+	name := filepath.Base(path)
+	return name != "." && name != ".." && strings.HasPrefix(name, ".")
+}
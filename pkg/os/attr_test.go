@@ -0,0 +1,74 @@
+package os_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestIsHidden(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/.a", "", nil))
+	kit.E(kit.OutputFile(p+"/a", "", nil))
+
+	assert.True(t, kit.E(kit.IsHidden(p + "/.a"))[0].(bool))
+	assert.False(t, kit.E(kit.IsHidden(p + "/a"))[0].(bool))
+	assert.False(t, kit.E(kit.IsHidden("."))[0].(bool))
+	assert.False(t, kit.E(kit.IsHidden(p + "/.."))[0].(bool))
+
+	_, err := kit.IsHidden(p + "/not-exists")
+	assert.Error(t, err)
+}
+
+func TestReadOnly(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p, "", nil))
+
+	kit.E(kit.SetReadOnly(p, true))
+	assert.True(t, kit.E(kit.IsReadOnly(p))[0].(bool))
+
+	kit.E(kit.SetReadOnly(p, false))
+	assert.False(t, kit.E(kit.IsReadOnly(p))[0].(bool))
+
+	assert.Error(t, kit.SetReadOnly(p+"/not-exists", true))
+	_, err := kit.IsReadOnly(p + "/not-exists")
+	assert.Error(t, err)
+}
+
+func TestExecutable(t *testing.T) {
+	p := "tmp/" + kit.RandString(10) + kit.ExecutableExt()
+	kit.E(kit.OutputFile(p, "", nil))
+
+	kit.E(kit.SetExecutable(p, true))
+	assert.True(t, kit.E(kit.IsExecutable(p))[0].(bool))
+
+	if runtime.GOOS != "windows" {
+		kit.E(kit.SetExecutable(p, false))
+		assert.False(t, kit.E(kit.IsExecutable(p))[0].(bool))
+	}
+
+	assert.False(t, kit.E(kit.IsExecutable("tmp"))[0].(bool))
+	assert.Error(t, kit.SetExecutable(p+"/not-exists", true))
+}
+
+func TestExecMode(t *testing.T) {
+	assert.Equal(t, "-rwxr-xr-x", kit.ExecMode(0644, true).String())
+	assert.Equal(t, "-rw-r--r--", kit.ExecMode(0755, false).String())
+}
+
+func TestCopyKeepAttrs(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	exe := p + "/src/a" + kit.ExecutableExt()
+	kit.E(kit.OutputFile(exe, "", nil))
+	kit.E(kit.OutputFile(p+"/src/.b", "", nil))
+	kit.E(kit.SetExecutable(exe, true))
+	kit.E(kit.SetReadOnly(p+"/src/.b", true))
+
+	kit.E(kit.Copy(p+"/src", p+"/dest"))
+
+	assert.True(t, kit.E(kit.IsExecutable(p + "/dest/a" + kit.ExecutableExt()))[0].(bool))
+	assert.True(t, kit.E(kit.IsReadOnly(p + "/dest/.b"))[0].(bool))
+	assert.True(t, kit.E(kit.IsHidden(p + "/dest/.b"))[0].(bool))
+}
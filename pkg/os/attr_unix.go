@@ -0,0 +1,53 @@
+// +build !windows
+
+package os
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsHidden checks if the file is hidden, on Windows the hidden attribute is also checked
+func IsHidden(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return isDotFile(path), nil
+}
+
+// IsExecutable checks if the file is executable, on Windows it's decided by the PATHEXT env
+func IsExecutable(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	return !info.IsDir() && info.Mode().Perm()&0111 != 0, nil
+}
+
+// SetExecutable adds or removes the exec bits, it does nothing on Windows
+func SetExecutable(path string, executable bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(path, ExecMode(info.Mode().Perm(), executable))
+}
+
+// the id of the device that the file is on
+func deviceID(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(info.Sys().(*syscall.Stat_t).Dev), nil
+}
+
+// the hidden files are decided by their names, the perm bits are kept by the Copy
+func keepAttrs(src, dest string) error {
+	return nil
+}
@@ -0,0 +1,120 @@
+// +build windows
+
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// IsHidden checks if the file is hidden, on Windows the hidden attribute is also checked
+func IsHidden(path string) (bool, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false, err
+	}
+
+	attrs, err := windows.GetFileAttributes(p)
+	if err != nil {
+		return false, &os.PathError{Op: "GetFileAttributes", Path: path, Err: err}
+	}
+
+	if attrs&windows.FILE_ATTRIBUTE_HIDDEN != 0 {
+		return true, nil
+	}
+
+	return isDotFile(path), nil
+}
+
+// IsExecutable checks if the file is executable, on Windows it's decided by the PATHEXT env
+func IsExecutable(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	if info.IsDir() {
+		return false, nil
+	}
+
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".com;.exe;.bat;.cmd"
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range strings.Split(strings.ToLower(exts), ";") {
+		if e != "" && e == ext {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SetExecutable adds or removes the exec bits, it does nothing on Windows
+func SetExecutable(path string, executable bool) error {
+	_, err := os.Stat(path)
+	return err
+}
+
+// the serial number of the volume that the file is on
+func deviceID(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	// the backup semantics flag is required to open a dir
+	h, err := windows.CreateFile(p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, &os.PathError{Op: "CreateFile", Path: path, Err: err}
+	}
+	defer func() { _ = windows.CloseHandle(h) }()
+
+	var info windows.ByHandleFileInformation
+	err = windows.GetFileInformationByHandle(h, &info)
+	if err != nil {
+		return 0, &os.PathError{Op: "GetFileInformationByHandle", Path: path, Err: err}
+	}
+
+	return uint64(info.VolumeSerialNumber), nil
+}
+
+// the hidden attribute isn't a perm bit, so the copy doesn't keep it
+func keepAttrs(src, dest string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		from, err := windows.UTF16PtrFromString(p)
+		if err != nil {
+			return err
+		}
+		attrs, err := windows.GetFileAttributes(from)
+		if err != nil || attrs&windows.FILE_ATTRIBUTE_HIDDEN == 0 {
+			return nil
+		}
+
+		to, err := windows.UTF16PtrFromString(filepath.Join(dest, rel))
+		if err != nil {
+			return err
+		}
+		destAttrs, err := windows.GetFileAttributes(to)
+		if err != nil {
+			// skipped by the opts
+			return nil
+		}
+		return windows.SetFileAttributes(to, destAttrs|windows.FILE_ATTRIBUTE_HIDDEN)
+	})
+}
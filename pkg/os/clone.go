@@ -0,0 +1,76 @@
+package os
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/otiai10/copy"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// Copy file or dir recursively. On the filesystems that support copy-on-write, such as Btrfs, XFS, APFS,
+// and ReFS, the regular files are cloned instead, so copying big files is nearly instant.
+// It falls back to the normal copy if the clone fails, or the opts need to read the content, such as FS and WrapReader.
+// The attributes are kept, such as the exec and read-only bits, and the hidden attribute on Windows.
+func Copy(src, dest string, opts ...copy.Options) error {
+	opt := copy.Options{}
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	err := copyFiles(src, dest, opt)
+	if err != nil || opt.FS != nil {
+		return err
+	}
+	return keepAttrs(src, dest)
+}
+
+func copyFiles(src, dest string, opt copy.Options) error {
+	if !cloneable(opt) {
+		return copy.Copy(src, dest, opt)
+	}
+
+	info, err := os.Lstat(src)
+	if err == nil && info.Mode().IsRegular() {
+		if err := Mkdir(filepath.Dir(dest), nil); err != nil {
+			return err
+		}
+		if clone(src, dest, info.Mode().Perm()) == nil {
+			return nil
+		}
+	}
+
+	skip := opt.Skip
+	opt.Skip = func(info os.FileInfo, src, dest string) (bool, error) {
+		if skip != nil {
+			s, err := skip(info, src, dest)
+			if s || err != nil {
+				return s, err
+			}
+		}
+
+		// the dest dir is created before its children are copied
+		return info.Mode().IsRegular() && clone(src, dest, info.Mode().Perm()) == nil, nil
+	}
+
+	return copy.Copy(src, dest, opt)
+}
+
+// MustCopy ...
+func MustCopy(src, dest string, opts ...copy.Options) {
+	utils.E(Copy(src, dest, opts...))
+}
+
+// the options that change how the content is read or written can't clone
+func cloneable(opt copy.Options) bool {
+	return opt.FS == nil && opt.WrapReader == nil && opt.PermissionControl == nil && opt.AddPermission == 0 &&
+		!opt.Sync && !opt.PreserveTimes && !opt.PreserveOwner
+}
+
+// clone the regular file with copy-on-write, the to will be replaced, the parent dir of the to must exist
+func clone(from, to string, perm os.FileMode) error {
+	if err := cloneFile(from, to); err != nil {
+		return err
+	}
+	return os.Chmod(to, perm)
+}
@@ -0,0 +1,22 @@
+// +build darwin
+
+package os
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// clonefile of APFS, it fails if the to exists
+func cloneFile(from, to string) error {
+	err := unix.Clonefile(from, to, unix.CLONE_NOFOLLOW)
+	if errors.Is(err, unix.EEXIST) {
+		if err = os.Remove(to); err != nil {
+			return err
+		}
+		err = unix.Clonefile(from, to, unix.CLONE_NOFOLLOW)
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+// +build linux
+
+package os
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink, such as Btrfs and XFS
+func cloneFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// so the fallback creates the file with the right perm
+		_ = os.Remove(to)
+	}
+	return err
+}
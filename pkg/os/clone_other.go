@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!windows
+
+package os
+
+import "errors"
+
+func cloneFile(from, to string) error {
+	return errors.New("clone is not supported on this platform")
+}
@@ -0,0 +1,102 @@
+// +build windows
+
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type duplicateExtentsData struct {
+	FileHandle       windows.Handle
+	SourceFileOffset int64
+	TargetFileOffset int64
+	ByteCount        int64
+}
+
+var procGetDiskFreeSpace = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetDiskFreeSpaceW")
+
+// each call can clone less than 4GB
+const cloneChunk = 1 << 30
+
+// block cloning of ReFS and Dev Drive, the ranges must be aligned to the cluster size
+func cloneFile(from, to string) (err error) {
+	cluster, err := clusterSize(from)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(to, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// so the fallback creates the file with the right perm
+			_ = dst.Close()
+			_ = os.Remove(to)
+		}
+	}()
+
+	if err := dst.Truncate(stat.Size()); err != nil {
+		return err
+	}
+
+	size := (stat.Size() + cluster - 1) / cluster * cluster
+	for offset := int64(0); offset < size; offset += cloneChunk {
+		data := duplicateExtentsData{windows.Handle(src.Fd()), offset, offset, min(cloneChunk, size-offset)}
+		var n uint32
+		err := windows.DeviceIoControl(windows.Handle(dst.Fd()), windows.FSCTL_DUPLICATE_EXTENTS_TO_FILE,
+			(*byte)(unsafe.Pointer(&data)), uint32(unsafe.Sizeof(data)), nil, 0, &n, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return dst.Close()
+}
+
+func clusterSize(p string) (int64, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return 0, err
+	}
+
+	name, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return 0, err
+	}
+
+	volume := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumePathName(name, &volume[0], uint32(len(volume))); err != nil {
+		return 0, err
+	}
+
+	var sectors, bytes, free, total uint32
+	r, _, err := procGetDiskFreeSpace.Call(
+		uintptr(unsafe.Pointer(&volume[0])),
+		uintptr(unsafe.Pointer(&sectors)),
+		uintptr(unsafe.Pointer(&bytes)),
+		uintptr(unsafe.Pointer(&free)),
+		uintptr(unsafe.Pointer(&total)),
+	)
+	if r == 0 {
+		return 0, err
+	}
+
+	return int64(sectors) * int64(bytes), nil
+}
@@ -0,0 +1,110 @@
+package os
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// EnsureFile writes the content to the file only if the file's content is different,
+// the content is the same as the data of OutputFile. Returns true if the file is changed.
+func EnsureFile(path string, content interface{}) (bool, error) {
+	bin, err := encodeData(content, defaultOutputFileOptions())
+	if err != nil {
+		return false, err
+	}
+
+	old, err := ioutil.ReadFile(path)
+	if err == nil && bytes.Equal(old, bin) {
+		return false, nil
+	}
+
+	return true, OutputFile(path, bin, nil)
+}
+
+// EnsureLine appends the line to the file if the file doesn't contain it,
+// the file will be created if it doesn't exist. Returns true if the file is changed.
+func EnsureLine(path, line string) (bool, error) {
+	old, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	for _, l := range bytes.Split(old, []byte{'\n'}) {
+		if string(bytes.TrimSuffix(l, []byte{'\r'})) == line {
+			return false, nil
+		}
+	}
+
+	if len(old) > 0 && old[len(old)-1] != '\n' {
+		old = append(old, '\n')
+	}
+
+	return true, OutputFile(path, append(old, line+"\n"...), nil)
+}
+
+// EnsureSymlink makes sure the symlink at from points to to, a symlink that points to
+// somewhere else will be replaced. Returns true if the symlink is changed.
+func EnsureSymlink(from, to string) (bool, error) {
+	info, err := os.Lstat(from)
+	if err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			return false, fmt.Errorf("%s exists and is not a symlink", from)
+		}
+
+		target, err := os.Readlink(from)
+		if err != nil {
+			return false, err
+		}
+		if target == to {
+			return false, nil
+		}
+
+		err = os.Remove(from)
+		if err != nil {
+			return false, err
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	err = Mkdir(filepath.Dir(from), nil)
+	if err != nil {
+		return false, err
+	}
+
+	return true, os.Symlink(to, from)
+}
+
+// EnsureDir creates the dir if it doesn't exist, and sets its permission if it's different,
+// the permission is ignored on Windows. Returns true if the dir is changed.
+func EnsureDir(path string, perm os.FileMode) (bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		err = Mkdir(path, &MkdirOptions{Perm: perm})
+		if err != nil {
+			return false, err
+		}
+		// the perm of MkdirAll is affected by umask
+		if runtime.GOOS != "windows" {
+			err = os.Chmod(path, perm)
+		}
+		return true, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !info.IsDir() {
+		return false, fmt.Errorf("%s exists and is not a dir", path)
+	}
+
+	if runtime.GOOS == "windows" || info.Mode().Perm() == perm.Perm() {
+		return false, nil
+	}
+
+	return true, os.Chmod(path, perm)
+}
@@ -0,0 +1,100 @@
+package os_test
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestEnsureFile(t *testing.T) {
+	p := "tmp/" + kit.RandString(10) + "/f"
+
+	changed, err := kit.EnsureFile(p, "ok")
+	kit.E(err)
+	assert.True(t, changed)
+
+	changed, err = kit.EnsureFile(p, []byte("ok"))
+	kit.E(err)
+	assert.False(t, changed)
+
+	changed, err = kit.EnsureFile(p, map[string]int{"a": 1})
+	kit.E(err)
+	assert.True(t, changed)
+	assert.Equal(t, "{\n    \"a\": 1\n}", readString(p))
+}
+
+func TestEnsureLine(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p, "a\r\nb", nil))
+
+	changed, err := kit.EnsureLine(p, "a")
+	kit.E(err)
+	assert.False(t, changed)
+
+	changed, err = kit.EnsureLine(p, "c")
+	kit.E(err)
+	assert.True(t, changed)
+	assert.Equal(t, "a\r\nb\nc\n", readString(p))
+
+	changed, err = kit.EnsureLine(p+"-new", "a")
+	kit.E(err)
+	assert.True(t, changed)
+	assert.Equal(t, "a\n", readString(p+"-new"))
+}
+
+func TestEnsureSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlink requires privilege on windows")
+	}
+
+	p := "tmp/" + kit.RandString(10)
+
+	changed, err := kit.EnsureSymlink(p+"/link", "a")
+	kit.E(err)
+	assert.True(t, changed)
+
+	changed, err = kit.EnsureSymlink(p+"/link", "a")
+	kit.E(err)
+	assert.False(t, changed)
+
+	changed, err = kit.EnsureSymlink(p+"/link", "b")
+	kit.E(err)
+	assert.True(t, changed)
+	target, _ := os.Readlink(p + "/link")
+	assert.Equal(t, "b", target)
+
+	kit.E(kit.OutputFile(p+"/file", "", nil))
+	_, err = kit.EnsureSymlink(p+"/file", "b")
+	assert.EqualError(t, err, p+"/file exists and is not a symlink")
+}
+
+func TestEnsureDir(t *testing.T) {
+	p := "tmp/" + kit.RandString(10) + "/a"
+
+	changed, err := kit.EnsureDir(p, 0700)
+	kit.E(err)
+	assert.True(t, changed)
+
+	changed, err = kit.EnsureDir(p, 0700)
+	kit.E(err)
+	assert.False(t, changed)
+
+	if runtime.GOOS != "windows" {
+		changed, err = kit.EnsureDir(p, 0755)
+		kit.E(err)
+		assert.True(t, changed)
+	}
+
+	kit.E(kit.OutputFile(p+"/f", "", nil))
+	_, err = kit.EnsureDir(p+"/f", 0755)
+	assert.EqualError(t, err, p+"/f exists and is not a dir")
+}
+
+func readString(p string) string {
+	s, err := kit.ReadString(p)
+	kit.E(err)
+	return s
+}
@@ -0,0 +1,75 @@
+package os
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvSnap a copy of the env of the process, the key is the name of the variable
+type EnvSnap map[string]string
+
+// EnvSnapshot copies the env of the process
+func EnvSnapshot() EnvSnap {
+	snap := EnvSnap{}
+	for _, kv := range os.Environ() {
+		if kv == "" {
+			continue
+		}
+		// the names of the special variables on windows start with "=", such as "=C:"
+		i := strings.Index(kv[1:], "=") + 1
+		if i < 1 {
+			continue
+		}
+		snap[kv[:i]] = kv[i+1:]
+	}
+	return snap
+}
+
+// EnvRestore resets the env of the process to the snap, the variables that aren't in the snap are removed
+func EnvRestore(snap EnvSnap) error {
+	for k := range EnvSnapshot() {
+		if _, has := snap[k]; !has && !strings.HasPrefix(k, "=") {
+			if err := os.Unsetenv(k); err != nil {
+				return err
+			}
+		}
+	}
+
+	for k, v := range snap {
+		if strings.HasPrefix(k, "=") {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var envLock sync.Mutex
+
+// WithEnv sets the env, calls fn, then restores the env even if fn panics, such as
+// WithEnv(map[string]string{"GOOS": "linux", "CGO_ENABLED": "0"}, build).
+// The calls are serialized, so the parallel tasks that use it won't see the env of each other,
+// but the code that changes the env without it isn't guarded.
+func WithEnv(env map[string]string, fn func()) (err error) {
+	envLock.Lock()
+	defer envLock.Unlock()
+
+	snap := EnvSnapshot()
+	defer func() {
+		if e := EnvRestore(snap); err == nil {
+			err = e
+		}
+	}()
+
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	fn()
+	return nil
+}
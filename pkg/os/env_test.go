@@ -0,0 +1,39 @@
+package os_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestEnvSnapshot(t *testing.T) {
+	snap := kit.EnvSnapshot()
+	assert.Equal(t, os.Getenv("PATH"), snap["PATH"])
+
+	kit.E(os.Setenv("KIT_TEST_ENV_NEW", "1"))
+	kit.E(os.Setenv("PATH", ""))
+	kit.E(kit.EnvRestore(snap))
+
+	_, has := os.LookupEnv("KIT_TEST_ENV_NEW")
+	assert.False(t, has)
+	assert.Equal(t, snap["PATH"], os.Getenv("PATH"))
+}
+
+func TestWithEnv(t *testing.T) {
+	goos := os.Getenv("GOOS")
+
+	err := kit.WithEnv(map[string]string{"GOOS": "plan9", "KIT_TEST_ENV": "a=b"}, func() {
+		assert.Equal(t, "plan9", os.Getenv("GOOS"))
+		assert.Equal(t, "a=b", kit.EnvSnapshot()["KIT_TEST_ENV"])
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, goos, os.Getenv("GOOS"))
+
+	assert.Panics(t, func() {
+		_ = kit.WithEnv(map[string]string{"KIT_TEST_ENV": "1"}, func() { panic("err") })
+	})
+	_, has := os.LookupEnv("KIT_TEST_ENV")
+	assert.False(t, has)
+}
@@ -0,0 +1,175 @@
+package os
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/karrick/godirwalk"
+)
+
+// MissingError lists the patterns that match nothing
+type MissingError struct {
+	Patterns []string
+}
+
+func (e *MissingError) Error() string {
+	return "missing files:\n  " + strings.Join(e.Patterns, "\n  ")
+}
+
+// ExistsAll returns the patterns that match nothing, such as checking the artifacts after a build:
+// ExistsAll("dist/index.html", "dist/*.js", "!dist/*.map"). The plain paths are checked directly,
+// all the glob patterns are checked with a single walk from their common parent dir.
+// The "!" patterns are the filters for all the glob patterns. The patterns are relative to the working directory.
+func ExistsAll(patterns ...string) (missing []string) {
+	plain, globs, filters := splitExistPatterns(patterns)
+
+	for _, p := range plain {
+		if !Exists(p) {
+			missing = append(missing, p)
+		}
+	}
+
+	found := make([]bool, len(globs))
+	left := len(globs)
+	_ = walkExists(globs, filters, func(i int) bool {
+		if !found[i] {
+			found[i] = true
+			left--
+		}
+		return left == 0
+	})
+
+	for i, g := range globs {
+		if !found[i] {
+			missing = append(missing, g)
+		}
+	}
+	return
+}
+
+// AnyExists returns true if any of the patterns matches a path, the walk stops at the first match.
+// The patterns are the same as ExistsAll.
+func AnyExists(patterns ...string) bool {
+	plain, globs, filters := splitExistPatterns(patterns)
+
+	for _, p := range plain {
+		if Exists(p) {
+			return true
+		}
+	}
+
+	found := false
+	_ = walkExists(globs, filters, func(int) bool {
+		found = true
+		return true
+	})
+	return found
+}
+
+// CheckExists returns a *MissingError that lists the patterns that match nothing, it's handy for the CI scripts
+func CheckExists(patterns ...string) error {
+	missing := ExistsAll(patterns...)
+	if len(missing) == 0 {
+		return nil
+	}
+	return &MissingError{missing}
+}
+
+func splitExistPatterns(patterns []string) (plain, globs, filters []string) {
+	for _, p := range patterns {
+		switch {
+		case len(p) > 1 && p[0] == '!':
+			filters = append(filters, p)
+		case isGlob(p):
+			globs = append(globs, p)
+		default:
+			plain = append(plain, p)
+		}
+	}
+	return
+}
+
+var errStopWalk = errors.New("stop walk")
+
+// walk once for all the globs, the fn is called with the index of the glob that matches a path,
+// the walk stops when the fn returns true
+func walkExists(globs, filters []string, fn func(i int) bool) error {
+	if len(globs) == 0 {
+		return nil
+	}
+
+	dir := ""
+	absGlobs := []string{}
+	for i, g := range globs {
+		g = MustAbs(g)
+		absGlobs = append(absGlobs, g)
+
+		if i == 0 {
+			dir = globBase(g)
+		} else {
+			dir = commonDir(dir, globBase(g))
+		}
+	}
+
+	for _, f := range filters {
+		if f != WalkGitIgnore {
+			dir = commonDir(dir, globBase(MustAbs(f[1:])))
+		}
+	}
+
+	// the negative patterns can't be absolute, they are relative to the dir
+	relFilters := []string{}
+	for _, f := range filters {
+		if f != WalkGitIgnore {
+			if rel, err := filepath.Rel(dir, MustAbs(f[1:])); err == nil {
+				f = "!" + rel
+			}
+		}
+		relFilters = append(relFilters, f)
+	}
+
+	if !DirExists(dir) {
+		return nil
+	}
+
+	matchers := []*Matcher{}
+	for _, g := range absGlobs {
+		matchers = append(matchers, NewMatcher(dir, []string{g}))
+	}
+
+	err := Walk(append(absGlobs, relFilters...)...).Dir(dir).Do(func(p string, info *godirwalk.Dirent) error {
+		for i, m := range matchers {
+			if matched, _, _ := m.Match(p, info.IsDir()); matched && fn(i) {
+				return errStopWalk
+			}
+		}
+		return nil
+	})
+	if err == errStopWalk {
+		return nil
+	}
+	return err
+}
+
+// the dir before the first segment that has a glob char
+func globBase(pattern string) string {
+	parts := strings.Split(pattern, string(filepath.Separator))
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[{") {
+			return filepath.Clean(strings.Join(parts[:i], string(filepath.Separator)) + string(filepath.Separator))
+		}
+	}
+	return filepath.Dir(pattern)
+}
+
+func commonDir(a, b string) string {
+	for !IsSubPath(a, b) {
+		parent := filepath.Dir(a)
+		if parent == a {
+			break
+		}
+		a = parent
+	}
+	return a
+}
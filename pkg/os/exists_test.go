@@ -0,0 +1,31 @@
+package os_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestExistsAll(t *testing.T) {
+	p := "tmp/exists/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/dist/index.html", "", nil)
+	_ = kit.OutputFile(p+"/dist/js/app.js", "", nil)
+	_ = kit.OutputFile(p+"/dist/js/app.js.map", "", nil)
+	_ = kit.OutputFile(p+"/lib/a.so", "", nil)
+
+	assert.Empty(t, kit.ExistsAll(p+"/dist/index.html", p+"/dist/**/*.js", p+"/lib/*.so"))
+
+	assert.Equal(t, []string{p + "/dist/a.css", p + "/dist/*.css", p + "/dist/**/*.map"}, kit.ExistsAll(
+		p+"/dist/index.html", p+"/dist/a.css", p+"/dist/*.css", p+"/dist/**/*.map", "!"+p+"/**/*.map",
+	))
+
+	assert.Equal(t, []string{p + "/x/*.js"}, kit.ExistsAll(p+"/x/*.js"))
+
+	assert.True(t, kit.AnyExists(p+"/dist/*.css", p+"/dist/js/*.js"))
+	assert.True(t, kit.AnyExists(p+"/x", p+"/lib/a.so"))
+	assert.False(t, kit.AnyExists(p+"/dist/*.css", p+"/x"))
+
+	assert.Nil(t, kit.CheckExists(p+"/dist/*.html"))
+	assert.EqualError(t, kit.CheckExists(p+"/dist/*.css", p+"/x"), "missing files:\n  "+p+"/x\n  "+p+"/dist/*.css")
+}
@@ -9,13 +9,9 @@ import (
 	"github.com/hectane/go-acl"
 	"github.com/karrick/godirwalk"
 	"github.com/mitchellh/go-homedir"
-	"github.com/otiai10/copy"
 	"github.com/ysmood/kit/pkg/utils"
 )
 
-// Copy file or dir recursively
-var Copy = copy.Copy
-
 // Chmod ...
 var Chmod = acl.Chmod
 
@@ -49,33 +45,38 @@ type OutputFileOptions struct {
 	JSONIndent string
 }
 
+func defaultOutputFileOptions() *OutputFileOptions {
+	return &OutputFileOptions{0775, 0664, "", "    "}
+}
+
 // OutputFile auto creates file if not exists, it will try to detect the data type and
 // auto output binary, string or json
 func OutputFile(p string, data interface{}, options *OutputFileOptions) error {
 	if options == nil {
-		options = &OutputFileOptions{0775, 0664, "", "    "}
+		options = defaultOutputFileOptions()
 	}
 
 	dir := filepath.Dir(p)
 	_ = Mkdir(dir, &MkdirOptions{Perm: options.DirPerm})
 
-	var bin []byte
+	bin, err := encodeData(data, options)
+	if err != nil {
+		return err
+	}
 
+	return ioutil.WriteFile(p, bin, options.FilePerm)
+}
+
+// the binary, string or json of the data
+func encodeData(data interface{}, options *OutputFileOptions) ([]byte, error) {
 	switch t := data.(type) {
 	case []byte:
-		bin = t
+		return t, nil
 	case string:
-		bin = []byte(t)
+		return []byte(t), nil
 	default:
-		var err error
-		bin, err = json.MarshalIndent(data, options.JSONPrefix, options.JSONIndent)
-
-		if err != nil {
-			return err
-		}
+		return json.MarshalIndent(data, options.JSONPrefix, options.JSONIndent)
 	}
-
-	return ioutil.WriteFile(p, bin, options.FilePerm)
 }
 
 // ReadFile reads file as bytes
@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/otiai10/copy"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ysmood/kit"
@@ -206,6 +207,30 @@ func TestMoveErr(t *testing.T) {
 	assert.Regexp(t, "not a directory|cannot find the path specified", err.Error())
 }
 
+func TestCopy(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/a/b", "b", &kit.OutputFileOptions{DirPerm: 0775, FilePerm: 0600})
+	_ = kit.OutputFile(p+"/a/c/d", "d", nil)
+	_ = kit.OutputFile(p+"/a/c/e", "e", nil)
+	_ = kit.OutputFile(p+"/f", "old", nil)
+
+	kit.MustCopy(p+"/a/b", p+"/f")
+	assert.Equal(t, "b", kit.E(kit.ReadString(p+"/f"))[0])
+
+	kit.MustCopy(p+"/a", p+"/g", copy.Options{Skip: func(_ os.FileInfo, src, _ string) (bool, error) {
+		return filepath.Base(src) == "e", nil
+	}})
+	assert.Equal(t, "b", kit.E(kit.ReadString(p+"/g/b"))[0])
+	assert.Equal(t, "d", kit.E(kit.ReadString(p+"/g/c/d"))[0])
+	assert.False(t, kit.Exists(p+"/g/c/e"))
+
+	if runtime.GOOS != "windows" {
+		stat, _ := os.Stat(p + "/g/b")
+		assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	}
+}
+
 func TestDirExists(t *testing.T) {
 	p := "tmp/" + kit.RandString(10)
 
@@ -0,0 +1,67 @@
+package os
+
+import (
+	"sort"
+	"strings"
+)
+
+// ExpandGlobs replaces the glob patterns in the args with the matched paths, like what a shell does.
+// The consecutive patterns are matched together like Walk, so the "!" patterns after a glob pattern
+// are negative filters, such as ExpandGlobs([]string{"-rf", "dist/**", "!dist/keep"}).
+// The patterns are relative to the working directory, the paths are sorted.
+// If nothing matches, the patterns will be kept as they are.
+func ExpandGlobs(args []string) []string {
+	list := []string{}
+	group := []string{}
+
+	expand := func() {
+		if len(group) == 0 {
+			return
+		}
+
+		paths := expandGlob(group)
+		if len(paths) == 0 {
+			list = append(list, group...)
+		} else {
+			list = append(list, paths...)
+		}
+		group = []string{}
+	}
+
+	for _, arg := range args {
+		if isGlob(arg) || (len(group) > 0 && len(arg) > 1 && arg[0] == '!') {
+			group = append(group, arg)
+			continue
+		}
+
+		expand()
+		list = append(list, arg)
+	}
+	expand()
+
+	return list
+}
+
+func isGlob(arg string) bool {
+	return len(arg) > 0 && arg[0] != '!' && strings.ContainsAny(arg, "*?[{")
+}
+
+func expandGlob(patterns []string) []string {
+	list, err := Walk(patterns...).Sort().List()
+	if err != nil {
+		return nil
+	}
+
+	paths := []string{}
+	for _, p := range list {
+		if rel, err := RelToWd(p); err == nil {
+			p = rel
+		}
+		if p != "." {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	return paths
+}
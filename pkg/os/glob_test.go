@@ -0,0 +1,23 @@
+package os_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestExpandGlobs(t *testing.T) {
+	_ = kit.OutputFile("tmp/expand/a.txt", "", nil)
+	_ = kit.OutputFile("tmp/expand/b.txt", "", nil)
+	_ = kit.OutputFile("tmp/expand/c.md", "", nil)
+
+	args := kit.ExpandGlobs([]string{
+		"-rf", "tmp/expand/*.txt", "!tmp/expand/b.txt", "--", "!x", "tmp/expand/*.go",
+	})
+
+	assert.Equal(t, []string{
+		"-rf", filepath.FromSlash("tmp/expand/a.txt"), "--", "!x", "tmp/expand/*.go",
+	}, args)
+}
@@ -0,0 +1,160 @@
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+	"github.com/karrick/godirwalk"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// MirrorContext ...
+type MirrorContext struct {
+	dir        string
+	patterns   []string
+	dest       string
+	transforms []mirrorTransform
+}
+
+// MirrorFile the file to copy, the transforms can change the Path and the Data
+type MirrorFile struct {
+	Src  string // the path of the source file
+	Path string // the path relative to the dest dir, such as rename "a.md" to "a.html"
+	Data []byte
+	Skip bool // don't write the file
+}
+
+type mirrorTransform struct {
+	patterns []string
+	fn       func(f *MirrorFile) error
+}
+
+// Mirror copies the files that match the srcPatterns to the destDir, the structure of the dirs is preserved.
+// The patterns are the same as Walk. The files inside the destDir won't be copied, so it's safe
+// to mirror a dir into its subdir, such as Mirror([]string{"**"}, "dist").
+// The files that no Transform matches are cloned on the filesystems that support copy-on-write, the same as Copy.
+func Mirror(srcPatterns []string, destDir string) *MirrorContext {
+	return &MirrorContext{
+		dir:      ".",
+		patterns: srcPatterns,
+		dest:     destDir,
+	}
+}
+
+// Dir sets the source dir, the default is the current dir
+func (ctx *MirrorContext) Dir(d string) *MirrorContext {
+	ctx.dir = d
+	return ctx
+}
+
+// Transform calls fn with the files whose relative paths match the patterns before they are written,
+// such as rendering templates or minification. If no pattern is set all the files match.
+// The transforms run in the order they are added.
+func (ctx *MirrorContext) Transform(fn func(f *MirrorFile) error, patterns ...string) *MirrorContext {
+	ctx.transforms = append(ctx.transforms, mirrorTransform{patterns, fn})
+	return ctx
+}
+
+// Do copies the files
+func (ctx *MirrorContext) Do() error {
+	src, err := filepath.Abs(ctx.dir)
+	if err != nil {
+		return err
+	}
+	dest, err := filepath.Abs(ctx.dest)
+	if err != nil {
+		return err
+	}
+
+	return Walk(ctx.patterns...).Dir(src).Do(func(p string, info *godirwalk.Dirent) error {
+		if p == dest || strings.HasPrefix(p, dest+string(os.PathSeparator)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isDir, _ := info.IsDirOrSymlinkToDir(); isDir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+
+		return ctx.copy(p, rel, dest)
+	})
+}
+
+// MustDo ...
+func (ctx *MirrorContext) MustDo() {
+	utils.E(ctx.Do())
+}
+
+func (ctx *MirrorContext) copy(p, rel, dest string) error {
+	stat, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	transforms := []mirrorTransform{}
+	for _, t := range ctx.transforms {
+		matched, err := t.match(rel)
+		if err != nil {
+			return err
+		}
+		if matched {
+			transforms = append(transforms, t)
+		}
+	}
+
+	// the file isn't changed, clone it if the filesystem supports copy-on-write
+	if len(transforms) == 0 && stat.Mode().IsRegular() {
+		to := filepath.Join(dest, rel)
+		if err := Mkdir(filepath.Dir(to), nil); err != nil {
+			return err
+		}
+		if clone(p, to, stat.Mode().Perm()) == nil {
+			return nil
+		}
+	}
+
+	data, err := ReadFile(p)
+	if err != nil {
+		return err
+	}
+
+	f := &MirrorFile{Src: p, Path: rel, Data: data}
+
+	for _, t := range transforms {
+		if err := t.fn(f); err != nil {
+			return err
+		}
+		if f.Skip {
+			return nil
+		}
+	}
+
+	return OutputFile(filepath.Join(dest, f.Path), f.Data, &OutputFileOptions{
+		DirPerm:  0775,
+		FilePerm: stat.Mode().Perm(),
+	})
+}
+
+// the patterns use "/" as the separator
+func (t mirrorTransform) match(rel string) (bool, error) {
+	if len(t.patterns) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range t.patterns {
+		matched, err := doublestar.PathMatch(filepath.FromSlash(pattern), rel)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,60 @@
+package os_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestMirror(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	kit.E(kit.OutputFile(p+"/a.md", "a", nil))
+	kit.E(kit.OutputFile(p+"/b/c.txt", "c", nil))
+	kit.E(kit.OutputFile(p+"/b/d.log", "d", nil))
+	kit.E(kit.OutputFile(p+"/b/skip.txt", "skip", nil))
+	kit.E(kit.OutputFile(p+"/dist/old.txt", "old", nil))
+
+	kit.Mirror([]string{"**", "!**/*.log"}, p+"/dist").Dir(p).
+		Transform(func(f *kit.MirrorFile) error {
+			f.Path = strings.TrimSuffix(f.Path, ".md") + ".html"
+			f.Data = append([]byte("<p>"), append(f.Data, "</p>"...)...)
+			return nil
+		}, "*.md").
+		Transform(func(f *kit.MirrorFile) error {
+			f.Skip = filepath.Base(f.Path) == "skip.txt"
+			return nil
+		}).
+		MustDo()
+
+	dist, _ := filepath.Abs(p + "/dist")
+	list := kit.Walk("**").Dir(dist).Sort().MustList()
+	rel := []string{}
+	for _, f := range list {
+		if kit.FileExists(f) {
+			r, _ := filepath.Rel(dist, f)
+			rel = append(rel, filepath.ToSlash(r))
+		}
+	}
+	assert.Equal(t, []string{"a.html", "b/c.txt", "old.txt"}, rel)
+
+	assert.Equal(t, "<p>a</p>", kit.E(kit.ReadString(p+"/dist/a.html"))[0])
+	assert.Equal(t, "c", kit.E(kit.ReadString(p+"/dist/b/c.txt"))[0])
+}
+
+func TestMirrorErr(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	kit.E(kit.OutputFile(p+"/a", "a", nil))
+
+	err := kit.Mirror([]string{"**"}, p+"/dist").Dir(p).
+		Transform(func(f *kit.MirrorFile) error {
+			return errors.New("err")
+		}).Do()
+
+	assert.EqualError(t, err, "err")
+}
@@ -0,0 +1,61 @@
+package os
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// ExpandHome replaces the leading "~" of the p with the HomeDir, such as "~/.config" or "~",
+// other forms like "~user" are returned as they are
+func ExpandHome(p string) string {
+	if p == "~" {
+		return HomeDir()
+	}
+	if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~"+string(filepath.Separator)) {
+		return filepath.Join(HomeDir(), p[2:])
+	}
+	return p
+}
+
+// NormalizePath expands the "~", converts the slashes to the separators of the OS, and cleans the p
+func NormalizePath(p string) string {
+	return filepath.Clean(filepath.FromSlash(ExpandHome(p)))
+}
+
+// MustAbs returns the absolute path of the normalized p, panics if the working dir is unknown
+func MustAbs(p string) string {
+	abs, err := filepath.Abs(NormalizePath(p))
+	utils.E(err)
+	return abs
+}
+
+// RelTo returns the p relative to the base, both of them are converted to absolute paths first,
+// so they can be relative to the working dir. It fails if the p can't be relative to the base,
+// such as they are on different volumes on Windows.
+func RelTo(base, p string) (string, error) {
+	absBase, err := filepath.Abs(NormalizePath(base))
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(NormalizePath(p))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absBase, abs)
+}
+
+// RelToWd returns the p relative to the working dir
+func RelToWd(p string) (string, error) {
+	return RelTo(".", p)
+}
+
+// IsSubPath returns true if the child is the parent or inside it, they are compared as absolute paths
+func IsSubPath(parent, child string) bool {
+	rel, err := RelTo(parent, child)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,60 @@
+package os_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ysmood/kit"
+)
+
+func TestExpandHome(t *testing.T) {
+	home := kit.HomeDir()
+
+	assert.Equal(t, home, kit.ExpandHome("~"))
+	assert.Equal(t, filepath.Join(home, "a", "b"), kit.ExpandHome("~/a/b"))
+	assert.Equal(t, "~user/a", kit.ExpandHome("~user/a"))
+	assert.Equal(t, "a/~", kit.ExpandHome("a/~"))
+	assert.Equal(t, "", kit.ExpandHome(""))
+}
+
+func TestNormalizePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("a", "c"), kit.NormalizePath("a/b/../c/"))
+	assert.Equal(t, filepath.Join(kit.HomeDir(), "a"), kit.NormalizePath("~/a/./"))
+	assert.Equal(t, ".", kit.NormalizePath(""))
+}
+
+func TestMustAbs(t *testing.T) {
+	wd, _ := os.Getwd()
+
+	assert.Equal(t, filepath.Join(wd, "a"), kit.MustAbs("a/b/.."))
+	assert.Equal(t, wd, kit.MustAbs(""))
+}
+
+func TestRelTo(t *testing.T) {
+	wd, _ := os.Getwd()
+
+	rel, err := kit.RelTo("a", filepath.Join(wd, "a", "b"))
+	assert.Nil(t, err)
+	assert.Equal(t, "b", rel)
+
+	rel, err = kit.RelTo("a/b", "a/c")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join("..", "c"), rel)
+
+	rel, err = kit.RelToWd(filepath.Join(wd, "a"))
+	assert.Nil(t, err)
+	assert.Equal(t, "a", rel)
+}
+
+func TestIsSubPath(t *testing.T) {
+	assert.True(t, kit.IsSubPath("a", "a/b/c"))
+	assert.True(t, kit.IsSubPath("a", "a"))
+	assert.True(t, kit.IsSubPath("a", "a/b/../c"))
+	assert.False(t, kit.IsSubPath("a", "ab"))
+	assert.False(t, kit.IsSubPath("a/b", "a"))
+	assert.False(t, kit.IsSubPath("a", "a/../b"))
+	assert.True(t, kit.IsSubPath(".", "..a"))
+}
@@ -0,0 +1,166 @@
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateWriter appends to a file and rotates it by size or time, the rotated files are renamed with the time,
+// such as "app.20060102-150405.000.log". It's safe for concurrent use.
+type RotateWriter struct {
+	path     string
+	maxSize  int64
+	interval time.Duration
+	backups  int
+
+	lock   sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// RotateFile creates a RotateWriter, by default the file is rotated when it's larger than 10MB,
+// and 5 rotated files are kept. The file and its dir are created on the first write.
+func RotateFile(path string) *RotateWriter {
+	return &RotateWriter{
+		path:    path,
+		maxSize: 10 * 1024 * 1024,
+		backups: 5,
+	}
+}
+
+// MaxSize rotates the file before it gets larger than n bytes, 0 means no limit
+func (w *RotateWriter) MaxSize(n int64) *RotateWriter {
+	w.maxSize = n
+	return w
+}
+
+// Interval rotates the file when it has been written for d, 0 means no limit
+func (w *RotateWriter) Interval(d time.Duration) *RotateWriter {
+	w.interval = d
+	return w
+}
+
+// Backups the number of the rotated files to keep, the older ones are removed, a negative n keeps all
+func (w *RotateWriter) Backups(n int) *RotateWriter {
+	w.backups = n
+	return w
+}
+
+// Write ...
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file != nil && w.full(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close ...
+func (w *RotateWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotateWriter) full(n int64) bool {
+	if w.maxSize > 0 && w.size > 0 && w.size+n > w.maxSize {
+		return true
+	}
+	return w.interval > 0 && time.Since(w.opened) >= w.interval
+}
+
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	w.opened = time.Now()
+	return nil
+}
+
+func (w *RotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	name := base + "." + time.Now().Format("20060102-150405.000") + ext
+
+	if err := os.Rename(w.path, name); err != nil {
+		return err
+	}
+
+	return w.prune(base, ext)
+}
+
+// remove the oldest rotated files
+func (w *RotateWriter) prune(base, ext string) error {
+	if w.backups < 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(base))
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Base(base) + "."
+	list := []string{}
+	for _, e := range entries {
+		name := e.Name()
+		if len(name) > len(prefix)+len(ext) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) &&
+			name[len(prefix)] >= '0' && name[len(prefix)] <= '9' {
+			list = append(list, filepath.Join(filepath.Dir(base), name))
+		}
+	}
+
+	// the time in the names sorts the files from old to new
+	sort.Strings(list)
+
+	for len(list) > w.backups {
+		if err := os.Remove(list[0]); err != nil {
+			return err
+		}
+		list = list[1:]
+	}
+	return nil
+}
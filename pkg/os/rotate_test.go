@@ -0,0 +1,54 @@
+package os_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+)
+
+func TestRotateFile(t *testing.T) {
+	dir := "tmp/" + kit.RandString(10)
+	p := filepath.Join(dir, "a.log")
+
+	w := kit.RotateFile(p).MaxSize(10).Backups(2)
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("123456"))
+		assert.Nil(t, err)
+		time.Sleep(2 * time.Millisecond)
+	}
+	kit.E(w.Close())
+
+	entries, _ := os.ReadDir(dir)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+		if e.Name() != "a.log" {
+			assert.True(t, strings.HasPrefix(e.Name(), "a.2"), e.Name())
+		}
+	}
+	assert.Len(t, names, 3)
+	s, _ := kit.ReadString(p)
+	assert.Equal(t, "123456", s)
+}
+
+func TestRotateFileInterval(t *testing.T) {
+	dir := "tmp/" + kit.RandString(10)
+	p := filepath.Join(dir, "a.log")
+
+	w := kit.RotateFile(p).MaxSize(0).Interval(10 * time.Millisecond)
+	_, _ = w.Write([]byte("a"))
+	_, _ = w.Write([]byte("b"))
+	time.Sleep(20 * time.Millisecond)
+	_, _ = w.Write([]byte("c"))
+	kit.E(w.Close())
+
+	s, _ := kit.ReadString(p)
+	assert.Equal(t, "c", s)
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 2)
+}
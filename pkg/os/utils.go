@@ -1,8 +1,10 @@
 package os
 
 import (
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 )
 
@@ -44,3 +46,20 @@ func RetryPanic(times int, wait time.Duration, fn func()) (errs []interface{}) {
 	}
 	return errs
 }
+
+// InContainer checks if the current process is running inside a container, such as docker or kubernetes
+func InContainer() bool {
+	if Exists("/.dockerenv") || Exists("/run/.containerenv") || os.Getenv("container") != "" {
+		return true
+	}
+
+	b, _ := ioutil.ReadFile("/proc/1/cgroup")
+	s := string(b)
+	for _, key := range []string{"docker", "kubepods", "containerd", "lxc"} {
+		if strings.Contains(s, key) {
+			return true
+		}
+	}
+
+	return false
+}
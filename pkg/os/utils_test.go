@@ -80,3 +80,12 @@ func TestEscape(t *T) {
 
 	assert.Equal(t, expected, kit.Escape("/?*"))
 }
+
+func TestInContainer(t *T) {
+	if kit.Exists("/.dockerenv") {
+		assert.True(t, kit.InContainer())
+	}
+
+	t.Setenv("container", "podman")
+	assert.True(t, kit.InContainer())
+}
@@ -1,13 +1,18 @@
 package os
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/bmatcuk/doublestar"
 	"github.com/karrick/godirwalk"
 	gitignore "github.com/monochromegane/go-gitignore"
 	"github.com/ysmood/kit/pkg/utils"
@@ -20,6 +25,10 @@ type WalkContext struct {
 	followSymbolicLinks  bool
 	postChildrenCallback WalkFunc
 	matcher              *Matcher
+	olderThan            time.Duration
+	largerThan           int64
+	sameDevice           bool
+	intoArchives         bool
 
 	callback WalkFunc
 	patterns []string
@@ -49,9 +58,9 @@ func Walk(patterns ...string) *WalkContext {
 	}
 }
 
-// Dir set dir
+// Dir set dir, the leading "~" is expanded to the home dir
 func (ctx *WalkContext) Dir(d string) *WalkContext {
-	ctx.dir = d
+	ctx.dir = ExpandHome(d)
 	return ctx
 }
 
@@ -61,7 +70,7 @@ func (ctx *WalkContext) Sort() *WalkContext {
 	return ctx
 }
 
-// FollowSymbolicLinks ...
+// FollowSymbolicLinks descends into the symlinks to dirs, the links to the dirs that are already walked are skipped
 func (ctx *WalkContext) FollowSymbolicLinks() *WalkContext {
 	ctx.followSymbolicLinks = true
 	return ctx
@@ -79,19 +88,132 @@ func (ctx *WalkContext) Matcher(m *Matcher) *WalkContext {
 	return ctx
 }
 
+// OlderThan only walks the files that are not modified within d, the dirs will be skipped
+func (ctx *WalkContext) OlderThan(d time.Duration) *WalkContext {
+	ctx.olderThan = d
+	return ctx
+}
+
+// LargerThan only walks the files that are larger than size bytes, the dirs will be skipped
+func (ctx *WalkContext) LargerThan(size int64) *WalkContext {
+	ctx.largerThan = size
+	return ctx
+}
+
+// SameDevice won't descend into the dirs on other devices, such as network mounts or bind-mounted caches,
+// like the -xdev of find
+func (ctx *WalkContext) SameDevice() *WalkContext {
+	ctx.sameDevice = true
+	return ctx
+}
+
+// skip the dirs that are not on the device of the root
+func (ctx *WalkContext) xdev(root string, cb WalkFunc) (WalkFunc, error) {
+	if !ctx.sameDevice {
+		return cb, nil
+	}
+
+	dev, err := deviceID(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(p string, info *godirwalk.Dirent) error {
+		// the symlinks are only descended when they are followed
+		isDir := info.IsDir()
+		if !isDir && ctx.followSymbolicLinks {
+			isDir, _ = info.IsDirOrSymlinkToDir()
+		}
+
+		if isDir {
+			d, err := deviceID(p)
+			if err == nil && d != dev {
+				return filepath.SkipDir
+			}
+		}
+		return cb(p, info)
+	}, nil
+}
+
+// skip the symlinks to the dirs that are already walked, such as a link to a parent dir,
+// because godirwalk doesn't detect the loops
+func (ctx *WalkContext) noLoop(root string, cb WalkFunc) WalkFunc {
+	if !ctx.followSymbolicLinks {
+		return cb
+	}
+
+	visited := map[string]bool{}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		visited[real] = true
+	}
+
+	return func(p string, info *godirwalk.Dirent) error {
+		if isDir, _ := info.IsDirOrSymlinkToDir(); isDir {
+			if real, err := filepath.EvalSymlinks(p); err == nil {
+				if info.IsSymlink() && visited[real] {
+					return filepath.SkipDir
+				}
+				visited[real] = true
+			}
+		}
+		return cb(p, info)
+	}
+}
+
+// filter the files by OlderThan and LargerThan
+func (ctx *WalkContext) filter(cb WalkFunc) WalkFunc {
+	if cb == nil || (ctx.olderThan == 0 && ctx.largerThan == 0) {
+		return cb
+	}
+
+	return func(p string, info *godirwalk.Dirent) error {
+		if info.IsDir() {
+			return nil
+		}
+
+		stat, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+
+		if !ctx.keep(stat) {
+			return nil
+		}
+
+		return cb(p, info)
+	}
+}
+
+// check the file by OlderThan and LargerThan
+func (ctx *WalkContext) keep(stat os.FileInfo) bool {
+	if ctx.olderThan != 0 && time.Since(stat.ModTime()) < ctx.olderThan {
+		return false
+	}
+	if ctx.largerThan != 0 && stat.Size() <= ctx.largerThan {
+		return false
+	}
+	return true
+}
+
 // Do execute walk
 func (ctx *WalkContext) Do(cb WalkFunc) error {
-	ctx.callback = cb
+	ctx.callback = ctx.filter(cb)
 
 	m := ctx.matcher
 	if m == nil {
 		m = NewMatcher(ctx.dir, ctx.patterns)
 	}
 
+	callback, err := ctx.xdev(m.dir, ctx.archives(m, cb, genMatchFn(m, ctx.callback)))
+	if err != nil {
+		return err
+	}
+	callback = ctx.noLoop(m.dir, callback)
+
 	return godirwalk.Walk(m.dir, &godirwalk.Options{
 		Unsorted:             !ctx.sort,
 		FollowSymbolicLinks:  ctx.followSymbolicLinks,
-		Callback:             genMatchFn(m, ctx.callback),
+		Callback:             callback,
 		PostChildrenCallback: genMatchFn(m, ctx.postChildrenCallback),
 	})
 }
@@ -110,6 +232,22 @@ func (ctx *WalkContext) MustList() []string {
 	return utils.E(ctx.List())[0].([]string)
 }
 
+// Remove removes the matched files, the dirs and the files in archives will be kept.
+// Such as remove the caches older than 7 days: Walk("cache/**").OlderThan(7 * 24 * time.Hour).Remove()
+func (ctx *WalkContext) Remove() error {
+	return ctx.Do(func(p string, info *godirwalk.Dirent) error {
+		if info.IsDir() || ctx.inArchive(p) {
+			return nil
+		}
+		return os.Remove(p)
+	})
+}
+
+// MustRemove ...
+func (ctx *WalkContext) MustRemove() {
+	utils.E(ctx.Remove())
+}
+
 func hasWalkGitIgnore(patterns []string) bool {
 	for _, p := range patterns {
 		if p == WalkGitIgnore {
@@ -145,22 +283,32 @@ func genMatchFn(
 	}
 }
 
-// Matcher ...
+// Matcher is safe for concurrent use. The patterns are compiled once, the ignore files are read once per dir,
+// and the results are memoized in a bounded LRU, the results of the paths in a dir are dropped when the ignore
+// file of the dir is loaded lazily. Use Invalidate or create a new Matcher to reload the rules.
 type Matcher struct {
 	dir           string
-	gitMatchers   map[string]gitignore.IgnoreMatcher
-	gitSubmodules []string
-	patterns      []string
+	gitGlobal     string
+	gitSubmodules map[string]bool
+	patterns      []*pattern
+
+	// guards the ignore files, it's not held while matching
+	lock           sync.RWMutex
+	gitMatchers    map[string]gitignore.IgnoreMatcher
+	ignoreFile     string
+	ignoreMatchers map[string]gitignore.IgnoreMatcher
+
+	cache *matchCache
 }
 
 // NewMatcher ...
 func NewMatcher(dir string, patterns []string) *Matcher {
-	dir, err := filepath.Abs(dir)
-	utils.E(err)
+	dir = MustAbs(dir)
 
 	homeDir := HomeDir()
 	gs := map[string]gitignore.IgnoreMatcher{}
-	var submodules []string
+	gPath := filepath.Join(homeDir, ".gitignore_global")
+	var submodules map[string]bool
 	if hasWalkGitIgnore(patterns) {
 		cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 		cmd.Dir = dir
@@ -169,7 +317,6 @@ func NewMatcher(dir string, patterns []string) *Matcher {
 			gitRoot := strings.TrimSpace(string(out))
 
 			submodules = getGitSubmodules(dir)
-			gPath := filepath.Join(homeDir, ".gitignore_global")
 			addIgnoreFile(gPath, dir, gs)
 
 			// check all parents
@@ -186,17 +333,24 @@ func NewMatcher(dir string, patterns []string) *Matcher {
 		}
 	}
 
+	compiled := []*pattern{}
+	for _, p := range normalizePatterns(dir, patterns) {
+		compiled = append(compiled, compilePattern(p))
+	}
+
 	return &Matcher{
 		dir:           dir,
 		gitMatchers:   gs,
+		gitGlobal:     gPath,
 		gitSubmodules: submodules,
-		patterns:      normalizePatterns(dir, patterns),
+		patterns:      compiled,
+		cache:         newMatchCache(matchCacheSize),
 	}
 }
 
 var submoduleReg = regexp.MustCompile(`\A [a-f0-9]+ (.+) \(.+\)\z`)
 
-func getGitSubmodules(dir string) []string {
+func getGitSubmodules(dir string) map[string]bool {
 	cmd := exec.Command("git", "submodule")
 	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
@@ -204,13 +358,12 @@ func getGitSubmodules(dir string) []string {
 		return nil
 	}
 
-	list := []string{}
+	list := map[string]bool{}
 	for _, l := range strings.Split(string(out), "\n") {
 		m := submoduleReg.FindStringSubmatch(l)
 
 		if len(m) > 1 {
-			p := filepath.Join(dir, m[1])
-			list = append(list, p)
+			list[filepath.Join(dir, m[1])] = true
 		}
 	}
 
@@ -223,22 +376,53 @@ func (m *Matcher) gitMatch(p string, isDir bool) bool {
 			return true
 		}
 
-		if m.gitSubmodules != nil {
-			for _, sub := range m.gitSubmodules {
-				if sub == p {
-					return true
-				}
-			}
+		if m.gitSubmodules[p] {
+			return true
 		}
 
-		addIgnoreFile(filepath.Join(p, ".gitignore"), p, m.gitMatchers)
+		m.loadIgnoreFile(true, p)
 	}
 
-	for f, g := range m.gitMatchers {
-		if !strings.HasPrefix(p, filepath.Dir(f)) {
-			continue
+	m.lock.RLock()
+	g := m.gitMatchers[m.gitGlobal]
+	m.lock.RUnlock()
+
+	if g != nil && g.Match(p, isDir) {
+		return true
+	}
+
+	return m.ancestorMatch(true, p, isDir)
+}
+
+// the ignore files of the gitignore or the IgnoreFile, the caller should hold the lock
+func (m *Matcher) ignoreFiles(git bool) (map[string]gitignore.IgnoreMatcher, string) {
+	if git {
+		return m.gitMatchers, ".gitignore"
+	}
+	return m.ignoreMatchers, m.ignoreFile
+}
+
+// check the rules of the ignore files in the dir p and its parents,
+// only the loaded files are checked, so the lookup is a map access per level
+func (m *Matcher) ancestorMatch(git bool, p string, isDir bool) bool {
+	list := []gitignore.IgnoreMatcher{}
+
+	m.lock.RLock()
+	gs, name := m.ignoreFiles(git)
+	for d := p; ; {
+		if g := gs[filepath.Join(d, name)]; g != nil {
+			list = append(list, g)
 		}
 
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	m.lock.RUnlock()
+
+	for _, g := range list {
 		if g.Match(p, isDir) {
 			return true
 		}
@@ -246,43 +430,205 @@ func (m *Matcher) gitMatch(p string, isDir bool) bool {
 	return false
 }
 
-func addIgnoreFile(file, dir string, gs map[string]gitignore.IgnoreMatcher) {
-	if _, has := gs[file]; !has {
-		g, err := gitignore.NewGitIgnore(file, dir)
-		if err == nil {
-			gs[file] = g
+// load the ignore file in the dir if it's not loaded yet, the file is read without holding the lock
+func (m *Matcher) loadIgnoreFile(git bool, dir string) {
+	m.lock.RLock()
+	gs, name := m.ignoreFiles(git)
+	file := filepath.Join(dir, name)
+	_, has := gs[file]
+	m.lock.RUnlock()
+
+	if has {
+		return
+	}
+
+	g, err := gitignore.NewGitIgnore(file, dir)
+
+	m.lock.Lock()
+	gs, name = m.ignoreFiles(git)
+	if _, has := gs[file]; has || name != filepath.Base(file) {
+		// loaded by another walk, or the IgnoreFile is changed
+		m.lock.Unlock()
+		return
+	}
+	if err != nil {
+		g = nil
+	}
+	gs[file] = g
+	m.lock.Unlock()
+
+	if g != nil {
+		m.dropCache(dir)
+	}
+}
+
+// IgnoreFile reads the files with the name in the matched dirs, such as ".guardignore", the syntax is the same as gitignore.
+// The rules in a file only apply to the dir where it lives, they are applied after the patterns.
+func (m *Matcher) IgnoreFile(name string) *Matcher {
+	m.lock.Lock()
+	m.ignoreFile = name
+	m.ignoreMatchers = map[string]gitignore.IgnoreMatcher{}
+	addIgnoreFile(filepath.Join(m.dir, name), m.dir, m.ignoreMatchers)
+	m.lock.Unlock()
+
+	m.cache.drop("")
+	return m
+}
+
+func (m *Matcher) ignoreMatch(p string, isDir bool) bool {
+	if m.ancestorMatch(false, p, isDir) {
+		return true
+	}
+
+	if isDir {
+		m.loadIgnoreFile(false, p)
+	}
+	return false
+}
+
+// drop the memoized results of the paths in the dir, they may be matched before the ignore file of the dir is loaded
+func (m *Matcher) dropCache(dir string) {
+	m.cache.drop(dir + string(os.PathSeparator))
+}
+
+// Invalidate reloads the ignore file, such as a ".gitignore" or the IgnoreFile that is created or changed,
+// and drops the memoized results. The other paths are ignored.
+func (m *Matcher) Invalidate(file string) {
+	file = MustAbs(file)
+	dir := filepath.Dir(file)
+
+	m.lock.Lock()
+
+	reload := func(gs map[string]gitignore.IgnoreMatcher) {
+		// the dirs that haven't been visited will be loaded lazily
+		if _, has := gs[file]; has {
+			delete(gs, file)
+			addIgnoreFile(file, dir, gs)
 		}
 	}
+
+	switch filepath.Base(file) {
+	case ".gitignore":
+		reload(m.gitMatchers)
+	case m.ignoreFile:
+		reload(m.ignoreMatchers)
+	default:
+		m.lock.Unlock()
+		return
+	}
+
+	m.lock.Unlock()
+
+	m.cache.drop("")
+}
+
+// the missing files are cached as nil so that they won't be read again until Invalidate
+func addIgnoreFile(file, dir string, gs map[string]gitignore.IgnoreMatcher) {
+	if _, has := gs[file]; has {
+		return
+	}
+
+	g, err := gitignore.NewGitIgnore(file, dir)
+	if err != nil {
+		gs[file] = nil
+		return
+	}
+	gs[file] = g
 }
 
 // Match ...
 func (m *Matcher) Match(p string, isDir bool) (matched, negative bool, err error) {
-	for _, pattern := range m.patterns {
-		if pattern == WalkGitIgnore {
+	r := m.match(p, isDir)
+	return r.matched, r.negative, r.err
+}
+
+// MatchPattern returns the pattern that makes the p matched, it's empty if the p is not matched
+func (m *Matcher) MatchPattern(p string, isDir bool) (string, error) {
+	r := m.match(p, isDir)
+	return r.pattern, r.err
+}
+
+// MatchRule returns the last rule that decides whether the p is matched, it's empty if no rule applies.
+// The rule is a pattern, a negative pattern such as "!*.txt", WalkGitIgnore, or the name of the IgnoreFile.
+func (m *Matcher) MatchRule(p string, isDir bool) (matched bool, rule string, err error) {
+	r := m.match(p, isDir)
+	return r.matched, r.rule, r.err
+}
+
+func (m *Matcher) match(p string, isDir bool) matchResult {
+	key := matchKey{p, isDir}
+
+	r, gen, has := m.cache.get(key)
+	if has {
+		return r
+	}
+
+	r = m.matchRules(p, isDir)
+	m.cache.put(key, r, gen)
+
+	return r
+}
+
+func (m *Matcher) matchRules(p string, isDir bool) (r matchResult) {
+	exclude := func(rule string) {
+		r.matched = false
+		r.negative = true
+		r.pattern = ""
+		r.rule = rule
+	}
+
+	name := p[len(m.dir):]
+	isRoot := len(name) == 0
+	if !isRoot && name[0] == os.PathSeparator {
+		name = name[1:]
+	}
+	var names []string
+
+	for _, pt := range m.patterns {
+		if pt.git {
 			if m.gitMatch(p, isDir) {
-				matched = false
-				negative = true
+				exclude(pt.raw)
 			}
 			continue
 		}
 
-		mm, neg, e := pathMatch(pattern, m.dir, p)
+		var mm bool
+		switch {
+		case pt.dot && p == m.dir:
+			mm = true
+		case isRoot:
+		default:
+			if names == nil {
+				names = splitName(name)
+			}
 
-		if e != nil {
-			err = e
-			return
+			var err error
+			mm, err = pt.match(name, names)
+			if err != nil {
+				r.err = err
+				return
+			}
 		}
 
 		if mm {
-			if neg {
-				negative = true
-				matched = false
+			if pt.negative {
+				exclude(pt.raw)
 			} else {
-				matched = true
+				r.matched = true
+				r.pattern = pt.raw
+				r.rule = pt.raw
 			}
 		}
 	}
 
+	m.lock.RLock()
+	ignoreFile := m.ignoreFile
+	m.lock.RUnlock()
+
+	if ignoreFile != "" && m.ignoreMatch(p, isDir) {
+		exclude(ignoreFile)
+	}
+
 	return
 }
 
@@ -300,33 +646,71 @@ func normalizePatterns(dir string, patterns []string) []string {
 	return newPatterns
 }
 
-func pathMatch(pattern, dir, path string) (bool, bool, error) {
-	name := path[len(dir):]
-	nameLen := len(name)
+// Duplicates walk and group the files that have the same content, each group has at least 2 files.
+// Files are grouped by size first, only the ones with the same size will be hashed.
+func (ctx *WalkContext) Duplicates() ([][]string, error) {
+	sizes := map[int64][]string{}
+	err := ctx.Do(func(p string, info *godirwalk.Dirent) error {
+		if !info.IsRegular() || ctx.inArchive(p) {
+			return nil
+		}
 
-	if nameLen > 0 && name[0] == os.PathSeparator {
-		name = name[1:]
+		fi, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		sizes[fi.Size()] = append(sizes[fi.Size()], p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	negative := false
+	groups := [][]string{}
+	for _, list := range sizes {
+		if len(list) < 2 {
+			continue
+		}
 
-	if pattern[0] == '!' {
-		pattern = pattern[1:]
-		negative = true
-	}
+		hashes := map[string][]string{}
+		for _, p := range list {
+			h, err := hashFile(p)
+			if err != nil {
+				return nil, err
+			}
+			hashes[h] = append(hashes[h], p)
+		}
 
-	if pattern == "." && path == dir {
-		return true, negative, nil
+		for _, group := range hashes {
+			if len(group) > 1 {
+				sort.Strings(group)
+				groups = append(groups, group)
+			}
+		}
 	}
 
-	if nameLen == 0 {
-		return false, negative, nil
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+
+	return groups, nil
+}
+
+// MustDuplicates ...
+func (ctx *WalkContext) MustDuplicates() [][]string {
+	return utils.E(ctx.Duplicates())[0].([][]string)
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
 	}
+	defer func() { _ = f.Close() }()
 
-	matched, err := doublestar.PathMatch(pattern, name)
+	h := sha256.New()
+	_, err = io.Copy(h, f)
 	if err != nil {
-		return false, false, err
+		return "", err
 	}
 
-	return matched, negative, nil
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
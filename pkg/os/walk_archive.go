@@ -0,0 +1,135 @@
+package os
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/karrick/godirwalk"
+)
+
+// WalkArchiveSep separates the path of the archive and the path of the entry in it,
+// such as "dist/app.zip!/bin/app"
+const WalkArchiveSep = "!" + string(os.PathSeparator)
+
+// IntoArchives also walks the files in the zip and tar archives, the paths of them are
+// joined by WalkArchiveSep, such as "dist/app.zip!/bin/app", so they can be matched by patterns
+// like "dist/*.zip!/bin/*". The dirs in the archives are omitted, and the entries
+// share the WalkDirent of the archive, because they don't exist on the disk.
+// A file that can't be read as an archive is walked as a plain file, the entries before the broken part are kept.
+// The supported extensions are .zip, .tar, .tar.gz, and .tgz.
+func (ctx *WalkContext) IntoArchives() *WalkContext {
+	ctx.intoArchives = true
+	return ctx
+}
+
+// after the archive file itself, walk the entries in it
+func (ctx *WalkContext) archives(m *Matcher, cb WalkFunc, match WalkFunc) WalkFunc {
+	if !ctx.intoArchives {
+		return match
+	}
+
+	return func(p string, info *godirwalk.Dirent) error {
+		err := match(p, info)
+		if err != nil || !info.IsRegular() || archiveType(p) == "" {
+			return err
+		}
+
+		// only the errors of the entries stop the walk
+		var stop error
+		_ = walkArchive(p, func(name string, stat fs.FileInfo) error {
+			if cb == nil || !ctx.keep(stat) {
+				return nil
+			}
+
+			vp := p + WalkArchiveSep + filepath.FromSlash(name)
+
+			matched, _, err := m.Match(vp, false)
+			if err == nil && matched {
+				err = cb(vp, info)
+			}
+			stop = err
+			return err
+		})
+
+		// the archive that can't be read, such as a broken one, is walked as a plain file
+		return stop
+	}
+}
+
+// the p is a file in an archive
+func (ctx *WalkContext) inArchive(p string) bool {
+	return ctx.intoArchives && strings.Contains(p, WalkArchiveSep)
+}
+
+func archiveType(p string) string {
+	l := strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(l, ".zip"):
+		return "zip"
+	case strings.HasSuffix(l, ".tar"):
+		return "tar"
+	case strings.HasSuffix(l, ".tar.gz"), strings.HasSuffix(l, ".tgz"):
+		return "tgz"
+	}
+	return ""
+}
+
+// call fn with the files in the archive, the name uses "/" as the separator
+func walkArchive(p string, fn func(name string, stat fs.FileInfo) error) error {
+	if archiveType(p) == "zip" {
+		r, err := zip.OpenReader(p)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = r.Close() }()
+
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			if err := fn(f.Name, f.FileInfo()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if archiveType(p) == "tgz" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
+		if err := fn(strings.TrimPrefix(h.Name, "./"), h.FileInfo()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package os
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// the max number of memoized results of a Matcher, the least recently used ones are evicted
+const matchCacheSize = 100000
+
+type matchKey struct {
+	path  string
+	isDir bool
+}
+
+type matchResult struct {
+	matched, negative bool
+	pattern, rule     string
+	err               error
+}
+
+type matchEntry struct {
+	key    matchKey
+	result matchResult
+}
+
+// matchCache is a bounded LRU of the match results, the lock is only held to access the entries
+type matchCache struct {
+	lock  sync.Mutex
+	size  int
+	list  *list.List
+	items map[matchKey]*list.Element
+
+	// it's bumped when the entries are dropped, a result that is computed before it won't be stored
+	gen int
+}
+
+func newMatchCache(size int) *matchCache {
+	return &matchCache{
+		size:  size,
+		list:  list.New(),
+		items: map[matchKey]*list.Element{},
+	}
+}
+
+// get returns the generation that should be passed to put with the computed result
+func (c *matchCache) get(key matchKey) (matchResult, int, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, has := c.items[key]; has {
+		c.list.MoveToFront(el)
+		return el.Value.(*matchEntry).result, c.gen, true
+	}
+	return matchResult{}, c.gen, false
+}
+
+func (c *matchCache) put(key matchKey, r matchResult, gen int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if el, has := c.items[key]; has {
+		el.Value.(*matchEntry).result = r
+		c.list.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.list.PushFront(&matchEntry{key, r})
+
+	if c.list.Len() > c.size {
+		el := c.list.Back()
+		c.list.Remove(el)
+		delete(c.items, el.Value.(*matchEntry).key)
+	}
+}
+
+// drop the entries of the paths that have the prefix, all entries are dropped if the prefix is empty
+func (c *matchCache) drop(prefix string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+
+	if prefix == "" {
+		c.list.Init()
+		c.items = map[matchKey]*list.Element{}
+		return
+	}
+
+	for k, el := range c.items {
+		if strings.HasPrefix(k.path, prefix) {
+			c.list.Remove(el)
+			delete(c.items, k)
+		}
+	}
+}
+
+func (c *matchCache) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.list.Len()
+}
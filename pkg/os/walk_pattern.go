@@ -0,0 +1,192 @@
+package os
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+// the pattern is compiled once per Matcher, so the paths are matched without parsing the pattern again.
+// The semantics are the same as doublestar.PathMatch, the patterns that it can't split safely,
+// such as the ones with escapes or alternatives, are left to doublestar.
+type pattern struct {
+	raw      string // the normalized pattern, such as "!**/*.go"
+	glob     string // the raw without the leading "!"
+	negative bool
+	git      bool // WalkGitIgnore
+	dot      bool // "." matches the dir itself
+
+	segs    []segment // nil if the glob is matched by doublestar
+	literal bool      // all the segments are literal, the name is compared as a whole
+	prefix  string    // the leading literal components, such as "src/" of "src/**/*.go"
+	ext     string    // the literal tail of the last component, such as ".go" of "**/*.go"
+}
+
+type segmentKind int
+
+const (
+	segLiteral  segmentKind = iota
+	segStar                 // only has "*" as the wildcard
+	segGlobstar             // "**"
+	segGlob                 // has "?" or "[", matched by doublestar
+)
+
+type segment struct {
+	kind  segmentKind
+	str   string   // the literal or the glob
+	parts []string // the literal parts between the stars
+}
+
+func compilePattern(raw string) *pattern {
+	p := &pattern{raw: raw}
+
+	if raw == WalkGitIgnore {
+		p.git = true
+		return p
+	}
+
+	glob := raw
+	if glob[0] == '!' {
+		glob = glob[1:]
+		p.negative = true
+	}
+	p.glob = glob
+	p.dot = glob == "."
+
+	// on windows the "\" is the separator, otherwise it's an escape
+	glob = filepath.ToSlash(glob)
+	if strings.ContainsAny(glob, `\{`) {
+		return p
+	}
+
+	for _, s := range strings.Split(glob, "/") {
+		p.segs = append(p.segs, compileSegment(s))
+	}
+
+	literals := []string{}
+	for _, s := range p.segs {
+		if s.kind != segLiteral {
+			break
+		}
+		literals = append(literals, s.str)
+	}
+	if len(literals) == len(p.segs) {
+		p.literal = true
+		p.prefix = strings.Join(literals, string(os.PathSeparator))
+	} else if len(literals) > 0 {
+		p.prefix = strings.Join(literals, string(os.PathSeparator)) + string(os.PathSeparator)
+	}
+
+	// the ext can't skip a glob segment, because doublestar may report the bad pattern of it
+	hasGlob := false
+	for _, s := range p.segs {
+		hasGlob = hasGlob || s.kind == segGlob
+	}
+	if last := p.segs[len(p.segs)-1]; !hasGlob && last.kind == segStar && len(last.parts) == 2 && last.parts[0] == "" {
+		p.ext = last.parts[1]
+	}
+
+	return p
+}
+
+func compileSegment(s string) segment {
+	switch {
+	case s == "**":
+		return segment{kind: segGlobstar}
+	case !strings.ContainsAny(s, "*?["):
+		return segment{kind: segLiteral, str: s}
+	case !strings.ContainsAny(s, "?["):
+		return segment{kind: segStar, parts: strings.Split(s, "*")}
+	default:
+		return segment{kind: segGlob, str: s}
+	}
+}
+
+// match the name that is relative to the dir of the Matcher, the names are the components of it
+func (p *pattern) match(name string, names []string) (bool, error) {
+	if p.segs == nil || names == nil {
+		return doublestar.PathMatch(p.glob, name)
+	}
+
+	if p.literal {
+		return name == p.prefix, nil
+	}
+
+	if !strings.HasPrefix(name, p.prefix) || !strings.HasSuffix(name, p.ext) {
+		return false, nil
+	}
+
+	return matchSegments(p.segs, names)
+}
+
+// the same as the doMatching of doublestar
+func matchSegments(segs []segment, names []string) (bool, error) {
+	s := segs[0]
+
+	if s.kind == segGlobstar {
+		if len(segs) == 1 {
+			return true, nil
+		}
+
+		for i := range names {
+			if m, err := matchSegments(segs[1:], names[i:]); m || err != nil {
+				return m, err
+			}
+		}
+		return false, nil
+	}
+
+	m, err := s.match(names[0])
+	if !m || err != nil {
+		return false, err
+	}
+
+	if len(segs) == 1 {
+		return len(names) == 1, nil
+	}
+	if len(names) == 1 {
+		return false, nil
+	}
+	return matchSegments(segs[1:], names[1:])
+}
+
+func (s segment) match(name string) (bool, error) {
+	switch s.kind {
+	case segLiteral:
+		return s.str == name, nil
+	case segStar:
+		return matchStars(s.parts, name), nil
+	default:
+		return doublestar.Match(s.str, name)
+	}
+}
+
+// the parts are split by the "*", the stars never match the separator because the name is a component
+func matchStars(parts []string, name string) bool {
+	first, last := parts[0], parts[len(parts)-1]
+
+	if len(name) < len(first)+len(last) || !strings.HasPrefix(name, first) || !strings.HasSuffix(name, last) {
+		return false
+	}
+	name = name[len(first) : len(name)-len(last)]
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return true
+}
+
+// split the name into components, it returns nil if the name has to be matched by doublestar,
+// such as a name with "\" that doublestar treats as an escape
+func splitName(name string) []string {
+	if os.PathSeparator != '\\' && strings.Contains(name, `\`) {
+		return nil
+	}
+	return strings.Split(name, string(os.PathSeparator))
+}
@@ -2,8 +2,11 @@ package os
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/bmatcuk/doublestar"
+	"github.com/stretchr/testify/assert"
 	"github.com/ysmood/kit/pkg/utils"
 )
 
@@ -16,3 +19,61 @@ func TestGetGitSubmodulesEmpty(t *testing.T) {
 		panic(l)
 	}
 }
+
+func TestCompilePattern(t *testing.T) {
+	patterns := []string{
+		"**", "*", ".", "a", "a/b", "a/*", "a/**", "**/a", "a/**/b", "**/*.go", "!**/*.go", "a/**/*.go",
+		"*.go", "a*b*c", "a**b", "**/.[^.]*", "a/?", "[ab]/c", "a/[", "[/**", "{a,b}/c", "a/{b/c,d}", `a\*b`, "",
+	}
+	names := []string{
+		"a", "b", "a/b", "a/c", "a/b/c", "a/b/c/b", "b/c", "x.go", "a/x.go", "a/b/x.go", "a/b/x.go/y",
+		"abc", "aXbYc", "aXcYb", "a/.git", ".hidden/a", "a/.", "a//b", "a/", "/a", `a\b`, "a*b", `a\*b`,
+	}
+
+	sep := string(os.PathSeparator)
+	assert.Equal(t, ".go", compilePattern(filepath.FromSlash("**/*.go")).ext)
+	assert.Equal(t, "a"+sep+"b"+sep, compilePattern(filepath.FromSlash("a/b/**")).prefix)
+	assert.True(t, compilePattern(filepath.FromSlash("a/b")).literal)
+	assert.Nil(t, compilePattern(filepath.FromSlash("{a,b}/c")).segs)
+
+	for _, pt := range patterns {
+		p := compilePattern(filepath.FromSlash("!" + pt))
+		for _, n := range names {
+			n = filepath.FromSlash(n)
+
+			expected, expectedErr := doublestar.PathMatch(filepath.FromSlash(pt), n)
+			matched, err := p.match(n, splitName(n))
+
+			assert.Equal(t, expected, matched, "pattern %q, name %q", pt, n)
+			assert.Equal(t, expectedErr, err, "pattern %q, name %q", pt, n)
+		}
+	}
+}
+
+func TestMatchCache(t *testing.T) {
+	c := newMatchCache(2)
+
+	_, gen, _ := c.get(matchKey{"a", false})
+	c.put(matchKey{"a", false}, matchResult{matched: true}, gen)
+	c.put(matchKey{"b", false}, matchResult{}, gen)
+
+	// the a is recently used, the b is evicted
+	r, _, has := c.get(matchKey{"a", false})
+	assert.True(t, has)
+	assert.True(t, r.matched)
+	c.put(matchKey{"c", false}, matchResult{}, gen)
+	_, _, has = c.get(matchKey{"b", false})
+	assert.False(t, has)
+	assert.Equal(t, 2, c.len())
+
+	// the result computed before the drop isn't stored
+	c.drop("c")
+	_, _, has = c.get(matchKey{"c", false})
+	assert.False(t, has)
+	c.put(matchKey{"c", false}, matchResult{}, gen)
+	_, _, has = c.get(matchKey{"c", false})
+	assert.False(t, has)
+
+	c.drop("")
+	assert.Equal(t, 0, c.len())
+}
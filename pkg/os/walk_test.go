@@ -1,9 +1,17 @@
 package os_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/ysmood/kit"
@@ -24,6 +32,34 @@ func TestMatch(t *testing.T) {
 	assert.Equal(t, true, negative)
 }
 
+func TestMatchPattern(t *testing.T) {
+	m := kit.NewMatcher("/root/a", []string{"**/*.go", "b/**", "!b/*.txt"})
+
+	p, _ := filepath.Abs("/root/a/b/c.go")
+	pattern, err := m.MatchPattern(p, false)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.FromSlash("b/**"), pattern)
+
+	p, _ = filepath.Abs("/root/a/b/c.txt")
+	pattern, _ = m.MatchPattern(p, false)
+	assert.Equal(t, "", pattern)
+}
+
+func TestMatchRule(t *testing.T) {
+	m := kit.NewMatcher("/root/a", []string{"**/*.go", "!b/*.txt"})
+
+	p, _ := filepath.Abs("/root/a/b/c.txt")
+	matched, rule, err := m.MatchRule(p, false)
+	assert.Nil(t, err)
+	assert.False(t, matched)
+	assert.Equal(t, filepath.FromSlash("!b/*.txt"), rule)
+
+	p, _ = filepath.Abs("/root/a/c.md")
+	matched, rule, _ = m.MatchRule(p, false)
+	assert.False(t, matched)
+	assert.Equal(t, "", rule)
+}
+
 func TestWalk(t *testing.T) {
 	list := kit.Walk(".//*").Dir("fixtures/路 径 [").MustList()
 
@@ -92,6 +128,281 @@ func TestWalkOptions(t *testing.T) {
 	assert.True(t, len(l) > 0)
 }
 
+func TestWalkFollowSymbolicLinks(t *testing.T) {
+	root, _ := filepath.Abs("tmp/" + kit.RandString(10))
+	p := filepath.Join(root, "p")
+	kit.E(kit.OutputFile(filepath.Join(root, "ext", "f"), "", nil))
+	kit.E(kit.OutputFile(filepath.Join(p, "a"), "", nil))
+	if err := os.Symlink(filepath.Join(root, "ext"), filepath.Join(p, "link")); err != nil {
+		t.Skip("symlink not supported", err)
+	}
+	kit.E(os.Symlink(p, filepath.Join(p, "loop")))
+
+	list := kit.Walk("**").Dir(p).Sort().MustList()
+	assert.Equal(t, []string{filepath.Join(p, "a"), filepath.Join(p, "link"), filepath.Join(p, "loop")}, list)
+
+	// the loop is skipped
+	list = kit.Walk("**").Dir(p).Sort().FollowSymbolicLinks().MustList()
+	assert.Equal(t, []string{filepath.Join(p, "a"), filepath.Join(p, "link"), filepath.Join(p, "link", "f")}, list)
+}
+
 func TestWalkErrPattern(t *testing.T) {
 	assert.EqualError(t, kit.ErrArg(kit.Walk("[]a]").List()), "syntax error in pattern")
 }
+
+func TestWalkDuplicates(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/a", "same", nil))
+	kit.E(kit.OutputFile(p+"/b/c", "same", nil))
+	kit.E(kit.OutputFile(p+"/d", "diff", nil))
+	kit.E(kit.OutputFile(p+"/e", "other", nil))
+	kit.E(kit.OutputFile(p+"/f", "other", nil))
+
+	groups := kit.Walk(p + "/**").MustDuplicates()
+
+	abs := func(name string) string {
+		f, _ := filepath.Abs(filepath.Join(p, name))
+		return f
+	}
+
+	assert.Equal(t, [][]string{
+		{abs("a"), abs("b/c")},
+		{abs("e"), abs("f")},
+	}, groups)
+}
+
+func TestWalkDuplicatesErr(t *testing.T) {
+	_, err := kit.Walk("[]a]").Duplicates()
+	assert.Error(t, err)
+}
+
+func TestWalkOlderThanRemove(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/old", "", nil))
+	kit.E(kit.OutputFile(p+"/dir/old", "", nil))
+	kit.E(kit.OutputFile(p+"/new", "", nil))
+
+	past := time.Now().Add(-48 * time.Hour)
+	kit.E(os.Chtimes(p+"/old", past, past))
+	kit.E(os.Chtimes(p+"/dir/old", past, past))
+
+	kit.Walk(p + "/**").OlderThan(24 * time.Hour).MustRemove()
+
+	assert.False(t, kit.Exists(p+"/old"))
+	assert.False(t, kit.Exists(p+"/dir/old"))
+	assert.True(t, kit.DirExists(p+"/dir"))
+	assert.True(t, kit.Exists(p+"/new"))
+}
+
+func TestWalkLargerThan(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/a", "12", nil))
+	kit.E(kit.OutputFile(p+"/b", "123", nil))
+
+	abs, _ := filepath.Abs(p + "/b")
+	assert.Equal(t, []string{abs}, kit.Walk(p+"/**").LargerThan(2).MustList())
+}
+
+func TestWalkSameDevice(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/a/b", "", nil))
+
+	assert.Equal(t, kit.Walk(p+"/**").Sort().MustList(), kit.Walk(p+"/**").Sort().SameDevice().MustList())
+
+	mounts, _ := os.ReadFile("/proc/mounts")
+	if !strings.Contains(string(mounts), " /dev/shm ") {
+		t.Skip("requires /dev/shm to be a mount point")
+	}
+	list := kit.Walk("**").Dir("/dev").SameDevice().MustList()
+	assert.Contains(t, list, "/dev/null")
+	assert.NotContains(t, list, "/dev/shm")
+}
+
+func TestWalkIntoArchives(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/a.txt", "", nil))
+
+	zf, _ := os.Create(p + "/app.zip")
+	zw := zip.NewWriter(zf)
+	_, _ = zw.Create("bin/")
+	w, _ := zw.Create("bin/app")
+	_, _ = w.Write([]byte("12345"))
+	_, _ = zw.Create("readme.md")
+	kit.E(zw.Close())
+	kit.E(zf.Close())
+
+	tf, _ := os.Create(p + "/app.tgz")
+	gw := gzip.NewWriter(tf)
+	tw := tar.NewWriter(gw)
+	kit.E(tw.WriteHeader(&tar.Header{Name: "./bin/app", Mode: 0755, Size: 2}))
+	_, _ = tw.Write([]byte("12"))
+	kit.E(tw.Close())
+	kit.E(gw.Close())
+	kit.E(tf.Close())
+
+	abs, _ := filepath.Abs(p)
+	entry := func(archive, name string) string {
+		return filepath.Join(abs, archive) + kit.WalkArchiveSep + filepath.FromSlash(name)
+	}
+
+	list := kit.Walk(p + "/**").Sort().IntoArchives().MustList()
+	assert.Equal(t, []string{
+		filepath.Join(abs, "a.txt"),
+		filepath.Join(abs, "app.tgz"),
+		entry("app.tgz", "bin/app"),
+		filepath.Join(abs, "app.zip"),
+		entry("app.zip", "bin/app"),
+		entry("app.zip", "readme.md"),
+	}, list)
+
+	list = kit.Walk(p + "/*.zip!/bin/*").IntoArchives().MustList()
+	assert.Equal(t, []string{entry("app.zip", "bin/app")}, list)
+
+	list = kit.Walk(p + "/*!/**").IntoArchives().LargerThan(3).MustList()
+	assert.Equal(t, []string{entry("app.zip", "bin/app")}, list)
+
+	assert.Len(t, kit.Walk(p+"/**").MustList(), 3)
+}
+
+func TestWalkIntoBrokenArchives(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/bad.zip", "not a zip", nil))
+	kit.E(kit.OutputFile(p+"/bad.tgz", "not a tgz", nil))
+	kit.E(kit.OutputFile(p+"/z.txt", "", nil))
+
+	abs, _ := filepath.Abs(p)
+
+	list := kit.Walk(p + "/**").Sort().IntoArchives().MustList()
+	assert.Equal(t, []string{
+		filepath.Join(abs, "bad.tgz"),
+		filepath.Join(abs, "bad.zip"),
+		filepath.Join(abs, "z.txt"),
+	}, list)
+}
+
+func TestMatcherIgnoreFile(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/.ignore", "b\n", nil))
+	kit.E(kit.OutputFile(p+"/a", "", nil))
+	kit.E(kit.OutputFile(p+"/b", "", nil))
+	kit.E(kit.OutputFile(p+"/c", "", nil))
+	kit.E(kit.OutputFile(p+"/d/b", "", nil))
+	kit.E(kit.OutputFile(p+"/sub/.ignore", "c\n", nil))
+	kit.E(kit.OutputFile(p+"/sub/c", "", nil))
+	kit.E(kit.OutputFile(p+"/sub/e", "", nil))
+	kit.E(kit.OutputFile(p+"/subx/c", "", nil))
+
+	abs, _ := filepath.Abs(p)
+	list := kit.Walk().Sort().Matcher(kit.NewMatcher(p, []string{"**", "!**/.ignore"}).IgnoreFile(".ignore")).MustList()
+
+	rel := []string{}
+	for _, l := range list {
+		r, _ := filepath.Rel(abs, l)
+		rel = append(rel, filepath.ToSlash(r))
+	}
+	assert.Equal(t, []string{"a", "c", "d", "sub", "sub/e", "subx", "subx/c"}, rel)
+}
+
+func TestMatcherInvalidate(t *T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/sub/c", "", nil))
+
+	m := kit.NewMatcher(p, []string{"**"}).IgnoreFile(".ignore")
+	abs, _ := filepath.Abs(p)
+
+	_, neg, _ := m.Match(filepath.Join(abs, "sub"), true)
+	assert.False(t, neg)
+	_, neg, _ = m.Match(filepath.Join(abs, "sub/c"), false)
+	assert.False(t, neg)
+
+	// the ignore file created after the dir is visited
+	kit.E(kit.OutputFile(p+"/sub/.ignore", "c\n", nil))
+	m.Invalidate(p + "/sub/.ignore")
+	_, neg, _ = m.Match(filepath.Join(abs, "sub/c"), false)
+	assert.True(t, neg)
+
+	kit.E(kit.OutputFile(p+"/sub/.ignore", "", nil))
+	m.Invalidate(p + "/sub/.ignore")
+	_, neg, _ = m.Match(filepath.Join(abs, "sub/c"), false)
+	assert.False(t, neg)
+}
+
+func TestMatcherLazyIgnoreFile(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	kit.E(kit.OutputFile(p+"/sub/c", "", nil))
+	kit.E(kit.OutputFile(p+"/sub/.ignore", "c\n", nil))
+
+	m := kit.NewMatcher(p, []string{"**"}).IgnoreFile(".ignore")
+	abs, _ := filepath.Abs(p)
+
+	// matched before the dir is visited, the ignore file of it isn't loaded yet
+	_, neg, _ := m.Match(filepath.Join(abs, "sub/c"), false)
+	assert.False(t, neg)
+
+	_, _, _ = m.Match(filepath.Join(abs, "sub"), true)
+	_, neg, _ = m.Match(filepath.Join(abs, "sub/c"), false)
+	assert.True(t, neg)
+}
+
+func TestMatcherConcurrent(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	for i := 0; i < 10; i++ {
+		kit.E(kit.OutputFile(fmt.Sprintf("%s/d%d/.ignore", p, i), "*.log\n", nil))
+		kit.E(kit.OutputFile(fmt.Sprintf("%s/d%d/a.log", p, i), "", nil))
+		kit.E(kit.OutputFile(fmt.Sprintf("%s/d%d/a.txt", p, i), "", nil))
+	}
+
+	m := kit.NewMatcher(p, []string{"**", "!**/.ignore"}).IgnoreFile(".ignore")
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list := kit.Walk().Matcher(m).MustList()
+			assert.Len(t, list, 20)
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	p := "tmp/" + kit.RandString(10)
+	rules := []string{}
+	for i := 0; i < 3000; i++ {
+		rules = append(rules, fmt.Sprintf("dir%d/**/*.log", i), fmt.Sprintf("!dir%d/keep.log", i))
+	}
+	kit.E(kit.OutputFile(p+"/.ignore", strings.Join(rules, "\n"), nil))
+
+	m := kit.NewMatcher(p, []string{"**"}).IgnoreFile(".ignore")
+	abs, _ := filepath.Abs(p)
+
+	// the baseline, every path is new so the rules are checked each time
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _ = m.Match(filepath.Join(abs, fmt.Sprintf("dir%d/a/%d.log", i%100, i)), false)
+		}
+	})
+
+	// the patterns are compiled once, every path is checked against all of them
+	b.Run("patterns", func(b *testing.B) {
+		m := kit.NewMatcher(p, rules)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _, _ = m.Match(filepath.Join(abs, fmt.Sprintf("dir%d/a/%d.log", i%100, i)), false)
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		paths := []string{}
+		for i := 0; i < 100; i++ {
+			paths = append(paths, filepath.Join(abs, fmt.Sprintf("dir%d/a/b.log", i)))
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _, _ = m.Match(paths[i%len(paths)], false)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package run
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// each bit of the field represents an allowed value
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+type cronSchedule struct {
+	minute cronField
+	hour   cronField
+	dom    cronField
+	month  cronField
+	dow    cronField
+
+	// if both dom and dow are restricted, either of them matches will do,
+	// a field that starts with "*", such as "*/2", isn't restricted
+	domAny bool
+	dowAny bool
+}
+
+// parseCron parses the standard 5 fields spec "minute hour day-of-month month day-of-week",
+// each field supports "*", "a-b", "a,b", and "/step"
+func parseCron(spec string) (*cronSchedule, error) {
+	if s, has := cronMacros[strings.TrimSpace(spec)]; has {
+		spec = s
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron spec should have 5 fields: %q", spec)
+	}
+
+	s := &cronSchedule{
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+
+	list := []struct {
+		field    *cronField
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		{&s.dow, 0, 7},
+	}
+
+	for i, el := range list {
+		f, err := parseCronField(fields[i], el.min, el.max)
+		if err != nil {
+			return nil, fmt.Errorf("cron spec %q: %w", spec, err)
+		}
+		*el.field = f
+	}
+
+	// both 0 and 7 are sunday
+	if s.dow.has(7) {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (cronField, error) {
+	var f cronField
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step: %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			var err error
+			bounds := strings.SplitN(part, "-", 2)
+
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value: %q", part)
+			}
+
+			to = from
+			if len(bounds) == 2 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value: %q", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+
+		if from < min || to > max || from > to {
+			return 0, fmt.Errorf("value out of range [%d, %d]: %q", min, max, part)
+		}
+
+		for v := from; v <= to; v += step {
+			f |= 1 << uint(v)
+		}
+	}
+
+	return f, nil
+}
+
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom.has(t.Day())
+	dow := s.dow.has(int(t.Weekday()))
+
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first time after t that matches the schedule,
+// returns zero time if nothing matches within the next 5 years
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		loc := t.Location()
+
+		if !s.month.has(int(m)) {
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.hour.has(t.Hour()) {
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
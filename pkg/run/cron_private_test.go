@@ -0,0 +1,50 @@
+package run
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 30, 20, 0, time.UTC)
+
+	list := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2020, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2020, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"5,10 11 * * *", time.Date(2020, 1, 1, 11, 5, 0, 0, time.UTC)},
+
+		// the field starts with "*" isn't restricted, so both the dom and dow must match
+		{"0 0 */2 * 1", time.Date(2020, 1, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * */2", time.Date(2020, 2, 13, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range list {
+		s, err := parseCron(c.spec)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, s.next(now), c.spec)
+	}
+}
+
+func TestCronNeverMatch(t *testing.T) {
+	s, err := parseCron("0 0 31 2 *")
+	assert.Nil(t, err)
+	assert.True(t, s.next(time.Now()).IsZero())
+}
+
+func TestCronErr(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "a * * * *", "*/0 * * * *", "1-a * * * *", "5-1 * * * *"} {
+		_, err := parseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
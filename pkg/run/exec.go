@@ -6,8 +6,11 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
+	gos "github.com/ysmood/kit/pkg/os"
 	"github.com/ysmood/kit/pkg/utils"
+	"golang.org/x/text/encoding"
 )
 
 // ExecContext ...
@@ -16,12 +19,48 @@ type ExecContext struct {
 	cmd     *exec.Cmd
 	dir     string
 
+	timeout       time.Duration
+	timeoutCancel context.CancelFunc
+	runCtx        context.Context // the context of the cmd, the context with the deadline of the Timeout
+
 	// Prefix prefix has a special syntax, the string after "@" can specify the color
 	// of the prefix and will be removed from the output
 	prefix string
 
 	isRaw bool // Set the terminal to raw mode
 
+	onlyStdout bool
+
+	syncLines bool
+	pane      bool
+	stdout    io.Writer
+	tail      *tailWriter
+
+	splitStreams bool
+	stderr       io.Writer
+	stderrPrefix string
+
+	encoding    encoding.Encoding
+	encodingErr error
+
+	input func() io.Reader // the stdin, the os.Stdin is used if it's nil
+
+	profile     bool
+	lastProfile *ExecProfile
+
+	retries      int
+	retryBackoff time.Duration
+	retryMax     time.Duration
+	retryIf      func(code int) bool
+
+	signals []os.Signal // forwarded to the command
+
+	ci      bool
+	autoCI  bool
+	console io.Writer // the writer that AutoCI checks
+	ptyCols int
+	ptyRows int
+
 	args []string
 	env  []string
 }
@@ -61,9 +100,9 @@ func (ctx *ExecContext) NewEnv(env ...string) *ExecContext {
 	return ctx
 }
 
-// Dir sets the working dir to execute
+// Dir sets the working dir to execute, the leading "~" is expanded to the home dir
 func (ctx *ExecContext) Dir(dir string) *ExecContext {
-	ctx.dir = dir
+	ctx.dir = gos.ExpandHome(dir)
 	return ctx
 }
 
@@ -79,6 +118,65 @@ func (ctx *ExecContext) Raw() *ExecContext {
 	return ctx
 }
 
+// Stdout sets the writer for the output of the command, the default is utils.Stdout
+func (ctx *ExecContext) Stdout(w io.Writer) *ExecContext {
+	ctx.stdout = w
+	return ctx
+}
+
+// SplitStreams keeps the stdout and stderr of the command separate, by default they are merged.
+// The stderr isn't a terminal then, so some commands may disable the color of it.
+func (ctx *ExecContext) SplitStreams() *ExecContext {
+	ctx.splitStreams = true
+	return ctx
+}
+
+// Stderr sets the writer for the stderr of the command, it implies SplitStreams, the default is the Stdout
+func (ctx *ExecContext) Stderr(w io.Writer) *ExecContext {
+	ctx.splitStreams = true
+	ctx.stderr = w
+	return ctx
+}
+
+// StderrPrefix sets the prefix of the stderr with the same syntax as Prefix, it implies SplitStreams,
+// the default is the Prefix
+func (ctx *ExecContext) StderrPrefix(p string) *ExecContext {
+	ctx.splitStreams = true
+	ctx.stderrPrefix = p
+	return ctx
+}
+
+// Tail keeps the last n lines of the output in memory, use LastOutput to get them.
+// The lines are reset each time the command runs.
+func (ctx *ExecContext) Tail(n int) *ExecContext {
+	ctx.tail = newTailWriter(n)
+	return ctx
+}
+
+// LastOutput returns the lines kept by Tail, it's safe to call it while the command is running
+func (ctx *ExecContext) LastOutput() string {
+	if ctx.tail == nil {
+		return ""
+	}
+	return ctx.tail.String()
+}
+
+// SyncLines buffers the output and writes each line as a whole, so the lines from
+// the commands that run concurrently won't interleave. The incomplete line will be
+// held until the newline, so it's not suitable for interactive commands.
+func (ctx *ExecContext) SyncLines() *ExecContext {
+	ctx.syncLines = true
+	return ctx
+}
+
+// PaneSeparator prints a separator with the prefix as the title when the output switches
+// from another command to this one, it implies SyncLines.
+func (ctx *ExecContext) PaneSeparator() *ExecContext {
+	ctx.syncLines = true
+	ctx.pane = true
+	return ctx
+}
+
 // GetCmd gets the exec.Cmd to execute
 func (ctx *ExecContext) GetCmd() *exec.Cmd {
 	if ctx.cmd != nil {
@@ -89,11 +187,8 @@ func (ctx *ExecContext) GetCmd() *exec.Cmd {
 		return nil
 	}
 
-	if ctx.context == nil {
-		ctx.context = context.Background()
-	}
-
-	cmd := exec.CommandContext(ctx.context, LookPath(ctx.args[0]), ctx.args[1:]...)
+	cmd := exec.CommandContext(ctx.cmdContext(), LookPath(ctx.args[0]), ctx.args[1:]...)
+	killTreeOnCancel(cmd)
 
 	if ctx.cmd == nil {
 		ctx.cmd = cmd
@@ -104,6 +199,9 @@ func (ctx *ExecContext) GetCmd() *exec.Cmd {
 	if ctx.env != nil {
 		ctx.cmd.Env = ctx.env
 	}
+	if ctx.input != nil {
+		ctx.cmd.Stdin = ctx.input()
+	}
 
 	ctx.cmd.Path = cmd.Path
 	ctx.cmd.Args = cmd.Args
@@ -113,9 +211,19 @@ func (ctx *ExecContext) GetCmd() *exec.Cmd {
 
 // Do the exec.Cmd
 func (ctx *ExecContext) Do() error {
-	cmd := ctx.GetCmd()
+	if ctx.encodingErr != nil {
+		return ctx.encodingErr
+	}
+
+	return ctx.retry(func() error {
+		cmd := ctx.GetCmd()
+
+		if ctx.tail != nil {
+			ctx.tail.reset()
+		}
 
-	return run(formatPrefix(ctx.prefix), ctx.isRaw, cmd)
+		return ctx.timeoutErr(ctx.measure(func() error { return run(ctx, cmd) }))
+	})
 }
 
 // MustDo ...
@@ -123,12 +231,51 @@ func (ctx *ExecContext) MustDo() {
 	utils.E(ctx.Do())
 }
 
-// String ...
-func (ctx *ExecContext) String() (string, error) {
-	cmd := ctx.GetCmd()
+// OnlyStdout makes the Bytes, String, and Lines return the stdout only, the stderr is kept in
+// the *exec.ExitError if the command fails. By default, they return the combined stdout and stderr.
+func (ctx *ExecContext) OnlyStdout() *ExecContext {
+	ctx.onlyStdout = true
+	return ctx
+}
+
+// Bytes runs the command and returns the output, the output isn't piped to the Stdout
+func (ctx *ExecContext) Bytes() ([]byte, error) {
+	if ctx.encodingErr != nil {
+		return nil, ctx.encodingErr
+	}
+
+	var b []byte
+	err := ctx.retry(func() error {
+		cmd := ctx.GetCmd()
+
+		err := ctx.measure(func() (err error) {
+			if ctx.onlyStdout {
+				b, err = cmd.Output()
+			} else {
+				b, err = cmd.CombinedOutput()
+			}
+			return
+		})
+
+		return ctx.timeoutErr(err)
+	})
+
+	return ctx.decodeBytes(b), err
+}
 
-	b, err := cmd.CombinedOutput()
+// MustBytes panic version of Bytes
+func (ctx *ExecContext) MustBytes() []byte {
+	out, err := ctx.Bytes()
+	if err != nil {
+		utils.Err(string(out))
+		panic(err)
+	}
+	return out
+}
 
+// String runs the command and returns the output as string
+func (ctx *ExecContext) String() (string, error) {
+	b, err := ctx.Bytes()
 	return string(b), err
 }
 
@@ -142,6 +289,29 @@ func (ctx *ExecContext) MustString() string {
 	return out
 }
 
+// Lines runs the command and returns the lines of the output, the line endings are removed
+func (ctx *ExecContext) Lines() ([]string, error) {
+	out, err := ctx.String()
+
+	lines := []string{}
+	if out = strings.TrimRight(out, "\r\n"); out != "" {
+		for _, l := range strings.Split(out, "\n") {
+			lines = append(lines, strings.TrimSuffix(l, "\r"))
+		}
+	}
+	return lines, err
+}
+
+// MustLines panic version of Lines
+func (ctx *ExecContext) MustLines() []string {
+	lines, err := ctx.Lines()
+	if err != nil {
+		utils.Err(strings.Join(lines, "\n"))
+		panic(err)
+	}
+	return lines
+}
+
 func formatPrefix(prefix string) string {
 	i := strings.LastIndex(prefix, "@")
 	if i == -1 {
@@ -153,7 +323,106 @@ func formatPrefix(prefix string) string {
 	return utils.C(prefix[:i], color)
 }
 
+// pipe the output of the command to stdout
+func (ctx *ExecContext) pipeOutput(reader io.Reader) {
+	ctx.pipe(reader, ctx.stdout, ctx.prefix)
+}
+
+// pipe the stderr to its own writer if SplitStreams is set, the returned function waits for the
+// stderr to be drained, call it after the command exits
+func (ctx *ExecContext) pipeStderr(cmd *exec.Cmd) func() {
+	if !ctx.splitStreams {
+		return func() {}
+	}
+
+	w, wait := ctx.forwardStderr()
+	cmd.Stderr = w
+	return wait
+}
+
+// the writer for the stderr of a cmd, it's piped to the Stderr with the StderrPrefix
+func (ctx *ExecContext) forwardStderr() (io.Writer, func()) {
+	prefix := ctx.stderrPrefix
+	if prefix == "" {
+		prefix = ctx.prefix
+	}
+
+	out := ctx.stderr
+	if out == nil {
+		out = ctx.stdout
+	}
+
+	return ctx.forward(out, prefix)
+}
+
+// the writer is piped to the out with the prefix, the returned function closes the writer and
+// waits for the output to be drained
+func (ctx *ExecContext) forward(out io.Writer, prefix string) (io.Writer, func()) {
+	r, w := io.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx.pipe(r, out, prefix)
+	}()
+
+	return w, func() {
+		_ = w.Close()
+		<-done
+	}
+}
+
+func (ctx *ExecContext) pipe(reader io.Reader, out io.Writer, prefix string) {
+	prefix = formatPrefix(prefix)
+	reader = ctx.decodeReader(reader)
+
+	if ctx.tail != nil {
+		reader = io.TeeReader(reader, ctx.tail)
+	}
+
+	if out == nil {
+		out = utils.Stdout
+	}
+	plain := ctx.isCI()
+	if plain {
+		out = plainWriter{out}
+	}
+
+	if !ctx.syncLines && !plain {
+		// the streams of the command or the pipeline may share the writer
+		pipeWithPrefix(lockedWriter{out}, prefix, reader)
+		return
+	}
+
+	title := ""
+	if ctx.pane {
+		title = ctx.paneTitle()
+	}
+
+	w := newLineWriter(out, prefix, title)
+	w.pane = ctx // the stdout and stderr share the pane
+	_, _ = io.Copy(w, reader)
+	w.Close()
+}
+
+func (ctx *ExecContext) paneTitle() string {
+	title := ctx.prefix
+	if i := strings.LastIndex(title, "@"); i != -1 {
+		title = title[:i]
+	}
+	title = strings.Trim(title, " |:")
+
+	if title == "" {
+		title = strings.Join(ctx.args, " ")
+	}
+	return title
+}
+
 func pipeToStdoutWithPrefix(prefix string, reader io.Reader) {
+	pipeWithPrefix(utils.Stdout, prefix, reader)
+}
+
+func pipeWithPrefix(out io.Writer, prefix string, reader io.Reader) {
 	const size = 32 * 1024
 	buf := make([]byte, size)
 	prefixBuf := []byte(prefix)
@@ -174,7 +443,7 @@ func pipeToStdoutWithPrefix(prefix string, reader io.Reader) {
 			}
 			bufOutIndex += copy(bufOut[bufOutIndex:], []byte(string(r)))
 		}
-		_, _ = utils.Stdout.Write(bufOut[:bufOutIndex])
+		_, _ = out.Write(bufOut[:bufOutIndex])
 		bufOutIndex = 0
 
 		if rerr != nil {
@@ -0,0 +1,70 @@
+package run
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var argsTemplateKey = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+var argsTemplateList = regexp.MustCompile(`^\{\{\s*([A-Za-z_]\w*)\s*\}\}$`)
+
+// ArgsTemplate renders each arg with the go template. The args won't be passed to a shell,
+// so no quoting is needed, an arg always renders to one arg even if it has spaces or quotes.
+// If data is a map[string]interface{}, each key can be used as a function, such as {{path}},
+// or as a field, such as {{.path}}. The func values are only called when they are used,
+// so the expensive ones like reading a file won't slow down the other args.
+// An arg that is exactly {{key}} with a []string or func() []string value expands to
+// one arg per element, otherwise the elements are joined by spaces.
+// A missing key of a typed map renders its zero value, such as "" for map[string]string.
+// Use {{"{{"}} to render a literal "{{".
+func ArgsTemplate(tmpl []string, data interface{}) ([]string, error) {
+	funcs := template.FuncMap{}
+	lists := map[string]func() []string{}
+
+	dict, _ := data.(map[string]interface{})
+	for k, v := range dict {
+		if !argsTemplateKey.MatchString(k) {
+			continue
+		}
+
+		switch v := v.(type) {
+		case []string:
+			lists[k] = func() []string { return v }
+		case func() []string:
+			lists[k] = v
+		default:
+			if v != nil && reflect.TypeOf(v).Kind() == reflect.Func {
+				funcs[k] = v
+			} else {
+				funcs[k] = func() interface{} { return v }
+			}
+		}
+	}
+
+	for k, fn := range lists {
+		funcs[k] = func() string { return strings.Join(fn(), " ") }
+	}
+
+	out := []string{}
+	for _, arg := range tmpl {
+		if m := argsTemplateList.FindStringSubmatch(arg); m != nil && lists[m[1]] != nil {
+			out = append(out, lists[m[1]]()...)
+			continue
+		}
+
+		t, err := template.New("").Option("missingkey=zero").Funcs(funcs).Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		var b strings.Builder
+		if err := t.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		out = append(out, b.String())
+	}
+
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package run
+
+import (
+	"path/filepath"
+	"runtime"
+
+	"github.com/ysmood/kit/pkg/os"
+)
+
+// ExecBin is the same as Exec, but the name is resolved by ResolveBin first,
+// so the scripts that run the built binaries work on all platforms, such as ExecBin("dist/app", "--help").
+func ExecBin(name string, args ...string) *ExecContext {
+	return Exec(append([]string{ResolveBin(name)}, args...)...)
+}
+
+// ResolveBin returns the first existing file of the name with the platform suffixes in order:
+// the ".exe" on Windows, the os name used by godev such as "app-mac" and "app-linux", "app-{GOOS}",
+// "app-{GOOS}-{GOARCH}", and "app_{GOOS}_{GOARCH}". If none exists, the name is returned as it is,
+// so it can still be found in the PATH.
+func ResolveBin(name string) string {
+	for _, p := range binCandidates(name, runtime.GOOS, runtime.GOARCH) {
+		if os.FileExists(p) {
+			return p
+		}
+	}
+	return name
+}
+
+func binCandidates(name, goos, goarch string) []string {
+	osName := goos
+	if goos == "darwin" {
+		osName = "mac"
+	}
+
+	suffixes := []string{"", "-" + osName}
+	if osName != goos {
+		suffixes = append(suffixes, "-"+goos)
+	}
+	suffixes = append(suffixes, "-"+goos+"-"+goarch, "_"+goos+"_"+goarch)
+
+	list := []string{}
+	for _, s := range suffixes {
+		if goos == "windows" && filepath.Ext(name) == "" {
+			list = append(list, name+s+".exe")
+		}
+		list = append(list, name+s)
+	}
+	return list
+}
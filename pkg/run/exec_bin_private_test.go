@@ -0,0 +1,17 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinCandidates(t *testing.T) {
+	assert.Equal(t, []string{"app", "app-linux", "app-linux-arm64", "app_linux_arm64"}, binCandidates("app", "linux", "arm64"))
+	assert.Equal(t, []string{"app", "app-mac", "app-darwin", "app-darwin-arm64", "app_darwin_arm64"}, binCandidates("app", "darwin", "arm64"))
+	assert.Equal(t, []string{
+		"app.exe", "app", "app-windows.exe", "app-windows", "app-windows-amd64.exe", "app-windows-amd64",
+		"app_windows_amd64.exe", "app_windows_amd64",
+	}, binCandidates("app", "windows", "amd64"))
+	assert.Equal(t, "app.cmd", binCandidates("app.cmd", "windows", "amd64")[0])
+}
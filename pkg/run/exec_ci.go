@@ -0,0 +1,75 @@
+package run
+
+import (
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/ysmood/kit/pkg/utils"
+	"golang.org/x/term"
+)
+
+// CI runs the command with plain pipes instead of a pty, and disables the colors of the output,
+// the NO_COLOR env is set for the command and the color codes left in the output are removed,
+// so the CI logs are clean. The output is written line by line like SyncLines.
+func (ctx *ExecContext) CI() *ExecContext {
+	ctx.ci = true
+	return ctx
+}
+
+// AutoCI enables the CI mode when the console isn't a terminal, such as the stdout is redirected to a file or a pipe.
+// The console is the writer the output finally goes to, by default it's the writer of the Stdout.
+// Pass it when the Stdout wraps the console, such as an io.MultiWriter of the utils.Stdout and a log file.
+func (ctx *ExecContext) AutoCI(console ...io.Writer) *ExecContext {
+	ctx.autoCI = true
+	if len(console) > 0 {
+		ctx.console = console[0]
+	}
+	return ctx
+}
+
+// PTYSize sets the size of the pty instead of following the size of the terminal,
+// such as 120 cols for the commands that wrap the output by the width. It's ignored in CI mode.
+func (ctx *ExecContext) PTYSize(cols, rows int) *ExecContext {
+	ctx.ptyCols = cols
+	ctx.ptyRows = rows
+	return ctx
+}
+
+func (ctx *ExecContext) isCI() bool {
+	if ctx.ci {
+		return true
+	}
+	if !ctx.autoCI {
+		return false
+	}
+
+	out := ctx.console
+	if out == nil {
+		out = ctx.stdout
+	}
+	// the default utils.Stdout wraps the os.Stdout on Windows
+	if out == nil || out == utils.Stdout {
+		out = os.Stdout
+	}
+	f, ok := out.(*os.File)
+	return !ok || !term.IsTerminal(int(f.Fd()))
+}
+
+func ciEnv(cmd *exec.Cmd) {
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(env, "NO_COLOR=1")
+}
+
+// plainWriter removes the color codes, each write should contain whole lines
+type plainWriter struct {
+	out io.Writer
+}
+
+func (w plainWriter) Write(p []byte) (int, error) {
+	_, err := w.out.Write([]byte(utils.StripANSI(string(p))))
+	return len(p), err
+}
@@ -0,0 +1,260 @@
+// +build windows
+
+package run
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/crypto/ssh/terminal"
+	"golang.org/x/sys/windows"
+)
+
+// the ConPTY is available since Windows 10 1809
+var conptyAvailable = windows.NewLazySystemDLL("kernel32.dll").NewProc("CreatePseudoConsole").Find() == nil
+
+var rawLock = sync.Mutex{}
+
+// run the cmd in a pseudo console, so the colors and prompts work the same as the pty on unix.
+// The started is false if it fails before the command starts, then the caller can fall back to the pipes.
+func runConPTY(ctx *ExecContext, cmd *exec.Cmd) (started bool, err error) {
+	var inRead, inWrite, outRead, outWrite windows.Handle
+	if err := windows.CreatePipe(&inRead, &inWrite, nil, 0); err != nil {
+		return false, err
+	}
+	if err := windows.CreatePipe(&outRead, &outWrite, nil, 0); err != nil {
+		_ = windows.CloseHandle(inRead)
+		_ = windows.CloseHandle(inWrite)
+		return false, err
+	}
+	in := os.NewFile(uintptr(inWrite), "conpty-in")
+	out := os.NewFile(uintptr(outRead), "conpty-out")
+	defer func() { _ = in.Close() }()
+	defer func() { _ = out.Close() }()
+
+	var pc windows.Handle
+	err = windows.CreatePseudoConsole(ctx.conptySize(), inRead, outWrite, 0, &pc)
+	// the pseudo console has its own copies of the handles
+	_ = windows.CloseHandle(inRead)
+	_ = windows.CloseHandle(outWrite)
+	if err != nil {
+		return false, err
+	}
+	closeConsole := sync.Once{}
+	defer closeConsole.Do(func() { windows.ClosePseudoConsole(pc) })
+
+	p, err := startInConsole(cmd, pc)
+	if err != nil {
+		return false, err
+	}
+	cmd.Process = p
+
+	if !ctx.forwards(os.Interrupt) {
+		children.add(p.Pid)
+		defer children.remove(p.Pid)
+	}
+	defer ctx.forwardSignals(cmd)()
+
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.runCtx.Done():
+			if cmd.Cancel != nil {
+				_ = cmd.Cancel()
+			} else {
+				_ = p.Kill()
+			}
+		case <-exited:
+		}
+	}()
+
+	if ctx.ptyCols == 0 {
+		go ctx.conptyResize(pc, exited)
+	}
+
+	if ctx.isRaw {
+		rawLock.Lock()
+		defer rawLock.Unlock()
+		// the raw mode also enables the virtual terminal input for the pseudo console
+		oldState, _ := terminal.MakeRaw(int(os.Stdin.Fd()))
+		defer restoreState(oldState)
+	}
+
+	setConptyStdin(in)
+	defer setConptyStdin(nil)
+
+	output := make(chan struct{})
+	go func() {
+		defer close(output)
+		ctx.pipeOutput(out)
+	}()
+
+	state, err := p.Wait()
+	if err != nil {
+		return true, err
+	}
+	cmd.ProcessState = state
+
+	// the output is flushed and closed with the pseudo console
+	closeConsole.Do(func() { windows.ClosePseudoConsole(pc) })
+	<-output
+
+	if !state.Success() {
+		return true, &exec.ExitError{ProcessState: state}
+	}
+	return true, nil
+}
+
+func startInConsole(cmd *exec.Cmd, pc windows.Handle) (*os.Process, error) {
+	attrs, err := windows.NewProcThreadAttributeList(1)
+	if err != nil {
+		return nil, err
+	}
+	defer attrs.Delete()
+
+	// the value of the attribute is the handle itself
+	err = attrs.Update(windows.PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE, *(*unsafe.Pointer)(unsafe.Pointer(&pc)), unsafe.Sizeof(pc))
+	if err != nil {
+		return nil, err
+	}
+
+	si := &windows.StartupInfoEx{ProcThreadAttributeList: attrs.List()}
+	si.Cb = uint32(unsafe.Sizeof(*si))
+
+	app, err := windows.UTF16PtrFromString(cmd.Path)
+	if err != nil {
+		return nil, err
+	}
+	line, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(cmd.Args))
+	if err != nil {
+		return nil, err
+	}
+	var dir *uint16
+	if cmd.Dir != "" {
+		if dir, err = windows.UTF16PtrFromString(cmd.Dir); err != nil {
+			return nil, err
+		}
+	}
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	pi := &windows.ProcessInformation{}
+	err = windows.CreateProcess(app, line, nil, nil, false,
+		windows.EXTENDED_STARTUPINFO_PRESENT|windows.CREATE_UNICODE_ENVIRONMENT,
+		envBlock(env), dir, &si.StartupInfo, pi)
+	if err != nil {
+		return nil, &os.PathError{Op: "exec", Path: cmd.Path, Err: err}
+	}
+	defer func() { _ = windows.CloseHandle(pi.Thread) }()
+	// hold the handle until the process is found, so the pid can't be reused
+	defer func() { _ = windows.CloseHandle(pi.Process) }()
+
+	return os.FindProcess(int(pi.ProcessId))
+}
+
+// the utf16 env block, each "key=value" ends with a zero, the block ends with an extra zero
+func envBlock(env []string) *uint16 {
+	b := []uint16{}
+	for _, s := range env {
+		b = append(b, utf16.Encode([]rune(s))...)
+		b = append(b, 0)
+	}
+	b = append(b, 0, 0)
+	return &b[0]
+}
+
+func (ctx *ExecContext) conptySize() windows.Coord {
+	if ctx.ptyCols > 0 {
+		return windows.Coord{X: int16(ctx.ptyCols), Y: int16(ctx.ptyRows)}
+	}
+	if size, ok := consoleSize(); ok {
+		return size
+	}
+	return windows.Coord{X: 80, Y: 25}
+}
+
+func consoleSize() (windows.Coord, bool) {
+	info := windows.ConsoleScreenBufferInfo{}
+	if windows.GetConsoleScreenBufferInfo(windows.Handle(os.Stdout.Fd()), &info) != nil {
+		return windows.Coord{}, false
+	}
+	w := info.Window
+	return windows.Coord{X: w.Right - w.Left + 1, Y: w.Bottom - w.Top + 1}, true
+}
+
+// there's no SIGWINCH on Windows, so the size of the console is polled
+func (ctx *ExecContext) conptyResize(pc windows.Handle, exited chan struct{}) {
+	last, _ := consoleSize()
+
+	t := time.NewTicker(300 * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exited:
+			return
+		case <-t.C:
+			if size, ok := consoleSize(); ok && size != last {
+				last = size
+				_ = windows.ResizePseudoConsole(pc, size)
+			}
+		}
+	}
+}
+
+func restoreState(oldState *terminal.State) {
+	if oldState != nil {
+		_ = terminal.Restore(int(os.Stdin.Fd()), oldState)
+	}
+}
+
+// the stdin is read by one goroutine and written to the pseudo console of the running command,
+// so the input won't be consumed by the reader of a command that has exited
+var conptyStdin = struct {
+	sync.Mutex
+	w       io.Writer
+	started bool
+}{}
+
+func setConptyStdin(w io.Writer) {
+	conptyStdin.Lock()
+	defer conptyStdin.Unlock()
+
+	conptyStdin.w = w
+	if w != nil && !conptyStdin.started {
+		conptyStdin.started = true
+		go pipeConptyStdin()
+	}
+}
+
+func pipeConptyStdin() {
+	buf := make([]byte, 1024)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			recordKeystroke()
+
+			conptyStdin.Lock()
+			w := conptyStdin.w
+			conptyStdin.Unlock()
+
+			if w != nil {
+				_, _ = w.Write(buf[:n])
+			}
+		}
+		if err != nil {
+			conptyStdin.Lock()
+			conptyStdin.started = false
+			conptyStdin.Unlock()
+			return
+		}
+	}
+}
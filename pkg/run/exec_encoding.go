@@ -0,0 +1,49 @@
+package run
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/transform"
+)
+
+// OutputEncoding sets the encoding of the output of the command, such as "gbk", "shift_jis", or "cp1252",
+// the output is converted to UTF-8 before it's prefixed or returned. The name is a label of the WHATWG
+// encoding standard or an IANA name, the command fails to run if it's unknown. On Windows the command
+// runs with the pipes instead of the pseudo console, because the pseudo console always outputs UTF-8.
+func (ctx *ExecContext) OutputEncoding(name string) *ExecContext {
+	ctx.encoding, ctx.encodingErr = lookupEncoding(name)
+	return ctx
+}
+
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	if e, err := htmlindex.Get(name); err == nil {
+		return e, nil
+	}
+	if e, err := ianaindex.IANA.Encoding(name); err == nil && e != nil {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown output encoding: %s", name)
+}
+
+// convert the reader to UTF-8 if the OutputEncoding is set
+func (ctx *ExecContext) decodeReader(r io.Reader) io.Reader {
+	if ctx.encoding == nil {
+		return r
+	}
+	return transform.NewReader(r, ctx.encoding.NewDecoder())
+}
+
+// convert the b to UTF-8 if the OutputEncoding is set, the b is returned as it is if it fails
+func (ctx *ExecContext) decodeBytes(b []byte) []byte {
+	if ctx.encoding == nil {
+		return b
+	}
+	if out, err := ctx.encoding.NewDecoder().Bytes(b); err == nil {
+		return out
+	}
+	return b
+}
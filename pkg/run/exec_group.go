@@ -0,0 +1,195 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// ExecError the error of a command in the group, with the context of which command fails
+type ExecError struct {
+	Index int
+	Args  []string
+	Err   error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("[%d] %s: %v", e.Index, strings.Join(e.Args, " "), e.Err)
+}
+
+// Unwrap ...
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// ExecErrors aggregated errors of the group
+type ExecErrors []*ExecError
+
+func (e ExecErrors) Error() string {
+	lines := []string{fmt.Sprintf("%d command(s) failed:", len(e))}
+	for _, err := range e {
+		lines = append(lines, "  "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap ...
+func (e ExecErrors) Unwrap() []error {
+	list := []error{}
+	for _, err := range e {
+		list = append(list, err)
+	}
+	return list
+}
+
+// ExecGroupContext ...
+type ExecGroupContext struct {
+	list     []*ExecContext
+	parallel bool
+	failFast bool
+}
+
+// ExecAll runs the commands concurrently, by default all of them will run to the end even if some fail
+func ExecAll(list ...*ExecContext) *ExecGroupContext {
+	return &ExecGroupContext{
+		list:     list,
+		parallel: true,
+	}
+}
+
+// ExecSeq runs the commands one by one, by default all of them will run even if some fail
+func ExecSeq(list ...*ExecContext) *ExecGroupContext {
+	return &ExecGroupContext{
+		list: list,
+	}
+}
+
+// FailFast stops the rest commands once one of them fails, the running ones will be killed
+func (ctx *ExecGroupContext) FailFast() *ExecGroupContext {
+	ctx.failFast = true
+	return ctx
+}
+
+// Do returns ExecErrors if any of the commands fails
+func (ctx *ExecGroupContext) Do() error {
+	var errs ExecErrors
+
+	if ctx.parallel {
+		errs = ctx.doAll()
+	} else {
+		errs = ctx.doSeq()
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// MustDo ...
+func (ctx *ExecGroupContext) MustDo() {
+	utils.E(ctx.Do())
+}
+
+func (ctx *ExecGroupContext) doSeq() ExecErrors {
+	errs := ExecErrors{}
+	for i, c := range ctx.list {
+		err := c.Do()
+		if err == nil {
+			continue
+		}
+
+		errs = append(errs, &ExecError{i, c.args, err})
+
+		if ctx.failFast {
+			break
+		}
+	}
+	return errs
+}
+
+func (ctx *ExecGroupContext) doAll() ExecErrors {
+	stop, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lock := sync.Mutex{}
+	errs := ExecErrors{}
+
+	fns := []func(){}
+	for i, c := range ctx.list {
+		i, c := i, c
+
+		// the fail-fast kills the running ones via their contexts
+		restore := func() bool { return false }
+		if ctx.failFast {
+			restore = bindStop(c, stop)
+		}
+
+		fns = append(fns, func() {
+			err := c.Do()
+			canceled := restore()
+			if err == nil {
+				return
+			}
+
+			// only skip the errors caused by the fail-fast kill
+			if canceled && killedByStop(err) {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			errs = append(errs, &ExecError{i, c.args, err})
+
+			if ctx.failFast {
+				cancel()
+			}
+		})
+	}
+
+	utils.All(fns...)()
+
+	sort.Slice(errs, func(a, b int) bool { return errs[a].Index < errs[b].Index })
+
+	return errs
+}
+
+// the command is killed or not started because of the canceled context
+func killedByStop(err error) bool {
+	return stopped(err) || errors.Is(err, context.Canceled)
+}
+
+// bind c to a sub context that is canceled when the stop is done, the cached cmd is dropped so that
+// it will be rebuilt with the sub context. The restore releases the sub context and sets the context of c back,
+// so the ExecContext of the caller isn't changed, it reports whether the sub context was canceled by the stop.
+// The rebuilt cmd is kept if it ran, so the caller can still get the exit code of it via the GetCmd,
+// otherwise the original cmd is restored.
+func bindStop(c *ExecContext, stop context.Context) (restore func() (canceled bool)) {
+	parent := c.context
+	cmd := c.cmd
+	base := parent
+	if base == nil {
+		base = context.Background()
+	}
+
+	sub, cancel := context.WithCancel(base)
+	release := context.AfterFunc(stop, cancel)
+	c.context = sub
+	c.cmd = nil
+
+	return func() bool {
+		canceled := !release()
+		cancel()
+		c.context = parent
+		if c.cmd == nil || c.cmd.ProcessState == nil {
+			c.cmd = cmd
+		}
+		return canceled
+	}
+}
@@ -0,0 +1,79 @@
+package run
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecAllFailFastContext(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, b := Exec("go", "version").Context(c), Exec("exitexit")
+
+	assert.Error(t, ExecAll(a, b).FailFast().Do())
+
+	// the contexts of the caller are kept
+	assert.Equal(t, c, a.context)
+	assert.Nil(t, b.context)
+}
+
+func TestExecAllFailFastBuiltCmd(t *testing.T) {
+	a, b := Exec("go", "run", "./fixtures/sleep"), Exec("exitexit")
+	built := a.GetCmd()
+
+	start := time.Now()
+	errs := ExecAll(a, b).FailFast().Do().(ExecErrors)
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 1, errs[0].Index)
+
+	// the cmd that ran is kept, the built one is restored if the fail-fast stops it before it starts
+	assert.True(t, a.cmd == built || a.cmd.ProcessState != nil)
+}
+
+func TestExecAllFailFastKeepCmd(t *testing.T) {
+	a, b := Exec("go", "version"), Exec("go", "env", "-unknown-flag")
+
+	assert.Error(t, ExecSeq(a, b).Do())
+	assert.Equal(t, 0, a.GetCmd().ProcessState.ExitCode())
+	assert.NotEqual(t, 0, b.GetCmd().ProcessState.ExitCode())
+
+	b = Exec("go", "env", "-unknown-flag")
+
+	assert.Error(t, ExecAll(Exec("go", "version"), b).FailFast().Do())
+	assert.NotEqual(t, 0, b.GetCmd().ProcessState.ExitCode())
+
+	// the cmd that didn't run is restored
+	c := Exec("go", "version")
+	built := c.GetCmd()
+	stop, cancel := context.WithCancel(context.Background())
+	bindStop(c, stop)()
+	cancel()
+	assert.Equal(t, built, c.cmd)
+}
+
+func TestExecAllFailFastKeepFailures(t *testing.T) {
+	stop, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := Exec("exitexit")
+	restore := bindStop(c, stop)
+	err := c.Do()
+
+	// the failure races with the cancel, it isn't caused by the kill
+	assert.True(t, restore())
+	assert.Error(t, err)
+	assert.False(t, killedByStop(err))
+
+	c = Exec("go", "run", "./fixtures/sleep")
+	restore = bindStop(c, stop)
+	err = c.Do()
+
+	assert.True(t, restore())
+	assert.True(t, killedByStop(err))
+}
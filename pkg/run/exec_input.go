@@ -0,0 +1,20 @@
+package run
+
+import (
+	"io"
+	"strings"
+)
+
+// Input feeds the r to the stdin of the command instead of the os.Stdin, such as piping a generated config
+// to "kubectl apply -f -". The command gets EOF when the r ends. The r can only be read once, so the retries
+// of Retry get an empty stdin, use InputString if they need the same input.
+func (ctx *ExecContext) Input(r io.Reader) *ExecContext {
+	ctx.input = func() io.Reader { return r }
+	return ctx
+}
+
+// InputString feeds the s to the stdin of the command, each try of Retry gets the whole s
+func (ctx *ExecContext) InputString(s string) *ExecContext {
+	ctx.input = func() io.Reader { return strings.NewReader(s) }
+	return ctx
+}
@@ -0,0 +1,169 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// ExecPipeContext ...
+type ExecPipeContext struct {
+	list    []*ExecContext
+	context context.Context
+}
+
+// ExecPipe connects the stdout of each command to the stdin of the next one, like "a | b" of the shell.
+// The stdout of the last command and the stderr of each command are piped to their own Stdout with the Prefix,
+// the Stderr and StderrPrefix of them are respected.
+func ExecPipe(list ...*ExecContext) *ExecPipeContext {
+	return &ExecPipeContext{list: list}
+}
+
+// Context sets the context of the pipeline, when it's done all the commands and their children are killed
+func (ctx *ExecPipeContext) Context(c context.Context) *ExecPipeContext {
+	ctx.context = c
+	return ctx
+}
+
+// Do returns ExecErrors of the failed commands, like the pipefail of bash. The upstream commands killed by
+// the broken pipe aren't failures, such as the "yes" of "yes | head -1".
+func (ctx *ExecPipeContext) Do() error {
+	return ctx.run(nil)
+}
+
+// MustDo ...
+func (ctx *ExecPipeContext) MustDo() {
+	utils.E(ctx.Do())
+}
+
+// Bytes runs the pipeline and returns the stdout of the last command
+func (ctx *ExecPipeContext) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := ctx.run(buf)
+	if len(ctx.list) == 0 {
+		return nil, err
+	}
+	return ctx.list[len(ctx.list)-1].decodeBytes(buf.Bytes()), err
+}
+
+// MustBytes panic version of Bytes
+func (ctx *ExecPipeContext) MustBytes() []byte {
+	return utils.E(ctx.Bytes())[0].([]byte)
+}
+
+// String runs the pipeline and returns the stdout of the last command as string
+func (ctx *ExecPipeContext) String() (string, error) {
+	b, err := ctx.Bytes()
+	return string(b), err
+}
+
+// MustString panic version of String
+func (ctx *ExecPipeContext) MustString() string {
+	return utils.E(ctx.String())[0].(string)
+}
+
+// if out is nil the stdout of the last command is piped to its Stdout
+func (ctx *ExecPipeContext) run(out io.Writer) error {
+	if len(ctx.list) == 0 {
+		return nil
+	}
+
+	if ctx.context != nil {
+		for _, c := range ctx.list {
+			defer bindStop(c, ctx.context)()
+		}
+	}
+
+	cmds := []*exec.Cmd{}
+	for i, c := range ctx.list {
+		if c.encodingErr != nil {
+			return ExecErrors{&ExecError{i, c.args, c.encodingErr}}
+		}
+		cmd := c.GetCmd()
+		if cmd == nil {
+			return ExecErrors{&ExecError{i, c.args, errors.New("empty command")}}
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	// the parent's copies of the pipes are closed after the commands start, so the readers get EOF
+	pipes := []io.Closer{}
+	defer func() {
+		for _, p := range pipes {
+			_ = p.Close()
+		}
+	}()
+
+	if cmds[0].Stdin == nil {
+		cmds[0].Stdin = os.Stdin
+	}
+	for i := 0; i < len(cmds)-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return err
+		}
+		pipes = append(pipes, r, w)
+		cmds[i].Stdout = w
+		cmds[i+1].Stdin = r
+	}
+
+	waits := []func(){}
+	defer func() {
+		for _, wait := range waits {
+			wait()
+		}
+	}()
+
+	last := ctx.list[len(ctx.list)-1]
+	if out == nil {
+		w, wait := last.forward(last.stdout, last.prefix)
+		cmds[len(cmds)-1].Stdout = w
+		waits = append(waits, wait)
+	} else {
+		cmds[len(cmds)-1].Stdout = out
+	}
+
+	for i, cmd := range cmds {
+		w, wait := ctx.list[i].forwardStderr()
+		cmd.Stderr = w
+		waits = append(waits, wait)
+	}
+
+	for i, cmd := range cmds {
+		if err := cmd.Start(); err != nil {
+			ctx.killAll(cmds[:i])
+			return ExecErrors{&ExecError{i, ctx.list[i].args, err}}
+		}
+	}
+
+	for _, p := range pipes {
+		_ = p.Close()
+	}
+	pipes = nil
+
+	errs := ExecErrors{}
+	for i, cmd := range cmds {
+		err := ctx.list[i].timeoutErr(cmd.Wait())
+		if err == nil || (i < len(cmds)-1 && isBrokenPipe(err)) {
+			continue
+		}
+		errs = append(errs, &ExecError{i, ctx.list[i].args, err})
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (ctx *ExecPipeContext) killAll(cmds []*exec.Cmd) {
+	for _, cmd := range cmds {
+		_ = KillTree(cmd.Process.Pid, os.Kill)
+		_ = cmd.Wait()
+	}
+}
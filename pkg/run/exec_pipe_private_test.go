@@ -0,0 +1,23 @@
+package run
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecPipeContext(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, b := Exec("go", "version"), Exec("go", "run", "./fixtures/upper")
+
+	out, err := ExecPipe(a, b).Context(c).String()
+	assert.NoError(t, err)
+	assert.Regexp(t, `^GO VERSION`, out)
+
+	// the contexts of the caller are kept
+	assert.Nil(t, a.context)
+	assert.Nil(t, b.context)
+}
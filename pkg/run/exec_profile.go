@@ -0,0 +1,78 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExecProfile the resource usage of the command
+type ExecProfile struct {
+	Wall time.Duration
+	User time.Duration // the user cpu time
+	Sys  time.Duration // the system cpu time
+
+	// MaxRSS is the peak resident memory in bytes, it's 0 on Windows
+	MaxRSS int64
+	// MajorFaults are the page faults that require io, it's 0 on Windows
+	MajorFaults int64
+	// MinorFaults are the page faults that don't require io, it's 0 on Windows
+	MinorFaults int64
+}
+
+// String such as "wall 1.203s, user 812ms, sys 95ms, max rss 45.2MB, page faults 0 major 1234 minor"
+func (p *ExecProfile) String() string {
+	list := []string{
+		"wall " + p.Wall.Round(time.Millisecond).String(),
+		"user " + p.User.Round(time.Millisecond).String(),
+		"sys " + p.Sys.Round(time.Millisecond).String(),
+	}
+
+	if p.MaxRSS > 0 {
+		list = append(list, fmt.Sprintf("max rss %.1fMB", float64(p.MaxRSS)/1024/1024))
+	}
+	if p.MajorFaults > 0 || p.MinorFaults > 0 {
+		list = append(list, fmt.Sprintf("page faults %d major %d minor", p.MajorFaults, p.MinorFaults))
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// Profile records the resource usage of the command, use GetProfile to get it after the command exits
+func (ctx *ExecContext) Profile() *ExecContext {
+	ctx.profile = true
+	return ctx
+}
+
+// GetProfile returns the resource usage of the last run, it's nil if Profile isn't set or the command
+// hasn't exited
+func (ctx *ExecContext) GetProfile() *ExecProfile {
+	return ctx.lastProfile
+}
+
+func (ctx *ExecContext) measure(fn func() error) error {
+	if !ctx.profile {
+		return fn()
+	}
+
+	start := time.Now()
+	err := fn()
+	wall := time.Since(start)
+
+	if state := ctx.cmd.ProcessState; state != nil {
+		ctx.lastProfile = newExecProfile(wall, state)
+	}
+
+	return err
+}
+
+func newExecProfile(wall time.Duration, state *os.ProcessState) *ExecProfile {
+	p := &ExecProfile{
+		Wall: wall,
+		User: state.UserTime(),
+		Sys:  state.SystemTime(),
+	}
+	p.MaxRSS, p.MajorFaults, p.MinorFaults = rusage(state)
+	return p
+}
@@ -0,0 +1,25 @@
+// +build !windows
+
+package run
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// returns the max rss in bytes, the major and minor page faults
+func rusage(state *os.ProcessState) (int64, int64, int64) {
+	u, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0, 0, 0
+	}
+
+	// the unit of Maxrss is kilobytes, except on darwin
+	rss := int64(u.Maxrss)
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		rss *= 1024
+	}
+
+	return rss, int64(u.Majflt), int64(u.Minflt)
+}
@@ -0,0 +1,10 @@
+// +build windows
+
+package run
+
+import "os"
+
+// the process is gone after Wait, so the memory counters can't be queried
+func rusage(state *os.ProcessState) (int64, int64, int64) {
+	return 0, 0, 0
+}
@@ -0,0 +1,193 @@
+package run
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+)
+
+// QuoteArgs joins the args into a command line that SplitCommand can split back, the rules of
+// the POSIX shell are used, or the rules of CommandLineToArgvW on windows
+func QuoteArgs(args []string) string {
+	if runtime.GOOS == "windows" {
+		return quoteWindows(args)
+	}
+	return shellescape.QuoteCommand(args)
+}
+
+// SplitCommand splits the command line into args without any expansion, such as the variables or globs.
+// The rules of the POSIX shell are used, or the rules of CommandLineToArgvW on windows.
+func SplitCommand(s string) ([]string, error) {
+	if runtime.GOOS == "windows" {
+		return splitWindows(s), nil
+	}
+	return splitPOSIX(s)
+}
+
+// ErrUnterminatedQuote the command has an unclosed quote or ends with a backslash
+var ErrUnterminatedQuote = errors.New("unterminated quote or escape")
+
+func splitPOSIX(s string) ([]string, error) {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+			continue
+
+		case c == '\\':
+			i++
+			if i == len(s) {
+				return nil, ErrUnterminatedQuote
+			}
+			if s[i] == '\n' { // the escaped newline is a line continuation
+				continue
+			}
+			arg.WriteByte(s[i])
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, ErrUnterminatedQuote
+			}
+			arg.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+
+		case c == '"':
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '"' {
+					closed = true
+					break
+				}
+				// only these chars can be escaped in double quotes
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				arg.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, ErrUnterminatedQuote
+			}
+
+		default:
+			arg.WriteByte(c)
+		}
+		inArg = true
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// the rules of CommandLineToArgvW, an unclosed quote ends at the end of the line
+func splitWindows(s string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inArg, quoted := false, false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case (c == ' ' || c == '\t') && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+			continue
+
+		case c == '\\':
+			n := 0
+			for i < len(s) && s[i] == '\\' {
+				n++
+				i++
+			}
+			if i < len(s) && s[i] == '"' {
+				// 2n backslashes and a quote are n backslashes and a delimiter,
+				// 2n+1 backslashes and a quote are n backslashes and a literal quote
+				arg.WriteString(strings.Repeat(`\`, n/2))
+				if n%2 == 1 {
+					arg.WriteByte('"')
+				} else {
+					quoted = !quoted
+				}
+			} else {
+				arg.WriteString(strings.Repeat(`\`, n))
+				i--
+			}
+
+		case c == '"':
+			if quoted && i+1 < len(s) && s[i+1] == '"' {
+				// a doubled quote inside quotes is a literal quote
+				arg.WriteByte('"')
+				i++
+			} else {
+				quoted = !quoted
+			}
+
+		default:
+			arg.WriteByte(c)
+		}
+		inArg = true
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+func quoteWindows(args []string) string {
+	list := []string{}
+	for _, arg := range args {
+		list = append(list, quoteWindowsArg(arg))
+	}
+	return strings.Join(list, " ")
+}
+
+// the same as syscall.EscapeArg of windows
+func quoteWindowsArg(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
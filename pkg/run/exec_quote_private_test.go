@@ -0,0 +1,30 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPOSIX(t *testing.T) {
+	args, err := splitPOSIX(` a  'b c'"d"\ e "f \"g\" \$ \x" '' a\` + "\n" + `b \` + "\n" + ` `)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b cd e", `f "g" $ \x`, "", "ab"}, args)
+
+	for _, s := range []string{`'a`, `"a`, `a\`} {
+		_, err = splitPOSIX(s)
+		assert.Equal(t, ErrUnterminatedQuote, err, s)
+	}
+}
+
+func TestSplitWindows(t *testing.T) {
+	assert.Equal(t, []string{`a\\b`, "c d", `e"f`, `g\`, `h\"i`, `j"k`, "", "l m"},
+		splitWindows(`a\\b "c d" e\"f "g\\" h\\\"i "j""k" "" "l m`))
+}
+
+func TestQuoteWindows(t *testing.T) {
+	args := []string{"a", "", "b c", `d"e`, `f\`, `g h\`, `C:\Program Files\`}
+	s := quoteWindows(args)
+	assert.Equal(t, `a "" "b c" "d\"e" f\ "g h\\" "C:\Program Files\\"`, s)
+	assert.Equal(t, args, splitWindows(s))
+}
@@ -0,0 +1,85 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// Retry reruns the failed command up to n times, it waits for the backoff before each retry.
+// It works with Do, Bytes, and the functions based on them. Each try creates a new cmd from the args,
+// and the Timeout applies to each try. The command killed by a signal or the Context won't be retried,
+// Guard turns it off for its runs, use GuardContext.RestartOnExit instead.
+func (ctx *ExecContext) Retry(n int, backoff time.Duration) *ExecContext {
+	ctx.retries = n
+	ctx.retryBackoff = backoff
+	return ctx
+}
+
+// RetryExponential makes the backoff of the Retry grow exponentially up to max
+func (ctx *ExecContext) RetryExponential(max time.Duration) *ExecContext {
+	ctx.retryMax = max
+	return ctx
+}
+
+// RetryIf sets the predicate on the exit code to decide if a failure should be retried,
+// the code is -1 if the command fails to start. By default, all the failures are retried.
+func (ctx *ExecContext) RetryIf(fn func(code int) bool) *ExecContext {
+	ctx.retryIf = fn
+	return ctx
+}
+
+// call fn with the retry policy, the error of the last try is returned
+func (ctx *ExecContext) retry(fn func() error) error {
+	if ctx.retries <= 0 {
+		return fn()
+	}
+
+	parent := ctx.context
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	wait := ctx.retryBackoff
+	for i := 0; ; i++ {
+		err := fn()
+		if err == nil || i == ctx.retries || parent.Err() != nil || stopped(err) {
+			return err
+		}
+		if ctx.retryIf != nil && !ctx.retryIf(exitCode(err)) {
+			return err
+		}
+
+		if utils.SleepContext(parent, wait) != nil {
+			return err
+		}
+		if ctx.retryMax > wait {
+			wait = utils.DefaultBackoff(wait)
+			if wait > ctx.retryMax {
+				wait = ctx.retryMax
+			}
+		}
+
+		// the cmd can only run once
+		ctx.cmd = nil
+	}
+}
+
+// the command is stopped by a signal from others, such as the KillTree, the Timeout of the try is excluded
+func stopped(err error) bool {
+	if errors.Is(err, ErrTimeout) {
+		return false
+	}
+
+	var e *exec.ExitError
+	if errors.As(err, &e) {
+		if s, ok := e.Sys().(syscall.WaitStatus); ok {
+			return s.Signaled()
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// ForwardSignals forwards the signals received by the current process to the process group of the command
+// while it's running, such as os.Interrupt, then Ctrl-C will stop the dev server instead of the current process.
+// The signals not in the list keep their default behavior. On Windows only os.Interrupt and os.Kill are supported.
+func (ctx *ExecContext) ForwardSignals(sigs ...os.Signal) *ExecContext {
+	ctx.signals = sigs
+	return ctx
+}
+
+func (ctx *ExecContext) forwards(sig os.Signal) bool {
+	for _, s := range ctx.signals {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// forward the signals to the started cmd until the returned func is called
+func (ctx *ExecContext) forwardSignals(cmd *exec.Cmd) func() {
+	if len(ctx.signals) == 0 {
+		return func() {}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, ctx.signals...)
+	done := make(chan utils.Nil)
+
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				if err := signalGroup(cmd.Process.Pid, sig); err != nil {
+					utils.Err(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
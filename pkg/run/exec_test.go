@@ -1,8 +1,13 @@
 package run_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"os"
+	"os/exec"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,6 +24,17 @@ func TestExec(t *testing.T) {
 	kit.Exec("go", "version").MustDo()
 }
 
+func TestExecBin(t *testing.T) {
+	p := "tmp/" + kit.RandString(10) + "/app"
+	bin := p + "-" + runtime.GOOS + "-" + runtime.GOARCH
+	_ = kit.OutputFile(bin, "", nil)
+
+	assert.Equal(t, bin, kit.ResolveBin(p))
+	assert.Equal(t, p+"x", kit.ResolveBin(p+"x"))
+
+	kit.ExecBin("go", "version").MustDo()
+}
+
 func TestExecCancel(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -37,6 +53,28 @@ func TestExecMustString(t *testing.T) {
 	assert.Regexp(t, "go version", kit.Exec("go", "version").MustString())
 }
 
+func TestExecOutput(t *testing.T) {
+	assert.Regexp(t, "^go version", string(kit.Exec("go", "version").MustBytes()))
+
+	lines := kit.Exec("go", "env", "GOOS", "GOARCH").MustLines()
+	assert.Equal(t, []string{runtime.GOOS, runtime.GOARCH}, lines)
+
+	// the stderr is in the error
+	out, err := kit.Exec("go", "env", "-unknown-flag").OnlyStdout().String()
+	assert.Equal(t, "", out)
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.NotEmpty(t, exitErr.Stderr)
+
+	out, err = kit.Exec("go", "env", "-unknown-flag").String()
+	assert.Error(t, err)
+	assert.Contains(t, out, "unknown-flag")
+
+	lines, err = kit.Exec("go", "env", "-unknown-flag").OnlyStdout().Lines()
+	assert.Error(t, err)
+	assert.Equal(t, []string{}, lines)
+}
+
 func TestExecMustStringErr(t *testing.T) {
 	assert.Panics(t, func() {
 		kit.Exec(kit.RandString(16)).MustString()
@@ -75,7 +113,225 @@ func TestExecKillTree(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestExecTimeout(t *testing.T) {
+	start := time.Now()
+	err := kit.Exec("go", "run", "./fixtures/sleep").Timeout(2 * time.Second).Do()
+
+	// the sleep process started by the "go run" is killed too, or the Do will wait for its output
+	assert.True(t, errors.Is(err, kit.ErrTimeout), err)
+	assert.Less(t, time.Since(start), 8*time.Second)
+
+	c, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = kit.Exec("go", "run", "./fixtures/sleep").Context(c).String()
+	assert.True(t, errors.Is(err, kit.ErrTimeout), err)
+
+	assert.Nil(t, kit.Exec("go", "version").Timeout(time.Minute).Do())
+}
+
 func TestOverrideGoBin(t *testing.T) {
 	err := kit.Exec("go", "version").NewEnv("GOBIN=test").Do()
 	assert.Nil(t, err)
 }
+
+func TestExecAll(t *testing.T) {
+	err := kit.ExecAll(
+		kit.Exec("go", "version"),
+		kit.Exec("exitexit"),
+		kit.Exec("go", "version"),
+		kit.Exec("exitexit2"),
+	).Do()
+
+	var errs kit.ExecErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, 1, errs[0].Index)
+	assert.Equal(t, 3, errs[1].Index)
+	assert.Regexp(t, `2 command\(s\) failed:\n  \[1\] exitexit: exec: "exitexit"`, err.Error())
+}
+
+func TestExecAllFailFast(t *testing.T) {
+	start := time.Now()
+
+	err := kit.ExecAll(
+		kit.Exec("go", "run", "./fixtures/sleep"),
+		kit.Exec("exitexit"),
+	).FailFast().Do()
+
+	assert.Len(t, err.(kit.ExecErrors), 1)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestExecAllOK(t *testing.T) {
+	kit.ExecAll(kit.Exec("go", "version"), kit.Exec("go", "version")).FailFast().MustDo()
+}
+
+func TestExecSeq(t *testing.T) {
+	err := kit.ExecSeq(kit.Exec("exitexit"), kit.Exec("go", "version"), kit.Exec("exitexit")).Do()
+	assert.Len(t, err.(kit.ExecErrors), 2)
+
+	err = kit.ExecSeq(kit.Exec("exitexit"), kit.Exec("exitexit")).FailFast().Do()
+	assert.Len(t, err.(kit.ExecErrors), 1)
+
+	var e *kit.ExecError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, []string{"exitexit"}, e.Args)
+}
+
+func TestExecSyncLines(t *testing.T) {
+	kit.ExecAll(
+		kit.Exec("go", "version").Prefix("a | ").SyncLines(),
+		kit.Exec("go", "version").Prefix("b | @green").PaneSeparator(),
+	).MustDo()
+}
+
+func TestExecStdout(t *testing.T) {
+	buf := &bytes.Buffer{}
+	kit.Exec("go", "version").Prefix("a | ").Stdout(buf).MustDo()
+	assert.Regexp(t, `a \| go version`, buf.String())
+
+	buf.Reset()
+	kit.Exec("go", "version").Prefix("b | ").SyncLines().Stdout(buf).MustDo()
+	assert.Regexp(t, `b \| go version`, buf.String())
+}
+
+func TestExecSplitStreams(t *testing.T) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	kit.Exec("go", "run", "./fixtures/streams").Prefix("o | ").StderrPrefix("e | @red").
+		Stdout(out).Stderr(errOut).MustDo()
+	assert.Equal(t, "o | out", strings.TrimSpace(kit.StripANSI(out.String())))
+	assert.Equal(t, "e | err", strings.TrimSpace(kit.StripANSI(errOut.String())))
+
+	// the same writer by default
+	out.Reset()
+	kit.Exec("go", "run", "./fixtures/streams").StderrPrefix("e | ").SyncLines().Stdout(out).MustDo()
+	// the pty may end the lines with "\r\n"
+	assert.Regexp(t, `out\r?\n`, out.String())
+	assert.Regexp(t, `e \| err\r?\n`, out.String())
+}
+
+func TestExecRetry(t *testing.T) {
+	bin := "tmp/" + kit.RandString(10) + "/flaky"
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	kit.Exec("go", "build", "-o", bin, "./fixtures/flaky").MustDo()
+
+	count := func(f string) string { return kit.E(kit.ReadString(f))[0].(string) }
+
+	f := "tmp/" + kit.RandString(10)
+	kit.Exec(bin, f, "3").Retry(2, time.Millisecond).RetryExponential(10 * time.Millisecond).MustDo()
+	assert.Equal(t, "3", count(f))
+
+	f = "tmp/" + kit.RandString(10)
+	_, err := kit.Exec(bin, f, "3").Retry(1, time.Millisecond).String()
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, 3, exitErr.ExitCode())
+	assert.Equal(t, "2", count(f))
+
+	f = "tmp/" + kit.RandString(10)
+	err = kit.Exec(bin, f, "3").Retry(2, time.Millisecond).RetryIf(func(code int) bool { return code != 3 }).Do()
+	assert.Error(t, err)
+	assert.Equal(t, "1", count(f))
+}
+
+func TestExecPipe(t *testing.T) {
+	out := kit.ExecPipe(kit.Exec("go", "version"), kit.Exec("go", "run", "./fixtures/upper")).MustString()
+	assert.Regexp(t, `^GO VERSION`, out)
+
+	buf := &lockedBuffer{}
+	kit.ExecPipe(kit.Exec("go", "version"), kit.Exec("go", "run", "./fixtures/upper").Prefix("p | ").Stdout(buf)).MustDo()
+	assert.Regexp(t, `p \| GO VERSION`, buf.String())
+
+	err := kit.ExecPipe(kit.Exec("go", "version"), kit.Exec("go", "env", "-unknown-flag").Stdout(&lockedBuffer{})).Do()
+	var errs kit.ExecErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 1, errs[0].Index)
+
+	c, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = kit.ExecPipe(kit.Exec("go", "run", "./fixtures/sleep"), kit.Exec("go", "run", "./fixtures/upper")).
+		Context(c).Do()
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestExecOutputEncoding(t *testing.T) {
+	assert.Equal(t, "你好\n", kit.Exec("go", "run", "./fixtures/gbk").OutputEncoding("gbk").MustString())
+
+	buf := &bytes.Buffer{}
+	kit.Exec("go", "run", "./fixtures/gbk").OutputEncoding("GBK").Prefix("p | ").Stdout(buf).MustDo()
+	assert.Contains(t, buf.String(), "p | 你好")
+
+	err := kit.Exec("go", "version").OutputEncoding("unknown").Do()
+	assert.EqualError(t, err, "unknown output encoding: unknown")
+}
+
+func TestExecInput(t *testing.T) {
+	out := kit.Exec("go", "run", "./fixtures/upper").InputString("abc").MustString()
+	assert.Equal(t, "ABC", out)
+
+	buf := &bytes.Buffer{}
+	kit.Exec("go", "run", "./fixtures/upper").Input(strings.NewReader("def")).Stdout(buf).MustDo()
+	assert.Contains(t, buf.String(), "DEF")
+
+	out = kit.ExecPipe(kit.Exec("go", "run", "./fixtures/upper").InputString("ghi"), kit.Exec("go", "run", "./fixtures/upper")).
+		MustString()
+	assert.Equal(t, "GHI", out)
+}
+
+func TestExecTail(t *testing.T) {
+	exe := kit.Exec("go", "version").Tail(1).Stdout(&bytes.Buffer{})
+	assert.Equal(t, "", exe.LastOutput())
+
+	exe.MustDo()
+	assert.Regexp(t, `^go version go\S+ \S+$`, exe.LastOutput())
+}
+
+func TestExecProfile(t *testing.T) {
+	exe := kit.Exec("go", "version").Profile().Stdout(&bytes.Buffer{})
+	assert.Nil(t, exe.GetProfile())
+
+	exe.MustDo()
+	p := exe.GetProfile()
+	assert.True(t, p.Wall > 0)
+	assert.Regexp(t, `^wall \S+, user \S+, sys \S+`, p.String())
+
+	exe = kit.Exec("go", "version").Profile()
+	exe.MustString()
+	assert.NotNil(t, exe.GetProfile())
+
+	p = &kit.ExecProfile{Wall: time.Second, MaxRSS: 3 * 1024 * 1024, MinorFaults: 2}
+	assert.Equal(t, "wall 1s, user 0s, sys 0s, max rss 3.0MB, page faults 0 major 2 minor", p.String())
+}
+
+func TestArgsTemplate(t *testing.T) {
+	args, err := kit.ArgsTemplate(
+		[]string{"lint", "{{files}}", "--name={{name}}", "{{.name}}", "a {{files}}", `{{"{{"}}x}}`},
+		map[string]interface{}{
+			"name":  "a b",
+			"files": func() []string { return []string{"x", "y"} },
+		},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"lint", "x", "y", "--name=a b", "a b", "a x y", "{{x}}"}, args)
+
+	args, err = kit.ArgsTemplate([]string{"{{.A}}"}, struct{ A int }{1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, args)
+
+	_, err = kit.ArgsTemplate([]string{"{{"}, nil)
+	assert.Error(t, err)
+}
+
+func TestQuoteArgs(t *testing.T) {
+	args := []string{"a", "b c", `d"e`, "f'g", ""}
+	s := kit.QuoteArgs(args)
+
+	list, err := kit.SplitCommand(s)
+	assert.Nil(t, err)
+	assert.Equal(t, args, list)
+}
@@ -0,0 +1,58 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ErrTimeout is returned when the command is killed because the deadline of the Timeout or the Context passes
+var ErrTimeout = errors.New("exec timeout")
+
+// Timeout kills the command and all its children after d, then Do returns an error that wraps ErrTimeout.
+// The deadline starts when the command is created by Do or GetCmd, it works with the Context.
+func (ctx *ExecContext) Timeout(d time.Duration) *ExecContext {
+	ctx.timeout = d
+	return ctx
+}
+
+// the context of the cmd, it has the deadline of the Timeout. The context of the caller is kept,
+// so the next cmd gets a new deadline.
+func (ctx *ExecContext) cmdContext() context.Context {
+	ctx.runCtx = ctx.context
+	if ctx.runCtx == nil {
+		ctx.runCtx = context.Background()
+	}
+	if ctx.timeout > 0 {
+		ctx.runCtx, ctx.timeoutCancel = context.WithTimeout(ctx.runCtx, ctx.timeout)
+	}
+	return ctx.runCtx
+}
+
+// kill the whole tree when the context is done, the children may hold the output pipes.
+// A process that has already exited isn't an error, so the result of the command is kept.
+func killTreeOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		err := KillTree(cmd.Process.Pid, os.Kill)
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+}
+
+// wrap the err with ErrTimeout if the deadline passed, and release the timer of the Timeout
+func (ctx *ExecContext) timeoutErr(err error) error {
+	if ctx.timeoutCancel != nil {
+		defer ctx.timeoutCancel()
+	}
+
+	if err != nil && ctx.runCtx != nil && ctx.runCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	return err
+}
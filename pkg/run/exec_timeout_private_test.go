@@ -0,0 +1,24 @@
+package run
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecTimeoutKeepContext(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx := Exec("go", "version").Context(c).Timeout(time.Minute)
+	assert.Nil(t, ctx.Do())
+
+	// the deadline of the Timeout is released, but the context of the caller is kept
+	assert.Equal(t, c, ctx.context)
+	assert.Nil(t, c.Err())
+
+	ctx.cmd = nil
+	assert.Nil(t, ctx.Do())
+}
@@ -0,0 +1,33 @@
+package run
+
+// the descendants of the pid, the parents come before their children. The tree is listed before
+// signaling, because the children of a killed process are adopted by init and can't be found anymore.
+func descendants(pid int) ([]int, error) {
+	parents, err := listParents()
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int][]int{}
+	for p, parent := range parents {
+		children[parent] = append(children[parent], p)
+	}
+
+	list := []int{}
+	seen := map[int]bool{pid: true}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		for _, c := range children[p] {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			list = append(list, c)
+			queue = append(queue, c)
+		}
+	}
+	return list, nil
+}
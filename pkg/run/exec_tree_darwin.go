@@ -0,0 +1,17 @@
+package run
+
+import "golang.org/x/sys/unix"
+
+// the parent of each process, read from the sysctl kern.proc.all
+func listParents() (map[int]int, error) {
+	list, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[int]int{}
+	for _, p := range list {
+		parents[int(p.Proc.P_pid)] = int(p.Eproc.Ppid)
+	}
+	return parents, nil
+}
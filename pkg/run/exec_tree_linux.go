@@ -0,0 +1,39 @@
+package run
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// the parent of each process, read from the /proc/<pid>/stat
+func listParents() (map[int]int, error) {
+	dirs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[int]int{}
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile("/proc/" + d.Name() + "/stat")
+		if err != nil {
+			continue // the process has exited
+		}
+
+		// the format is "pid (comm) state ppid ...", the comm may contain spaces and parentheses
+		s := string(b)
+		fields := strings.Fields(s[strings.LastIndexByte(s, ')')+1:])
+		if len(fields) < 2 {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			parents[pid] = ppid
+		}
+	}
+	return parents, nil
+}
@@ -0,0 +1,33 @@
+// +build !linux,!darwin,!windows
+
+package run
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// the parent of each process, read from the ps
+func listParents() (map[int]int, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[int]int{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			parents[pid] = ppid
+		}
+	}
+	return parents, nil
+}
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// the parent of each process, read from the Toolhelp snapshot. Windows reuses the pid of an exited
+// parent, so a process that is older than its parent isn't its child.
+func listParents() (map[int]int, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = windows.CloseHandle(snapshot) }()
+
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	err = windows.Process32First(snapshot, &entry)
+
+	parents := map[int]int{}
+	for err == nil {
+		parents[int(entry.ProcessID)] = int(entry.ParentProcessID)
+		err = windows.Process32Next(snapshot, &entry)
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return nil, err
+	}
+
+	created := map[int]int64{}
+	for pid := range parents {
+		created[pid] = creationTime(pid)
+	}
+	for pid, parent := range parents {
+		if t, has := created[parent]; has && t > 0 && created[pid] > 0 && created[pid] < t {
+			delete(parents, pid)
+		}
+	}
+	return parents, nil
+}
+
+// returns 0 if the process can't be opened
+func creationTime(pid int) int64 {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = windows.CloseHandle(h) }()
+
+	var created, exited, kernel, user windows.Filetime
+	if windows.GetProcessTimes(h, &created, &exited, &kernel, &user) != nil {
+		return 0
+	}
+	return created.Nanoseconds()
+}
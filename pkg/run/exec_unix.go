@@ -3,6 +3,8 @@
 package run
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
@@ -17,12 +19,35 @@ import (
 
 var rawLock = sync.Mutex{}
 
-func run(prefix string, isRaw bool, cmd *exec.Cmd) error {
-	p, err := pty.Start(cmd)
+func run(ctx *ExecContext, cmd *exec.Cmd) error {
+	if ctx.isCI() {
+		return runPlain(ctx, cmd)
+	}
+
+	var size *pty.Winsize
+	if ctx.ptyCols > 0 {
+		size = &pty.Winsize{Cols: uint16(ctx.ptyCols), Rows: uint16(ctx.ptyRows)}
+	}
+
+	// the pty only takes the stdin and stderr that aren't set
+	defer ctx.pipeStderr(cmd)()
+	fed := cmd.Stdin != nil // the Input is set
+	if fed {
+		// the controlling terminal is the stdout, the fd 0 isn't a tty
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Ctty = 1
+	}
+
+	// the fixed size is set before the command starts, so it won't read the default size
+	p, err := pty.StartWithSize(cmd, size)
 	if err != nil {
 		return err
 	}
 
+	defer ctx.forwardSignals(cmd)()
+
 	// Make sure to close the pty at the end.
 	defer func() { utils.E(p.Close()) }() // Best effort.
 
@@ -36,12 +61,16 @@ func run(prefix string, isRaw bool, cmd *exec.Cmd) error {
 			if _, ok := <-ch; !ok {
 				return
 			}
-			_ = pty.InheritSize(os.Stdin, p)
+			if size != nil {
+				_ = pty.Setsize(p, size)
+			} else {
+				_ = pty.InheritSize(os.Stdin, p)
+			}
 		}
 	}()
 	ch <- syscall.SIGWINCH // Initial resize.
 
-	if isRaw {
+	if ctx.isRaw && !fed {
 		rawLock.Lock()
 		defer rawLock.Unlock()
 		// Set stdin in raw mode.
@@ -50,17 +79,53 @@ func run(prefix string, isRaw bool, cmd *exec.Cmd) error {
 		defer restoreState(oldState)
 	}
 
-	stdinWriter = p
-	if !stdinPiperRunning {
-		go stdinPiper()
+	if !fed {
+		stdinWriter = p
+		if !stdinPiperRunning {
+			go stdinPiper()
+		}
 	}
 
-	pipeToStdoutWithPrefix(prefix, p)
+	ctx.pipeOutput(p)
 
 	// because we created goroutine for stdin, we need to wait for it to finish
 	return cmd.Wait()
 }
 
+// run with plain pipes, the command still leads a new process group, so KillTree works
+func runPlain(ctx *ExecContext, cmd *exec.Cmd) error {
+	ciEnv(cmd)
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+
+	r, w := io.Pipe()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	defer ctx.pipeStderr(cmd)()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	defer ctx.forwardSignals(cmd)()
+
+	wait := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		_ = w.Close()
+		wait <- err
+	}()
+
+	ctx.pipeOutput(r)
+
+	return <-wait
+}
+
 var stdinWriter io.Writer
 var stdinPiperRunning = false
 
@@ -72,6 +137,7 @@ func stdinPiper() {
 	for {
 		nr, er := os.Stdin.Read(buf)
 		if nr > 0 {
+			recordKeystroke()
 			nw, ew := stdinWriter.Write(buf[0:nr])
 			if ew != nil || nr != nw {
 				break
@@ -90,9 +156,47 @@ func restoreState(oldState *terminal.State) {
 	}
 }
 
-// KillTree kill process and all its children process
-func KillTree(pid int) error {
-	group, _ := os.FindProcess(-1 * pid)
+// the pty makes the command the leader of a new process group
+func signalGroup(pid int, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal: %v", sig)
+	}
+	return syscall.Kill(-pid, s)
+}
+
+// KillTree kill process and all its children process, the sig is SIGTERM by default.
+// Besides the process group, every descendant is signaled, so the ones that call setpgid won't survive.
+func KillTree(pid int, sig ...os.Signal) error {
+	s := syscall.SIGTERM
+	if len(sig) > 0 {
+		var ok bool
+		if s, ok = sig[0].(syscall.Signal); !ok {
+			return fmt.Errorf("unsupported signal: %v", sig[0])
+		}
+	}
 
-	return group.Signal(syscall.SIGTERM)
+	list, _ := descendants(pid)
+
+	err := syscall.Kill(-pid, s)
+	if err != nil {
+		// the process isn't a group leader
+		err = syscall.Kill(pid, s)
+	}
+
+	for _, p := range list {
+		_ = syscall.Kill(p, s)
+	}
+
+	return err
+}
+
+// the command is killed by SIGPIPE, such as the upstream of a pipeline whose downstream exits
+func isBrokenPipe(err error) bool {
+	var e *exec.ExitError
+	if !errors.As(err, &e) {
+		return false
+	}
+	s, ok := e.Sys().(syscall.WaitStatus)
+	return ok && s.Signaled() && s.Signal() == syscall.SIGPIPE
 }
@@ -3,11 +3,20 @@
 package run
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/creack/pty"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -42,3 +51,102 @@ func TestPipeToStdoutWithPrefixReadEOF(t *testing.T) {
 func TestPipeToStdoutWithPrefixReadErr(t *testing.T) {
 	pipeToStdoutWithPrefix("", testWriter{err: errors.New("err")})
 }
+
+func TestForwardSignals(t *testing.T) {
+	ctx := Exec("sh", "-c", `trap "echo got; exit 0" USR1; echo ready; while true; do sleep 0.1; done`).
+		Stdout(io.Discard).Tail(10).ForwardSignals(syscall.SIGUSR1)
+
+	done := make(chan error)
+	go func() { done <- ctx.Do() }()
+
+	for !strings.Contains(ctx.LastOutput(), "ready") {
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	assert.Nil(t, <-done)
+	assert.Contains(t, ctx.LastOutput(), "got")
+}
+
+func TestPTYSize(t *testing.T) {
+	ctx := Exec("stty", "size").Stdout(io.Discard).Tail(1).PTYSize(100, 24)
+	assert.Nil(t, ctx.Do())
+	assert.Equal(t, "24 100", strings.TrimSpace(ctx.LastOutput()))
+}
+
+func TestCI(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := Exec("sh", "-c", `printf '\033[31mred\033[0m\n'; echo $NO_COLOR; echo err >&2`).
+		Prefix("p | @red").Stdout(buf).CI().Do()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "p | red\np | 1\np | err\n", buf.String())
+}
+
+func TestAutoCI(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.True(t, Exec().Stdout(buf).AutoCI().isCI())
+	assert.False(t, Exec().Stdout(buf).isCI())
+
+	// the console is checked instead of the wrapper of it
+	f, err := os.Open(os.DevNull)
+	assert.Nil(t, err)
+	defer func() { _ = f.Close() }()
+	assert.True(t, Exec().Stdout(io.MultiWriter(f, buf)).AutoCI(f).isCI())
+
+	ptmx, tty, err := pty.Open()
+	assert.Nil(t, err)
+	defer func() { _ = ptmx.Close(); _ = tty.Close() }()
+	assert.False(t, Exec().Stdout(io.MultiWriter(tty, buf)).AutoCI(tty).isCI())
+
+	// the writer of the caller is kept as it is
+	err = Exec("sh", "-c", `printf '\033[31mred\033[0m\n'`).Stdout(buf).Do()
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "\033[31mred")
+}
+
+func TestKillTreeEscapedGroup(t *testing.T) {
+	cmd := exec.Command(os.Args[0], "-test.run=TestKillTreeHelper")
+	cmd.Env = append(os.Environ(), "KIT_KILL_TREE_HELPER=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	out, _ := cmd.StdoutPipe()
+	assert.Nil(t, cmd.Start())
+
+	line := make([]byte, 32)
+	n, _ := out.Read(line)
+	child, err := strconv.Atoi(strings.TrimSpace(string(line[:n])))
+	assert.Nil(t, err)
+
+	list, err := descendants(cmd.Process.Pid)
+	assert.Nil(t, err)
+	assert.Contains(t, list, child)
+
+	assert.Nil(t, KillTree(cmd.Process.Pid, syscall.SIGKILL))
+	_ = cmd.Wait()
+
+	// the orphan may stay as a zombie if the init doesn't reap it
+	assert.Eventually(t, func() bool {
+		state, _ := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(child)).Output()
+		s := strings.TrimSpace(string(state))
+		return s == "" || strings.HasPrefix(s, "Z")
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+// the child of the helper leaves the process group via setpgid
+func TestKillTreeHelper(t *testing.T) {
+	if os.Getenv("KIT_KILL_TREE_HELPER") == "" {
+		return
+	}
+
+	cmd := exec.Command("sleep", "30")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	_ = cmd.Start()
+	fmt.Println(cmd.Process.Pid)
+	_ = cmd.Wait()
+}
+
+func TestExecPipeBrokenPipe(t *testing.T) {
+	out, err := ExecPipe(Exec("yes"), Exec("head", "-n", "1")).String()
+	assert.Nil(t, err)
+	assert.Equal(t, "y\n", out)
+}
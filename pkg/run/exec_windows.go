@@ -3,36 +3,217 @@
 package run
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
 )
 
-// The pty lib doesn't support Windows, so we just pipe everything
-func run(prefix string, isRaw bool, cmd *exec.Cmd) error {
-	cmd.Stdin = os.Stdin
+// how long to wait for the process to exit after CTRL_BREAK before killing it by force
+var killTimeout = 3 * time.Second
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return err
+// The command runs in a pseudo console if it's available, otherwise we just pipe everything.
+// The SplitStreams always uses the pipes, the pseudo console merges the stderr into the stdout.
+// The OutputEncoding also uses the pipes, the pseudo console converts the output to UTF-8 by itself.
+// So does the Input, the stdin of the pseudo console is the console.
+func run(ctx *ExecContext, cmd *exec.Cmd) error {
+	if conptyAvailable && !ctx.isCI() && !ctx.splitStreams && ctx.encoding == nil && cmd.Stdin == nil {
+		if started, err := runConPTY(ctx, cmd); started {
+			return err
+		}
+	}
+
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if ctx.isCI() {
+		ciEnv(cmd)
+	}
+
+	// Run the command in its own process group, so that we can send CTRL_BREAK to it.
+	// The group ignores Ctrl-C from the console, the ctrlHandler will clean it up instead.
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
 	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_NEW_PROCESS_GROUP
+	setCtrlHandlerOnce.Do(setCtrlHandler)
+
+	var output io.Reader
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
 	}
+	if ctx.splitStreams {
+		defer ctx.pipeStderr(cmd)()
+		output = stdout
+	} else {
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return err
+		}
+		output = io.MultiReader(stderr, stdout)
+	}
 
 	err = cmd.Start()
 	if err != nil {
 		return err
 	}
 
-	pipeToStdoutWithPrefix(prefix, io.MultiReader(stderr, stdout))
+	// the forwarded commands handle Ctrl-C themselves
+	if !ctx.forwards(os.Interrupt) {
+		children.add(cmd.Process.Pid)
+		defer children.remove(cmd.Process.Pid)
+	}
+	defer ctx.forwardSignals(cmd)()
+
+	ctx.pipeOutput(output)
+
+	return cmd.Wait()
+}
+
+// the running commands, they will be killed when the console receives Ctrl-C
+var children = &childSet{pids: map[int]struct{}{}}
+
+type childSet struct {
+	lock sync.Mutex
+	pids map[int]struct{}
+}
+
+func (s *childSet) add(pid int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pids[pid] = struct{}{}
+}
+
+func (s *childSet) remove(pid int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.pids, pid)
+}
+
+func (s *childSet) killAll() {
+	s.lock.Lock()
+	pids := []int{}
+	for pid := range s.pids {
+		pids = append(pids, pid)
+	}
+	s.lock.Unlock()
+
+	wg := sync.WaitGroup{}
+	for _, pid := range pids {
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			_ = KillTree(pid)
+		}(pid)
+	}
+	wg.Wait()
+}
+
+var setCtrlHandlerOnce sync.Once
+
+// The handlers are called in the reverse order of registration, so this one runs before
+// the one of the go runtime that turns the event into os.Interrupt.
+func setCtrlHandler() {
+	proc := windows.NewLazySystemDLL("kernel32.dll").NewProc("SetConsoleCtrlHandler")
+	_, _, _ = proc.Call(syscall.NewCallback(ctrlHandler), 1)
+}
+
+func ctrlHandler(event uint32) uintptr {
+	switch event {
+	case windows.CTRL_C_EVENT, windows.CTRL_BREAK_EVENT, windows.CTRL_CLOSE_EVENT:
+		children.killAll()
+	}
+
+	// pass the event to the next handler
+	return 0
+}
+
+// os.Interrupt is sent as CTRL_BREAK, because the group ignores Ctrl-C
+func signalGroup(pid int, sig os.Signal) error {
+	switch sig {
+	case os.Interrupt:
+		return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(pid))
+	case os.Kill:
+		return KillTree(pid)
+	}
+	return fmt.Errorf("unsupported signal: %v", sig)
+}
+
+// KillTree kill process and all its children process.
+// By default, it sends CTRL_BREAK to the process group first, if the process doesn't exit in time,
+// the tree will be killed by "taskkill /T /F". The sig os.Interrupt only sends the CTRL_BREAK,
+// the os.Kill kills the tree immediately.
+func KillTree(pid int, sig ...os.Signal) error {
+	if len(sig) > 0 {
+		switch sig[0] {
+		case os.Interrupt:
+			return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(pid))
+		case os.Kill:
+			return terminateTree(pid)
+		}
+		return fmt.Errorf("unsupported signal: %v", sig[0])
+	}
+
+	if windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(pid)) == nil && waitExit(pid, killTimeout) {
+		return nil
+	}
+	return terminateTree(pid)
+}
+
+// kill the tree with taskkill, if it fails, such as the taskkill isn't available, terminate each process
+// of the tree instead
+func terminateTree(pid int) error {
+	err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+	if err == nil {
+		return nil
+	}
+	return terminateEach(pid)
+}
+
+// the tree is listed before terminating, the children of a terminated process can't be found by the pid
+func terminateEach(pid int) error {
+	list, _ := descendants(pid)
+
+	err := terminate(pid)
+	for _, p := range list {
+		_ = terminate(p)
+	}
+	return err
+}
+
+func terminate(pid int) error {
+	h, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		if err == windows.ERROR_INVALID_PARAMETER {
+			return nil // the process is gone
+		}
+		return err
+	}
+	defer func() { _ = windows.CloseHandle(h) }()
+
+	return windows.TerminateProcess(h, 1)
+}
+
+// returns true if the process exits within the timeout
+func waitExit(pid int, timeout time.Duration) bool {
+	h, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(pid))
+	if err != nil {
+		return true // the process is gone
+	}
+	defer func() { _ = windows.CloseHandle(h) }()
 
-	return nil
+	event, err := windows.WaitForSingleObject(h, uint32(timeout/time.Millisecond))
+	return err == nil && event == windows.WAIT_OBJECT_0
 }
 
-// KillTree kill process and all its children process
-func KillTree(pid int) error {
-	return exec.Command("taskkill", "/t", "/f", "/pid", strconv.Itoa(pid)).Run()
+// there's no SIGPIPE on Windows, the writer gets an error and decides how to exit
+func isBrokenPipe(err error) bool {
+	return false
 }
@@ -0,0 +1,82 @@
+// +build windows
+
+package run
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/windows"
+)
+
+func TestCtrlHandler(t *testing.T) {
+	done := make(chan error)
+	go func() {
+		done <- Exec("go", "run", "./fixtures/sleep").Do()
+	}()
+
+	for {
+		children.lock.Lock()
+		n := len(children.pids)
+		children.lock.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, uintptr(0), ctrlHandler(windows.CTRL_C_EVENT))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the child is still running")
+	}
+}
+
+func TestTerminateTree(t *testing.T) {
+	for _, kill := range []func(int) error{terminateTree, terminateEach} {
+		cmd := exec.Command("go", "run", "./fixtures/sleep")
+		assert.Nil(t, cmd.Start())
+
+		// wait for the "go run" to start the sleep
+		var list []int
+		for len(list) == 0 {
+			time.Sleep(100 * time.Millisecond)
+			list, _ = descendants(cmd.Process.Pid)
+		}
+
+		assert.Nil(t, kill(cmd.Process.Pid))
+		_ = cmd.Wait()
+
+		for _, pid := range list {
+			assert.True(t, waitExit(pid, 5*time.Second))
+		}
+	}
+}
+
+func TestWaitExit(t *testing.T) {
+	assert.True(t, waitExit(-1, time.Millisecond))
+}
+
+func TestConPTY(t *testing.T) {
+	if !conptyAvailable {
+		t.Skip("the pseudo console isn't available")
+	}
+
+	buf := &bytes.Buffer{}
+	ctx := Exec("go", "version").Stdout(buf)
+	started, err := runConPTY(ctx, ctx.GetCmd())
+	assert.True(t, started)
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "go version")
+
+	ctx = Exec("go", "env", "-unknown-flag").Stdout(&bytes.Buffer{})
+	_, err = runConPTY(ctx, ctx.GetCmd())
+	var exitErr *exec.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// it counts the runs in the file, and fails until the count reaches the number
+func main() {
+	file := os.Args[1]
+	until, _ := strconv.Atoi(os.Args[2])
+
+	b, _ := ioutil.ReadFile(file)
+	count, _ := strconv.Atoi(string(b))
+	count++
+	_ = ioutil.WriteFile(file, []byte(strconv.Itoa(count)), 0644)
+
+	if count < until {
+		os.Exit(3)
+	}
+}
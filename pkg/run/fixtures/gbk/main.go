@@ -0,0 +1,8 @@
+package main
+
+import "os"
+
+// "你好" in GBK
+func main() {
+	_, _ = os.Stdout.Write([]byte("\xc4\xe3\xba\xc3\n"))
+}
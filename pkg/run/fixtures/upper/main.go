@@ -0,0 +1,12 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	b, _ := ioutil.ReadAll(os.Stdin)
+	_, _ = os.Stdout.Write(bytes.ToUpper(b))
+}
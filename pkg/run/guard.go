@@ -1,9 +1,15 @@
 package run
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/radovskyb/watcher"
@@ -11,6 +17,9 @@ import (
 	"github.com/ysmood/kit/pkg/utils"
 )
 
+// GuardEvent is the file event that triggers a run, it's nil for the initial run and the scheduled runs
+type GuardEvent = watcher.Event
+
 // GuardContext ...
 type GuardContext struct {
 	args     []string
@@ -18,40 +27,111 @@ type GuardContext struct {
 	dir      string
 
 	clearScreen  bool
+	clearMode    utils.ClearMode
 	interval     *time.Duration // default 300ms
 	execCtx      *ExecContext
-	execCtxClone ExecContext
+	current      *ExecContext   // the copy of execCtx for the latest run
 	debounce     *time.Duration // default 300ms
 	noInitRun    bool
+	stdout       io.Writer
+	grace        time.Duration
+	stop         guardStop
+	typingIdle   time.Duration
+	restart      time.Duration
+	httpTrigger  string
+	batch        time.Duration
+	noKill       int
+	runner       func(e *GuardEvent) error
+	preSteps     []*guardPreStep
+	steps        []*ExecContext
+	routes       []*guardRoute
+	context      context.Context
+	jsonLog      bool
+	xargs        bool
+	xargsMax     int
+	maxFiles     int
+	onMaxFiles   func(err *GuardMaxFilesError) error
+	watchDirs    bool // the MaxFiles is exceeded, only the dirs are watched
+	watchedFiles int
+	liveReload   *guardLiveReload
+	dryRun       bool
+	execDelay    time.Duration
+	queue        GuardQueuePolicy
+	busy         bool // a run is in progress, only used by the Queue
+	queued       *guardQueued
+	after        []*GuardContext
+	limiter      *GuardLimiter
+	maxFailures  int
+	onDone       func(r *GuardResult)
+	onBeforeRun  func(e *GuardEvent)
+	onAfterRun   func(err error)
+	onReady      func(files []string)
+	events       chan *GuardWatchEvent
+	priority     int
+	every        time.Duration
+	cron         string
+	container    bool
+	sameDevice   bool
+	followLinks  bool
+	noGoWork     bool
+	modules      []*guardModule // the member modules of the go.work outside the dir
+
+	prefix    string
+	wait      chan utils.Nil
+	schedule  chan string
+	noKillSem chan utils.Nil
+	ticket    *limiterTicket // the ticket of the latest run
+	preCancel func()         // cancels the latest run before its command starts
+	reload    chan guardReload
+	watcher   *watcher.Watcher
+	matcher   *os.Matcher
+	pending   []watcher.Event      // the events received by unblock, only used by the watch loop
+	reloaded  map[string]time.Time // the mtime of the files newly watched by the latest reload
 
-	prefix  string
-	count   int
-	wait    chan utils.Nil
-	watcher *watcher.Watcher
-	matcher *os.Matcher
+	lock    sync.Mutex
+	stats   guardStats
+	started chan utils.Nil // closed when the first run starts
 }
 
 // Guard run and guard a command, kill and rerun it if watched files are modified.
 // Because it's based on polling, so it's cross-platform and file system.
-// The args supports go template, variables {{path}}, {{file}}, {{op}} are available.
+// The args supports go template, variables {{path}}, {{paths}}, {{file}}, {{op}}, {{runDir}} are available,
+// the {{runDir}} is a fresh temp dir for each run, it's removed after the command exits.
+// The {{dir}}, {{base}}, and {{ext}} are the parts of the {{path}}, such as "a/b", "c.go", and ".go".
+// The {{env.NAME}} is the env variable of guard, empty if not set.
+// The {{timestamp}} is the start time of the run, such as "20060102-150405".
 // The default patterns are GuardDefaultPatterns
 func Guard(args ...string) *GuardContext {
 	return &GuardContext{
-		args:   args,
-		prefix: utils.C("[guard]", "cyan"),
-		count:  0,
-		wait:   make(chan utils.Nil),
+		args:     args,
+		prefix:   utils.C("[guard]", "cyan"),
+		wait:     make(chan utils.Nil),
+		schedule: make(chan string),
+		reload:   make(chan guardReload),
+		started:  make(chan utils.Nil),
+		stats:    guardStats{changed: map[string]int{}},
 	}
 }
 
+// GuardIgnoreFile the files with the name in the watched dirs will be merged into the patterns,
+// the syntax is the same as gitignore, so the exclusions can be committed alongside the code
+const GuardIgnoreFile = ".guardignore"
+
 // GuardDefaultPatterns match all, then ignore all gitignore rules and all submodules
 func GuardDefaultPatterns() []string {
 	return []string{"**", os.WalkGitIgnore}
 }
 
-// Dir set dir
+// Dir set dir, the leading "~" is expanded to the home dir
 func (ctx *GuardContext) Dir(d string) *GuardContext {
-	ctx.dir = d
+	ctx.dir = os.ExpandHome(d)
+	return ctx
+}
+
+// Context sets the context, when it's done the guard stops watching and the running command is stopped
+// the same way as a rerun, then Do returns nil. The ExecCtx with its own context won't be stopped by it.
+func (ctx *GuardContext) Context(c context.Context) *GuardContext {
+	ctx.context = c
 	return ctx
 }
 
@@ -73,6 +153,13 @@ func (ctx *GuardContext) ClearScreen() *GuardContext {
 	return ctx
 }
 
+// ClearMode clears the screen by the mode before each run, such as pushing the previous output into the scrollback
+func (ctx *GuardContext) ClearMode(mode utils.ClearMode) *GuardContext {
+	ctx.clearScreen = true
+	ctx.clearMode = mode
+	return ctx
+}
+
 // Interval poll interval
 func (ctx *GuardContext) Interval(interval *time.Duration) *GuardContext {
 	ctx.interval = interval
@@ -85,6 +172,174 @@ func (ctx *GuardContext) Debounce(debounce *time.Duration) *GuardContext {
 	return ctx
 }
 
+// Stdout sets the writer for the logs of guard and the output of the command,
+// the writes are serialized, so w doesn't need to be goroutine-safe
+func (ctx *GuardContext) Stdout(w io.Writer) *GuardContext {
+	ctx.stdout = w
+	return ctx
+}
+
+// the logs of guard share the lock with the output of the command
+func (ctx *GuardContext) logOutput() io.Writer {
+	if ctx.stdout == nil {
+		return utils.Stdout
+	}
+	return lockedWriter{ctx.stdout}
+}
+
+// NoKill runs the command concurrently for each change without killing the previous ones,
+// at most limit commands will run at the same time, the default limit is the number of CPUs.
+// It's useful for commands that are idempotent per file, such as uploading the changed file.
+func (ctx *GuardContext) NoKill(limit int) *GuardContext {
+	if limit < 1 {
+		limit = runtime.NumCPU()
+	}
+	ctx.noKill = limit
+	return ctx
+}
+
+// StartupGrace won't kill the command within d after it starts, the events during the period
+// are queued, the command will rerun once with the latest event when the period ends.
+// It prevents a half-started server from being killed repeatedly when saving multiple files.
+func (ctx *GuardContext) StartupGrace(d time.Duration) *GuardContext {
+	ctx.grace = d
+	return ctx
+}
+
+// Batch collects the file events within the window after the first one, then runs the command once
+// with all of them. The {{paths}} placeholder renders the relative paths of the changed files,
+// an arg that is exactly {{paths}} expands to one arg per file, such as Guard("eslint", "{{paths}}").
+// Otherwise, the paths are joined by spaces. The {{path}} placeholder is the last changed file.
+func (ctx *GuardContext) Batch(window time.Duration) *GuardContext {
+	ctx.batch = window
+	return ctx
+}
+
+// TypingIdle holds the runs triggered by file events until no keystroke is piped to the command within d,
+// so the autosave of the editors won't kill the command constantly while typing in the same terminal.
+// The keystrokes are only tracked on unix, because the command reads the stdin directly on windows.
+func (ctx *GuardContext) TypingIdle(d time.Duration) *GuardContext {
+	ctx.typingIdle = d
+	return ctx
+}
+
+// RestartOnExit reruns the command after the backoff if it exits on its own, even without a file change,
+// so a crashed dev server comes back like under a supervisor. The runs killed by a newer run won't restart.
+// Use MaxFailures to stop a crash loop. It doesn't apply to NoKill and Runner.
+func (ctx *GuardContext) RestartOnExit(backoff time.Duration) *GuardContext {
+	ctx.restart = backoff
+	return ctx
+}
+
+// Runner replaces the command with the fn, so the watching and debounce can be used to rebuild in-process,
+// such as re-rendering templates. The fn can't be killed, so the runs are serialized unless NoKill is set.
+func (ctx *GuardContext) Runner(fn func(e *GuardEvent) error) *GuardContext {
+	ctx.runner = fn
+	return ctx
+}
+
+// PreStep runs args before the command when a file that matches the patterns changes, such as
+// "go mod download" when go.mod changes. The pending steps run in the order they are added,
+// a failed step fails the run and will be retried by the next run. The patterns should also be watched.
+func (ctx *GuardContext) PreStep(args []string, patterns ...string) *GuardContext {
+	ctx.preSteps = append(ctx.preSteps, &guardPreStep{args: args, patterns: patterns})
+	return ctx
+}
+
+// Limiter shares the cap of concurrent runs with other guards, the queued runs start by the priority.
+// Such as run the backend build before the frontend build when a shared file changes.
+func (ctx *GuardContext) Limiter(l *GuardLimiter, priority int) *GuardContext {
+	ctx.limiter = l
+	ctx.priority = priority
+	return ctx
+}
+
+// MaxFailures pauses the reruns after n consecutive failures, so a broken build won't crash loop.
+// The last output kept by ExecContext.Tail will be logged, call Restart or Reload to resume.
+func (ctx *GuardContext) MaxFailures(n int) *GuardContext {
+	ctx.maxFailures = n
+	return ctx
+}
+
+// OnDone calls fn after each run, the canceled runs are skipped. It's called in the goroutine of the run,
+// so a slow fn delays the next run, such as sending a notification when the run fails.
+func (ctx *GuardContext) OnDone(fn func(r *GuardResult)) *GuardContext {
+	ctx.onDone = fn
+	return ctx
+}
+
+// OnBeforeRun calls fn before each run starts, the e is nil for the initial run and the scheduled runs
+func (ctx *GuardContext) OnBeforeRun(fn func(e *GuardEvent)) *GuardContext {
+	ctx.onBeforeRun = fn
+	return ctx
+}
+
+// OnAfterRun calls fn after each run with the error of the run, the err is context.Canceled
+// if the run is canceled before the command starts
+func (ctx *GuardContext) OnAfterRun(fn func(err error)) *GuardContext {
+	ctx.onAfterRun = fn
+	return ctx
+}
+
+// OnWatchReady calls fn with the watched files when the watcher starts and each time it's reloaded
+func (ctx *GuardContext) OnWatchReady(fn func(files []string)) *GuardContext {
+	ctx.onReady = fn
+	return ctx
+}
+
+// GuardWatchEvent a change of the watched files
+type GuardWatchEvent struct {
+	Path    string // relative to the dir of the guard
+	Op      watcher.Op
+	Time    time.Time
+	Pattern string // the pattern that matches the path
+}
+
+// Events returns the changes of the watched files, it should be called before Do. The channel will be
+// closed after Stop, and the watching is blocked until the event is received.
+// Guard without args and runner only watches, such as Guard().Events() is a cross-platform watcher
+// with the glob and gitignore filtering.
+func (ctx *GuardContext) Events() <-chan *GuardWatchEvent {
+	if ctx.events == nil {
+		ctx.events = make(chan *GuardWatchEvent, 64)
+	}
+	return ctx.events
+}
+
+// Every reruns the command periodically, it works alongside the file events
+func (ctx *GuardContext) Every(d time.Duration) *GuardContext {
+	ctx.every = d
+	return ctx
+}
+
+// Cron reruns the command by the standard 5 fields cron spec, such as "0 3 * * *",
+// the macros like "@daily" are also supported. It works alongside the file events.
+func (ctx *GuardContext) Cron(spec string) *GuardContext {
+	ctx.cron = spec
+	return ctx
+}
+
+// Container detects changes by the inode and content hash besides the mtime, because
+// the mtime-only polling misses changes in some docker bind mounts.
+// It reads all the watched files on each poll, so it's opt-in, guard only logs a hint inside a container.
+func (ctx *GuardContext) Container() *GuardContext {
+	ctx.container = true
+	return ctx
+}
+
+// SameDevice won't watch the dirs on other devices, such as a mounted NAS
+func (ctx *GuardContext) SameDevice() *GuardContext {
+	ctx.sameDevice = true
+	return ctx
+}
+
+// FollowSymlinks watches the dirs that the symlinks link to, such as the node_modules or workspace links.
+// The links to the dirs that are already watched are skipped, so the loops are safe.
+func (ctx *GuardContext) FollowSymlinks() *GuardContext {
+	ctx.followLinks = true
+	return ctx
+}
+
 // ExecCtx ...
 func (ctx *GuardContext) ExecCtx(c *ExecContext) *GuardContext {
 	ctx.execCtx = c
@@ -106,82 +361,295 @@ func (ctx *GuardContext) Do() error {
 		ctx.execCtx = Exec()
 	}
 
-	ctx.watcher = watcher.New()
-	ctx.matcher = os.NewMatcher(ctx.dir, ctx.patterns)
-
-	ctx.addWatchFiles(ctx.dir)
+	if ctx.stdout != nil && ctx.execCtx.stdout == nil {
+		ctx.execCtx.Stdout(ctx.stdout)
+	}
 
-	go ctx.watch()
+	var cron *cronSchedule
+	if ctx.cron != "" {
+		var err error
+		cron, err = parseCron(ctx.cron)
+		if err != nil {
+			return err
+		}
+	}
 
-	if !ctx.noInitRun {
-		go ctx.run(nil)
+	if !ctx.container && os.InContainer() {
+		ctx.log("container detected, enable the container mode if the changes in the bind mounts are missed")
 	}
 
 	interval := ctx.interval
 	if interval == nil {
 		t := time.Millisecond * 300
+		if ctx.container {
+			t = time.Second
+		}
 		interval = &t
 	}
 
+	if ctx.context != nil && ctx.context.Err() != nil {
+		return nil
+	}
+
+	ctx.lock.Lock()
+	ctx.watcher = watcher.New()
+	ctx.lock.Unlock()
+
+	if ctx.context != nil {
+		go ctx.stopOnDone()
+	}
+
+	if ctx.noKill > 0 {
+		ctx.noKillSem = make(chan utils.Nil, ctx.noKill)
+	} else if ctx.runner != nil && ctx.queue == GuardQueueRestart {
+		ctx.noKillSem = make(chan utils.Nil, 1)
+	}
+
+	ctx.matcher = os.NewMatcher(ctx.dir, ctx.patterns).IgnoreFile(GuardIgnoreFile)
+	for _, step := range ctx.preSteps {
+		step.matcher = os.NewMatcher(ctx.dir, step.patterns)
+	}
+	ctx.initGoWork()
+	ctx.initRoutes()
+
+	files, err := ctx.addWatchFiles(ctx.dir)
+	if err != nil {
+		return err
+	}
+	moduleFiles, err := ctx.addGoWorkFiles()
+	if err != nil {
+		return err
+	}
+	files = append(files, moduleFiles...)
+
+	if ctx.httpTrigger != "" {
+		if err := ctx.serveHTTPTrigger(); err != nil {
+			return err
+		}
+	}
+
+	if ctx.liveReload != nil {
+		if err := ctx.serveLiveReload(); err != nil {
+			return err
+		}
+	}
+
+	if ctx.onReady != nil {
+		go func() {
+			ctx.watcher.Wait()
+			ctx.onReady(files)
+		}()
+	}
+
+	go ctx.watch()
+
+	if ctx.container {
+		go ctx.pollHash(*interval)
+	}
+
+	if ctx.every > 0 {
+		go ctx.tickEvery()
+	}
+
+	if cron != nil {
+		go ctx.tickCron(cron)
+	}
+
+	if !ctx.noInitRun {
+		if ctx.execDelay > 0 || len(ctx.after) > 0 {
+			go ctx.initRunLater()
+		} else {
+			ctx.rerun(nil, nil)
+		}
+	}
+
 	return ctx.watcher.Start(*interval)
 }
 
-// unescape the {{path}}, {{file}}, {{op}} placeholders
-func (ctx *GuardContext) unescapeArgs(args []string, e *watcher.Event) []string {
+// the format of the {{timestamp}}
+const guardTimestamp = "20060102-150405"
+
+// unescape the placeholders listed in the doc of Guard, the paths are the batched files
+func (ctx *GuardContext) unescapeArgs(args []string, e *watcher.Event, paths []string, runDir *guardRunDir) ([]string, error) {
 	if e == nil {
 		e = &watcher.Event{}
+	} else if paths == nil {
+		paths = []string{ctx.relPath(e.Path)}
 	}
 
-	newArgs := []string{}
-	for _, arg := range args {
-		dir, err := filepath.Abs(ctx.dir)
-		ctx.logErr(err)
-
-		p, err := filepath.Abs(e.Path)
-		ctx.logErr(err)
+	p, err := os.RelTo(ctx.dir, e.Path)
+	ctx.logErr(err)
 
-		p, err = filepath.Rel(dir, p)
-		ctx.logErr(err)
+	now := time.Now()
 
-		newArgs = append(
-			newArgs,
-			utils.S(arg,
-				"path", func() string { return p },
-				"file", func() string { f, _ := os.ReadFile(p); return string(f) },
-				"op", func() string { return e.Op.String() },
-			),
-		)
-	}
-	return newArgs
+	return ArgsTemplate(args, map[string]interface{}{
+		"path":      func() string { return p },
+		"file":      func() string { f, _ := os.ReadFile(p); return string(f) },
+		"op":        func() string { return e.Op.String() },
+		"paths":     paths,
+		"runDir":    runDir.get,
+		"dir":       func() string { return filepath.Dir(p) },
+		"base":      func() string { return filepath.Base(p) },
+		"ext":       func() string { return filepath.Ext(p) },
+		"env":       os.EnvSnapshot,
+		"timestamp": func() string { return now.Format(guardTimestamp) },
+	})
 }
 
 func (ctx *GuardContext) logErr(err error) {
 	if err != nil {
-		utils.Log(ctx.prefix, err)
+		ctx.logEvent("error", guardLogFields{"error": err.Error()}, err)
 	}
 }
 
-func (ctx *GuardContext) run(e *watcher.Event) {
+func (ctx *GuardContext) log(v ...interface{}) {
+	if ctx.jsonLog {
+		ctx.logText(v...)
+		return
+	}
+
+	v = append([]interface{}{ctx.prefix}, v...)
+	if ctx.stdout == nil {
+		utils.Log(v...)
+	} else {
+		utils.LogTo(ctx.logOutput(), v...)
+	}
+}
+
+// the c is canceled by a newer run, it only works before the command starts
+func (ctx *GuardContext) run(c context.Context, execCtx *ExecContext, e *watcher.Event, paths []string, t *limiterTicket) {
+	if t == nil {
+		ctx.exec(c, execCtx, e, paths)
+	} else if ctx.limiter.acquire(t) { // it fails if canceled by a newer run
+		ctx.exec(c, execCtx, e, paths)
+		ctx.limiter.release()
+	}
+
+	ctx.wait <- utils.Nil{}
+}
+
+// run without killing the previous ones, the number of concurrent runs is limited by noKillSem
+func (ctx *GuardContext) runNoKill(e *watcher.Event, paths []string) {
+	ctx.noKillSem <- utils.Nil{}
+	defer func() { <-ctx.noKillSem }()
+
+	if ctx.limiter != nil {
+		ctx.limiter.acquire(ctx.limiter.ticket(ctx.priority))
+		defer ctx.limiter.release()
+	}
+
+	ctx.exec(nil, ctx.newRun(), e, paths)
+}
+
+// the c is nil if the run can't be canceled
+func (ctx *GuardContext) exec(c context.Context, execCtx *ExecContext, e *watcher.Event, paths []string) {
+	var chunks [][]string
+	if ctx.xargs && ctx.runner == nil {
+		chunks = ctx.xargsChunks(e, paths)
+		if len(chunks) == 0 {
+			ctx.log("skipped, no changed file for xargs")
+			return
+		}
+	}
+
 	if ctx.clearScreen {
-		_ = utils.ClearScreen()
+		out := ctx.stdout
+		if out == nil {
+			out = utils.Stdout
+		}
+		// the size of the terminal is read from the out, so lock it directly
+		outputLock.Lock()
+		_ = utils.ClearScreenTo(out, ctx.clearMode)
+		outputLock.Unlock()
 	}
 
 	id := utils.RandString(8)
 
-	ctx.count++
-	args := ctx.unescapeArgs(ctx.args, e)
-	utils.Log(ctx.prefix, "run", id, ctx.count, utils.C(ctx.formatArgs(args), "green"))
+	runDir := &guardRunDir{}
+	defer func() { ctx.logErr(runDir.remove()) }()
+
+	if ctx.dryRun {
+		ctx.previewRun(id, e, paths, chunks, runDir)
+		return
+	}
 
-	ctx.execCtxClone = *ctx.execCtx
-	err := ctx.execCtxClone.Dir(ctx.dir).Args(args).Do()
+	if ctx.onBeforeRun != nil {
+		ctx.onBeforeRun(e)
+	}
+
+	start := time.Now()
+	n := ctx.recordStart()
+	ctx.reloadPages()
+
+	var args []string
+	var err error
+	if ctx.runner == nil {
+		args, err = ctx.unescapeArgs(ctx.args, e, paths, runDir)
+	}
+
+	if err == nil {
+		err = ctx.runPreSteps(c, id, execCtx, e, paths, runDir)
+	}
+	if err == nil && chunks != nil {
+		args, err = ctx.runXargs(c, id, execCtx, args, chunks)
+	}
+	if err == nil && ctx.runner == nil {
+		ctx.logEvent("run", guardLogFields{"id": id, "run": n, "args": args}, "run", id, n, utils.C(ctx.formatArgs(args), "green"))
+		execCtx.Dir(ctx.dir).Args(args)
+		if ctx.context != nil && execCtx.context == nil {
+			execCtx.Context(ctx.context)
+			ctx.cancelOn(execCtx.GetCmd())
+		}
+		err = execCtx.Do()
+	} else if err == nil {
+		ctx.logEvent("run", guardLogFields{"id": id, "run": n, "runner": true}, "run", id, n, utils.C("runner", "green"))
+		err = ctx.runner(e)
+	}
+	d := time.Since(start)
+	failed, flaky, paused := ctx.recordDone(n, d, err, execCtx.LastOutput())
+
+	if ctx.onAfterRun != nil {
+		ctx.onAfterRun(err)
+	}
 
+	if err == context.Canceled {
+		ctx.logEvent("canceled", guardLogFields{"id": id, "run": n}, "canceled", id)
+		return
+	}
+
+	ctx.restartLater(n)
+
+	if ctx.onDone != nil {
+		r := &GuardResult{Args: args, Err: err, Failed: failed, Flaky: flaky, Duration: d, Output: execCtx.LastOutput()}
+		if e != nil {
+			r.Path = ctx.relPath(e.Path)
+		}
+		ctx.onDone(r)
+	}
+
+	fields := guardLogFields{"id": id, "run": n, "durationMs": d.Milliseconds(), "exitCode": exitCode(err)}
 	errMsg := ""
 	if err != nil {
 		errMsg = utils.C(err, "red")
+		fields["error"] = err.Error()
+	}
+	if p := execCtx.GetProfile(); p != nil && ctx.runner == nil {
+		fields["profile"] = p
+		ctx.logEvent("done", fields, "done", id, errMsg, utils.C(p, "240"))
+	} else {
+		ctx.logEvent("done", fields, "done", id, errMsg)
 	}
-	utils.Log(ctx.prefix, "done", id, errMsg)
 
-	ctx.wait <- utils.Nil{}
+	if flaky {
+		ctx.log(utils.C("flaky, the run passed without any change after a failure", "yellow"))
+	}
+
+	if paused {
+		ctx.log(utils.C(fmt.Sprintf("paused after %d consecutive failures, restart to resume", ctx.maxFailures), "red"))
+		if out := execCtx.LastOutput(); strings.TrimSpace(out) != "" {
+			ctx.log("last output:\n" + out)
+		}
+	}
 }
 
 func (ctx *GuardContext) formatArgs(args []string) []string {
@@ -200,8 +668,18 @@ func (ctx *GuardContext) formatArgs(args []string) []string {
 	return list
 }
 
-func (ctx *GuardContext) addWatchFiles(dir string) {
-	list, _ := os.Walk().Dir(dir).Matcher(ctx.matcher).List()
+// returns the matched files, the err is from the MaxFiles
+func (ctx *GuardContext) addWatchFiles(dir string) ([]string, error) {
+	walk := os.Walk().Dir(dir).Matcher(ctx.matcherOf(dir))
+	if ctx.sameDevice {
+		walk.SameDevice()
+	}
+	if ctx.followLinks {
+		walk.FollowSymbolicLinks()
+	}
+	list, _ := walk.List()
+
+	dirsOnly, err := ctx.overMaxFiles(list)
 
 	dict := map[string]utils.Nil{}
 
@@ -217,17 +695,21 @@ func (ctx *GuardContext) addWatchFiles(dir string) {
 			dict[dir] = utils.Nil{}
 			_ = ctx.watcher.Add(dir)
 		}
-		_ = ctx.watcher.Add(p)
+		if !dirsOnly {
+			_ = ctx.watcher.Add(p)
+		}
 	}
 
 	var watched string
 	if len(list) > 10 {
-		watched = strings.Join(append(list[0:10], "..."), " ")
+		watched = strings.Join(append(list[0:10:10], "..."), " ")
 	} else {
 		watched = strings.Join(list, " ")
 	}
 
-	utils.Log(ctx.prefix, "watched", len(list), "files:", utils.C(watched, "green"))
+	ctx.log("watched", len(list), "files:", utils.C(watched, "green"))
+
+	return list, err
 }
 
 func (ctx *GuardContext) watch() {
@@ -238,52 +720,494 @@ func (ctx *GuardContext) watch() {
 		debounce = &t
 	}
 
+	var started time.Time // when the latest run is triggered
+	if !ctx.noInitRun {
+		started = time.Now()
+	}
+	var queued *watcher.Event
+	var graceEnd <-chan time.Time
+	var idleEnd <-chan time.Time
+
+	var batch []string // the files changed since the latest run in the Batch mode
+	var batchLast watcher.Event
+	var batchEnd <-chan time.Time
+
+	rerun := func(e *watcher.Event) {
+		if ctx.isPaused() {
+			return
+		}
+		started = time.Now()
+		queued = nil
+		graceEnd = nil
+		idleEnd = nil
+		paths := batch
+		batch = nil
+		ctx.rerun(e, paths)
+	}
+
+	// hold the run until the typing stops
+	trigger := func(e *watcher.Event) {
+		if wait := typingWait(ctx.typingIdle); wait > 0 {
+			if idleEnd == nil {
+				ctx.log("queued, waiting for the typing to stop")
+			}
+			queued = e
+			idleEnd = time.After(wait)
+			return
+		}
+		rerun(e)
+	}
+
+	fire := func(e *watcher.Event) {
+		if ctx.grace > 0 && time.Since(started) < ctx.grace {
+			if queued == nil {
+				graceEnd = time.After(ctx.grace - time.Since(started))
+			}
+			queued = e
+			ctx.log("queued, the command is still starting up")
+			return
+		}
+
+		if ctx.isPaused() {
+			ctx.log("paused, restart to resume")
+		}
+
+		trigger(e)
+	}
+
+	handle := func(e watcher.Event) {
+		if ctx.reloadNoise(e) {
+			return
+		}
+
+		if filepath.Base(e.Path) == ".gitignore" {
+			ctx.matcherOf(e.Path).Invalidate(e.Path)
+		}
+
+		pattern, err := ctx.matcherOf(e.Path).MatchPattern(e.Path, e.IsDir())
+		ctx.logErr(err)
+
+		if pattern == "" {
+			return
+		}
+
+		if filepath.Base(e.Path) == GuardIgnoreFile {
+			ctx.doReload(guardReload{ctx.args, ctx.patterns})
+			return
+		}
+
+		ctx.emit(&GuardWatchEvent{ctx.relPath(e.Path), e.Op, time.Now(), pattern})
+
+		ctx.recordChange(e.Path)
+
+		if ctx.injectCSS(e) {
+			ctx.logChange(e)
+			return
+		}
+
+		ctx.markPreSteps(e.Path)
+
+		if g := ctx.route(e.Path); g != nil {
+			ctx.logChange(e)
+			ctx.watchCreated(e)
+			g.rerun(&e, nil)
+			return
+		}
+
+		if ctx.batch > 0 {
+			ctx.watchCreated(e)
+			batch = addPath(batch, ctx.relPath(e.Path))
+			batchLast = e
+			if batchEnd == nil {
+				batchEnd = time.After(ctx.batch)
+			}
+			return
+		}
+
+		if time.Since(lastRun) < *debounce {
+			lastRun = time.Now()
+			return
+		}
+		lastRun = time.Now()
+
+		// TODO: sometimes the stdout will sallow the \r
+		// Still don't know why
+		ctx.logChange(e)
+
+		ctx.watchCreated(e)
+
+		fire(&e)
+	}
+
 	for {
+		if len(ctx.pending) > 0 {
+			e := ctx.pending[0]
+			ctx.pending = ctx.pending[1:]
+			handle(e)
+			continue
+		}
+
 		select {
 		case e := <-ctx.watcher.Event:
-			matched, _, err := ctx.matcher.Match(e.Path, e.IsDir())
-			ctx.logErr(err)
+			handle(e)
+
+		case <-batchEnd:
+			batchEnd = nil
+			e := batchLast
+			ctx.log(len(batch), "files changed:", utils.C(strings.Join(batch, " "), "green"))
+
+			fire(&e)
+
+		case <-graceEnd:
+			trigger(queued)
+
+		case <-idleEnd:
+			trigger(queued)
 
-			if !matched {
+		case r := <-ctx.reload:
+			ctx.doReload(r)
+			ctx.resume()
+			rerun(nil)
+
+		case reason := <-ctx.schedule:
+			if reason == guardDequeue {
+				if q := ctx.dequeue(); q != nil && !ctx.isPaused() {
+					ctx.log(reason)
+					ctx.rerun(q.e, q.paths)
+				}
 				continue
 			}
 
-			if time.Since(lastRun) < *debounce {
-				lastRun = time.Now()
+			if reason == guardRestart {
+				ctx.resume()
+			} else if ctx.isPaused() {
 				continue
 			}
-			lastRun = time.Now()
+			ctx.log(reason)
 
-			// TODO: sometimes the stdout will sallow the \r
-			// Still don't know why
-			utils.Log(ctx.prefix, e, "\r")
+			rerun(nil)
 
-			if e.Op == watcher.Create {
-				if e.IsDir() {
-					ctx.addWatchFiles(e.Path)
-					ctx.logErr(err)
-				} else {
-					_ = ctx.watcher.Add(e.Path)
-				}
+		case err := <-ctx.watcher.Error:
+			ctx.logErr(err)
+
+		case <-ctx.watcher.Closed:
+			if ctx.events != nil {
+				close(ctx.events)
 			}
+			return
+		}
+	}
+}
 
-			if ctx.execCtxClone.GetCmd() != nil && ctx.execCtxClone.GetCmd().Process != nil {
-				_ = KillTree(ctx.execCtxClone.GetCmd().Process.Pid)
+func (ctx *GuardContext) emit(e *GuardWatchEvent) {
+	if ctx.events == nil {
+		return
+	}
+	select {
+	case ctx.events <- e:
+	case <-ctx.watcher.Closed:
+	}
+}
 
-				<-ctx.wait
-			}
+// there's nothing to run, the guard only emits the events
+func (ctx *GuardContext) watchOnly() bool {
+	return len(ctx.args) == 0 && ctx.runner == nil
+}
 
-			go ctx.run(&e)
+// watch the created file or dir
+func (ctx *GuardContext) watchCreated(e watcher.Event) {
+	if e.Op != watcher.Create {
+		return
+	}
+	ctx.unblock(func() {
+		if e.IsDir() || (ctx.followLinks && os.DirExists(e.Path)) {
+			_, err := ctx.addWatchFiles(e.Path)
+			ctx.logErr(err)
+		} else if !ctx.watchDirs {
+			_ = ctx.watcher.Add(e.Path)
+		}
+	})
+}
+
+// The watcher holds its lock while it waits for the watch loop to take an event, so calling the
+// watcher in the watch loop may deadlock. The fn runs in another goroutine, the events received
+// meanwhile are queued to the pending.
+func (ctx *GuardContext) unblock(fn func()) {
+	done := make(chan utils.Nil)
+	go func() {
+		defer close(done)
+		fn()
+	}()
 
+	for {
+		select {
+		case e := <-ctx.watcher.Event:
+			ctx.pending = append(ctx.pending, e)
 		case err := <-ctx.watcher.Error:
 			ctx.logErr(err)
+		case <-done:
+			return
+		}
+	}
+}
+
+// append p if it's not in the list
+func addPath(list []string, p string) []string {
+	for _, el := range list {
+		if el == p {
+			return list
+		}
+	}
+	return append(list, p)
+}
+
+// the unix nano time of the latest keystroke piped to the command
+var lastKeystroke int64
 
+func recordKeystroke() {
+	atomic.StoreInt64(&lastKeystroke, time.Now().UnixNano())
+}
+
+// how long to wait until there's no keystroke within idle
+func typingWait(idle time.Duration) time.Duration {
+	if idle <= 0 {
+		return 0
+	}
+	last := atomic.LoadInt64(&lastKeystroke)
+	if last == 0 {
+		return 0
+	}
+	return idle - time.Since(time.Unix(0, last))
+}
+
+type guardReload struct {
+	args     []string
+	patterns []string
+}
+
+// Reload replaces the command and patterns of the running guard, the watcher will be rewired
+// in place and the command will be rerun. If patterns is empty, GuardDefaultPatterns will be used.
+func (ctx *GuardContext) Reload(args []string, patterns ...string) {
+	select {
+	case ctx.reload <- guardReload{args, patterns}:
+	case <-ctx.watcher.Closed:
+	}
+}
+
+func (ctx *GuardContext) doReload(r guardReload) {
+	if len(r.patterns) == 0 {
+		r.patterns = GuardDefaultPatterns()
+	}
+
+	ctx.args = r.args
+	ctx.patterns = r.patterns
+	ctx.matcher = os.NewMatcher(ctx.dir, ctx.patterns).IgnoreFile(GuardIgnoreFile)
+	for _, step := range ctx.preSteps {
+		step.matcher = os.NewMatcher(ctx.dir, step.patterns)
+	}
+
+	ctx.initGoWork()
+
+	var files []string
+	ctx.unblock(func() {
+		old := ctx.watcher.WatchedFiles()
+
+		ctx.watchDirs = false
+		ctx.watchedFiles = 0
+		list, err := ctx.addWatchFiles(ctx.dir)
+		ctx.logErr(err)
+		moduleFiles, err := ctx.addGoWorkFiles()
+		ctx.logErr(err)
+		files = append(list, moduleFiles...)
+
+		// only unwatch the stale ones, so the unchanged files won't be reported again
+		keep := map[string]bool{}
+		for _, p := range files {
+			p = os.MustAbs(p)
+			keep[p] = true
+			keep[filepath.Dir(p)] = true
+		}
+		for p := range old {
+			if !keep[p] && !keep[filepath.Dir(p)] {
+				_ = ctx.watcher.Remove(p)
+			}
+		}
+
+		ctx.reloaded = map[string]time.Time{}
+		for p, info := range ctx.watcher.WatchedFiles() {
+			if _, has := old[p]; !has {
+				ctx.reloaded[p] = info.ModTime()
+			}
+		}
+	})
+
+	ctx.log("reloaded")
+
+	if ctx.onReady != nil {
+		ctx.onReady(files)
+	}
+}
+
+// The reload isn't atomic to the poll of the watcher, so the watcher may report the newly watched files
+// as removed and created again, even though they are unchanged.
+func (ctx *GuardContext) reloadNoise(e watcher.Event) bool {
+	t, has := ctx.reloaded[e.Path]
+	if !has {
+		return false
+	}
+
+	switch e.Op {
+	case watcher.Remove:
+		return os.Exists(e.Path)
+	case watcher.Create:
+		delete(ctx.reloaded, e.Path)
+		return e.ModTime().Equal(t)
+	}
+
+	delete(ctx.reloaded, e.Path)
+	return false
+}
+
+// kill the running command and run it again
+func (ctx *GuardContext) rerun(e *watcher.Event, paths []string) {
+	if ctx.watchOnly() {
+		return
+	}
+
+	if ctx.noKillSem != nil {
+		go ctx.runNoKill(e, paths)
+		return
+	}
+
+	if ctx.queue != GuardQueueRestart {
+		if !ctx.enqueue(e, paths) {
+			go ctx.runQueued(e, paths)
+		}
+		return
+	}
+
+	ctx.lock.Lock()
+	if ctx.ticket != nil {
+		ctx.limiter.cancel(ctx.ticket)
+		ctx.ticket = nil
+	}
+	if ctx.limiter != nil {
+		ctx.ticket = ctx.limiter.ticket(ctx.priority)
+	}
+	t := ctx.ticket
+	// cancel under the lock, so the previous run won't start its command after it
+	cancelPrev := ctx.preCancel
+	if cancelPrev != nil {
+		cancelPrev()
+	}
+	base := ctx.context
+	if base == nil {
+		base = context.Background()
+	}
+	c, cancel := context.WithCancel(base)
+	ctx.preCancel = cancel
+	ctx.lock.Unlock()
+
+	if cancelPrev != nil {
+		<-ctx.wait
+	} else if ctx.current != nil && ctx.current.GetCmd() != nil && ctx.current.GetCmd().Process != nil {
+		ctx.recordKill()
+		ctx.stopCurrent(ctx.current.GetCmd().Process.Pid)
+	}
+
+	// each run has its own copy, so a rerun won't share the cmd with the previous run
+	execCtx := ctx.newRun()
+	ctx.current = execCtx
+	go ctx.run(c, execCtx, e, paths, t)
+}
+
+// The copy of the execCtx for a run. The Retry is turned off, because guard stops the command
+// to rerun it, the retry would start the stopped command again behind the back of guard.
+// Each run has its own Tail, so the concurrent runs of NoKill won't mix their output.
+func (ctx *GuardContext) newRun() *ExecContext {
+	execCtx := *ctx.execCtx
+	execCtx.retries = 0
+	if execCtx.tail != nil {
+		execCtx.tail = newTailWriter(execCtx.tail.n)
+	}
+	return &execCtx
+}
+
+func (ctx *GuardContext) tickEvery() {
+	t := time.NewTicker(ctx.every)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			ctx.trigger("every " + ctx.every.String())
 		case <-ctx.watcher.Closed:
 			return
 		}
 	}
 }
 
+func (ctx *GuardContext) tickCron(cron *cronSchedule) {
+	for {
+		next := cron.next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		t := time.NewTimer(time.Until(next))
+
+		select {
+		case <-t.C:
+			ctx.trigger("cron " + ctx.cron)
+		case <-ctx.watcher.Closed:
+			t.Stop()
+			return
+		}
+	}
+}
+
+// Restart kills the running command and runs it again, as if a watched file is modified.
+// It also resumes the guard paused by MaxFailures.
+func (ctx *GuardContext) Restart() {
+	ctx.trigger(guardRestart)
+}
+
+const guardRestart = "restart"
+
+// send the reason to the watch loop, so the rerun won't race with the file events
+func (ctx *GuardContext) trigger(reason string) {
+	select {
+	case ctx.schedule <- reason:
+	case <-ctx.watcher.Closed:
+	}
+}
+
+// restart the run n after the backoff if it's still the latest one and it isn't killed
+func (ctx *GuardContext) restartLater(n int) {
+	if ctx.restart <= 0 || ctx.noKillSem != nil {
+		return
+	}
+
+	go func() {
+		t := time.NewTimer(ctx.restart)
+		defer t.Stop()
+
+		select {
+		case <-t.C:
+		case <-ctx.watcher.Closed:
+			return
+		}
+
+		ctx.lock.Lock()
+		exited := n == ctx.stats.started && n != ctx.stats.killed
+		ctx.lock.Unlock()
+
+		if exited {
+			ctx.trigger("exited, restarting")
+		}
+	}()
+}
+
 // MustDo ...
 func (ctx *GuardContext) MustDo() {
 	utils.E(ctx.Do())
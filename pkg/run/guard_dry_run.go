@@ -0,0 +1,53 @@
+package run
+
+import (
+	"github.com/radovskyb/watcher"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// DryRun prints the commands of each run with the placeholders rendered instead of executing them,
+// including the pending pre-steps, the steps, and each chunk of the Xargs. So the complex commands,
+// such as the sync commands, can be validated safely against the real events.
+func (ctx *GuardContext) DryRun() *GuardContext {
+	ctx.dryRun = true
+	return ctx
+}
+
+// print the commands that the run would execute
+func (ctx *GuardContext) previewRun(id string, e *watcher.Event, paths []string, chunks [][]string, runDir *guardRunDir) {
+	if ctx.runner != nil {
+		ctx.logEvent("dry-run", guardLogFields{"id": id, "runner": true}, "dry-run", id, utils.C("runner", "green"))
+		return
+	}
+
+	cmds := [][]string{}
+
+	ctx.lock.Lock()
+	for _, step := range ctx.preSteps {
+		if step.pending {
+			cmds = append(cmds, step.args)
+		}
+	}
+	ctx.lock.Unlock()
+
+	for _, step := range append(ctx.steps, &ExecContext{args: ctx.args}) {
+		args, err := ctx.unescapeArgs(step.args, e, paths, runDir)
+		if err != nil {
+			ctx.logErr(err)
+			return
+		}
+		cmds = append(cmds, args)
+	}
+
+	if chunks != nil {
+		args := cmds[len(cmds)-1]
+		cmds = cmds[:len(cmds)-1]
+		for _, chunk := range chunks {
+			cmds = append(cmds, append(append([]string{}, args...), chunk...))
+		}
+	}
+
+	for _, args := range cmds {
+		ctx.logEvent("dry-run", guardLogFields{"id": id, "args": args}, "dry-run", id, utils.C(QuoteArgs(args), "green"))
+	}
+}
@@ -0,0 +1,177 @@
+package run
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/karrick/godirwalk"
+	"github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// GuardExplanation tells why a path is watched or ignored
+type GuardExplanation struct {
+	Path    string // relative to the Dir, it starts with ".." if the path is outside of the Dir
+	IsDir   bool
+	Watched bool
+
+	// Rule decides the result, it's a pattern, a negative pattern, os.WalkGitIgnore, or GuardIgnoreFile.
+	// It's empty if no pattern matches.
+	Rule string
+
+	// Parent is the excluded dir that hides the path, the Rule excludes it
+	Parent string
+}
+
+func (e *GuardExplanation) outside() bool {
+	return e.Path == ".." || strings.HasPrefix(e.Path, ".."+string(filepath.Separator))
+}
+
+func (e *GuardExplanation) reason() string {
+	rule := e.Rule
+	switch rule {
+	case os.WalkGitIgnore:
+		rule += " (the gitignore rules or a submodule)"
+	case GuardIgnoreFile:
+		rule = "the " + rule + " file"
+	}
+
+	switch {
+	case e.outside():
+		return "outside of the dir"
+	case e.Parent != "":
+		return "the parent dir " + filepath.ToSlash(e.Parent) + " is excluded by " + rule
+	case e.Rule == "":
+		return "no pattern matches"
+	case e.Watched:
+		return "matched by " + rule
+	default:
+		return "excluded by " + rule
+	}
+}
+
+// GuardExplanations the result of Explain
+type GuardExplanations []*GuardExplanation
+
+// String formats the explanations as a table
+func (list GuardExplanations) String() string {
+	rows := [][]string{}
+	for _, e := range list {
+		p := filepath.ToSlash(e.Path)
+		if e.IsDir {
+			p += "/"
+		}
+
+		result := utils.C("ignored", "yellow")
+		if e.Watched {
+			result = utils.C("watched", "green")
+		}
+
+		rows = append(rows, []string{p, result, e.reason()})
+	}
+	return utils.Table([]string{"path", "result", "reason"}, rows)
+}
+
+// Explain walks the Dir with the same rules as Do, and tells why each path is watched or ignored,
+// the children of an excluded dir are skipped the same way. It doesn't watch or run anything,
+// use it to debug the combinations of the patterns, such as "**" and "!g".
+// If the paths are set, only they are explained, they are relative to the Dir and don't have to exist.
+func (ctx *GuardContext) Explain(paths ...string) (GuardExplanations, error) {
+	patterns := ctx.patterns
+	if len(patterns) == 0 {
+		patterns = GuardDefaultPatterns()
+	}
+	m := os.NewMatcher(ctx.dir, patterns).IgnoreFile(GuardIgnoreFile)
+
+	dir, err := filepath.Abs(ctx.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) > 0 {
+		list := GuardExplanations{}
+		for _, p := range paths {
+			e, err := explainPath(m, dir, p)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, e)
+		}
+		return list, nil
+	}
+
+	walk := os.Walk().Dir(dir).Sort().Matcher(os.NewMatcher(dir, []string{"**"}))
+	if ctx.sameDevice {
+		walk.SameDevice()
+	}
+	if ctx.followLinks {
+		walk.FollowSymbolicLinks()
+	}
+
+	list := GuardExplanations{}
+	err = walk.Do(func(p string, info *godirwalk.Dirent) error {
+		if p == dir {
+			return nil
+		}
+
+		matched, rule, err := m.MatchRule(p, info.IsDir())
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		list = append(list, &GuardExplanation{Path: rel, IsDir: info.IsDir(), Watched: matched, Rule: rule})
+
+		// the walk of Do doesn't enter the excluded dirs
+		if info.IsDir() && !matched && rule != "" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	return list, err
+}
+
+// the walk of Do doesn't enter the excluded dirs, so the parents are checked first
+func explainPath(m *os.Matcher, dir, p string) (*GuardExplanation, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &GuardExplanation{Path: rel, IsDir: os.DirExists(p)}
+	if e.outside() {
+		return e, nil
+	}
+
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i := 1; i < len(parts); i++ {
+		parent := filepath.Join(parts[:i]...)
+		matched, rule, err := m.MatchRule(filepath.Join(dir, parent), true)
+		if err != nil {
+			return nil, err
+		}
+
+		if !matched && rule != "" {
+			e.Rule = rule
+			e.Parent = parent
+			return e, nil
+		}
+	}
+
+	e.Watched, e.Rule, err = m.MatchRule(p, e.IsDir)
+	return e, err
+}
+
+// MustExplain ...
+func (ctx *GuardContext) MustExplain(paths ...string) GuardExplanations {
+	return utils.E(ctx.Explain(paths...))[0].(GuardExplanations)
+}
@@ -0,0 +1,166 @@
+package run
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gos "github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// a member module of the go.work that is outside the Dir
+type guardModule struct {
+	dir     string
+	matcher *gos.Matcher
+}
+
+// NoGoWork disables the detection of the go.work. By default, if the Dir is in a go workspace, the member
+// modules outside the Dir are watched with the same patterns, and their own gitignore files are respected,
+// so editing a dependency module restarts the command. The go.work is found the same way as the go command,
+// the GOWORK env is respected, such as GOWORK=off.
+func (ctx *GuardContext) NoGoWork() *GuardContext {
+	ctx.noGoWork = true
+	return ctx
+}
+
+// load the member modules of the go.work
+func (ctx *GuardContext) initGoWork() {
+	ctx.modules = nil
+	if ctx.noGoWork {
+		return
+	}
+
+	file := findGoWork(ctx.dir)
+	if file == "" {
+		return
+	}
+
+	dirs, err := goWorkUses(file)
+	if err != nil {
+		ctx.logErr(err)
+		return
+	}
+
+	list := []string{}
+	for _, d := range dirs {
+		if gos.IsSubPath(ctx.dir, d) {
+			continue // already watched
+		}
+		ctx.modules = append(ctx.modules, &guardModule{
+			dir:     d,
+			matcher: gos.NewMatcher(d, ctx.patterns).IgnoreFile(GuardIgnoreFile),
+		})
+		list = append(list, ctx.relPath(d))
+	}
+
+	if len(list) > 0 {
+		ctx.log("go workspace", ctx.relPath(file), "adds the modules:", utils.C(strings.Join(list, " "), "green"))
+	}
+}
+
+// returns the matched files of the member modules
+func (ctx *GuardContext) addGoWorkFiles() ([]string, error) {
+	var files []string
+	var err error
+	for _, m := range ctx.modules {
+		list, e := ctx.addWatchFiles(m.dir)
+		files = append(files, list...)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return files, err
+}
+
+// the matcher of the module that contains the p, the innermost one wins
+func (ctx *GuardContext) matcherOf(p string) *gos.Matcher {
+	if gos.IsSubPath(ctx.dir, p) {
+		return ctx.matcher
+	}
+
+	var m *guardModule
+	for _, el := range ctx.modules {
+		if gos.IsSubPath(el.dir, p) && (m == nil || len(el.dir) > len(m.dir)) {
+			m = el
+		}
+	}
+	if m == nil {
+		return ctx.matcher
+	}
+	return m.matcher
+}
+
+// find the go.work in the dir and its parents, returns empty if there's none or GOWORK=off
+func findGoWork(dir string) string {
+	switch env := os.Getenv("GOWORK"); env {
+	case "off":
+		return ""
+	case "":
+	default:
+		return gos.MustAbs(env)
+	}
+
+	for d := gos.MustAbs(dir); ; {
+		p := filepath.Join(d, "go.work")
+		if gos.FileExists(p) {
+			return p
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			return ""
+		}
+		d = parent
+	}
+}
+
+// the absolute dirs of the use directives, such as "use ./a" or a "use ( ... )" block
+func goWorkUses(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	root := filepath.Dir(file)
+	dirs := []string{}
+	inBlock := false
+
+	add := func(s string) {
+		if u, err := strconv.Unquote(s); err == nil {
+			s = u
+		}
+		if s == "" {
+			return
+		}
+		if !filepath.IsAbs(s) {
+			s = filepath.Join(root, filepath.FromSlash(s))
+		}
+		dirs = append(dirs, filepath.Clean(s))
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock:
+			add(line)
+		case line == "use (" || line == "use(":
+			inBlock = true
+		case strings.HasPrefix(line, "use ") || strings.HasPrefix(line, "use\t"):
+			add(strings.TrimSpace(line[len("use"):]))
+		}
+	}
+
+	return dirs, scanner.Err()
+}
@@ -0,0 +1,94 @@
+package run
+
+import (
+	"hash/fnv"
+	"io"
+	"os"
+	"time"
+
+	"github.com/radovskyb/watcher"
+)
+
+// files larger than this will only be compared by size and inode
+const maxHashSize = 10 * 1024 * 1024
+
+type fileState struct {
+	mtime time.Time
+	inode uint64
+	size  int64
+	hash  uint64
+}
+
+func getFileState(p string, info os.FileInfo) fileState {
+	s := fileState{
+		mtime: info.ModTime(),
+		inode: inode(info),
+		size:  info.Size(),
+	}
+
+	if info.Size() > maxHashSize {
+		return s
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return s
+	}
+	defer func() { _ = f.Close() }()
+
+	h := fnv.New64a()
+	_, _ = io.Copy(h, f)
+	s.hash = h.Sum64()
+
+	return s
+}
+
+// pollHash emits write events for the files whose inode or content changed but the mtime didn't,
+// the watcher only compares the mtime and mode, so the changes of the mtime are left to it
+func (ctx *GuardContext) pollHash(interval time.Duration) {
+	states := map[string]fileState{}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		seen := map[string]bool{}
+
+		for p, info := range ctx.watcher.WatchedFiles() {
+			if info.IsDir() {
+				continue
+			}
+
+			fi, err := os.Stat(p)
+			if err != nil {
+				continue
+			}
+			seen[p] = true
+
+			s := getFileState(p, fi)
+			old, has := states[p]
+			states[p] = s
+
+			if has && old != s && old.mtime.Equal(s.mtime) {
+				select {
+				case ctx.watcher.Event <- watcher.Event{Op: watcher.Write, Path: p, OldPath: p, FileInfo: fi}:
+				case <-ctx.watcher.Closed:
+					return
+				}
+			}
+		}
+
+		// forget the files that are removed or no longer watched
+		for p := range states {
+			if !seen[p] {
+				delete(states, p)
+			}
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.watcher.Closed:
+			return
+		}
+	}
+}
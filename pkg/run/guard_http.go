@@ -0,0 +1,60 @@
+package run
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+)
+
+// GuardTriggerHeader the header that a POST request to the HTTPTrigger must have, its value is ignored.
+// The browsers can't send a custom header cross-origin without the CORS preflight,
+// so the web pages can't rerun the command.
+const GuardTriggerHeader = "X-Guard-Trigger"
+
+// HTTPTrigger listens on the addr, such as "127.0.0.1:3000", a POST request with the GuardTriggerHeader
+// reruns the command like Restart, a GET request returns the GuardSummary as json.
+// So the editors and CI hooks can rerun without touching files. The listener is closed when the guard stops.
+func (ctx *GuardContext) HTTPTrigger(addr string) *GuardContext {
+	ctx.httpTrigger = addr
+	return ctx
+}
+
+// the max time to read the request headers, the slow clients won't hold the connections
+const guardTriggerHeaderTimeout = 10 * time.Second
+
+func (ctx *GuardContext) serveHTTPTrigger() error {
+	l, err := net.Listen("tcp", ctx.httpTrigger)
+	if err != nil {
+		return err
+	}
+
+	ctx.log("http trigger listening on", l.Addr().String())
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if r.Header.Get(GuardTriggerHeader) == "" {
+				http.Error(w, "the "+GuardTriggerHeader+" header is required", http.StatusForbidden)
+				return
+			}
+			ctx.Restart()
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ctx.Summary())
+	})
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: guardTriggerHeaderTimeout}
+
+	go func() { _ = srv.Serve(l) }()
+	go func() {
+		<-ctx.watcher.Closed
+		_ = srv.Close()
+	}()
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/radovskyb/watcher"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// JSONLog writes the logs of guard as json lines instead of the colored text, so they can be ingested by
+// the log pipelines, such as {"args":["go","test"],"id":"a1b2c3d4","run":1,"time":"...","type":"run"}.
+// The type is "run", "done", "canceled", "change", "error", or "log" for the rest, which has the text in
+// the "msg" field. The output of the command isn't changed.
+func (ctx *GuardContext) JSONLog() *GuardContext {
+	ctx.jsonLog = true
+	return ctx
+}
+
+type guardLogFields map[string]interface{}
+
+// log the v as text, or the fields as a json line in the JSONLog mode
+func (ctx *GuardContext) logEvent(typ string, fields guardLogFields, v ...interface{}) {
+	if !ctx.jsonLog {
+		ctx.log(v...)
+		return
+	}
+
+	fields["type"] = typ
+	fields["time"] = time.Now().Format(time.RFC3339Nano)
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		b, _ = json.Marshal(guardLogFields{"type": "error", "time": fields["time"], "error": err.Error()})
+	}
+
+	_, _ = fmt.Fprintln(ctx.logOutput(), string(b))
+}
+
+func (ctx *GuardContext) logChange(e watcher.Event) {
+	ctx.logEvent("change", guardLogFields{"path": ctx.relPath(e.Path), "op": e.Op.String()}, e, "\r")
+}
+
+func (ctx *GuardContext) logText(v ...interface{}) {
+	msg := strings.TrimSpace(utils.StripANSI(fmt.Sprintln(v...)))
+	ctx.logEvent("log", guardLogFields{"msg": msg}, v...)
+}
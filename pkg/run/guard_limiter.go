@@ -0,0 +1,87 @@
+package run
+
+import (
+	"runtime"
+	"sync"
+)
+
+// GuardLimiter caps the concurrent runs of the guards that share it, such as the sections of the guard cli.
+// When the cap is reached, the queued runs start by the priority, the higher the sooner.
+type GuardLimiter struct {
+	lock    sync.Mutex
+	limit   int
+	running int
+	seq     int
+	queue   []*limiterTicket
+}
+
+// NewGuardLimiter if limit is less than 1, the number of CPUs will be used
+func NewGuardLimiter(limit int) *GuardLimiter {
+	if limit < 1 {
+		limit = runtime.NumCPU()
+	}
+	return &GuardLimiter{limit: limit}
+}
+
+type limiterTicket struct {
+	priority int
+	seq      int
+	ready    chan bool // true if granted, false if canceled
+}
+
+func (l *GuardLimiter) ticket(priority int) *limiterTicket {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.seq++
+	return &limiterTicket{priority: priority, seq: l.seq, ready: make(chan bool, 1)}
+}
+
+// returns false if the ticket is canceled before it's granted
+func (l *GuardLimiter) acquire(t *limiterTicket) bool {
+	l.lock.Lock()
+	l.queue = append(l.queue, t)
+	l.next()
+	l.lock.Unlock()
+
+	return <-t.ready
+}
+
+func (l *GuardLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.running--
+	l.next()
+}
+
+// cancel the ticket if it's still queued
+func (l *GuardLimiter) cancel(t *limiterTicket) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for i, q := range l.queue {
+		if q == t {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			t.ready <- false
+			return
+		}
+	}
+}
+
+// grant the queued tickets by priority, the earlier one wins a tie
+func (l *GuardLimiter) next() {
+	for l.running < l.limit && len(l.queue) > 0 {
+		best := 0
+		for i, t := range l.queue {
+			if t.priority > l.queue[best].priority {
+				best = i
+			}
+		}
+
+		t := l.queue[best]
+		l.queue = append(l.queue[:best], l.queue[best+1:]...)
+		l.running++
+		t.ready <- true
+	}
+}
@@ -0,0 +1,45 @@
+package run
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardLimiter(t *testing.T) {
+	l := NewGuardLimiter(1)
+
+	assert.True(t, l.acquire(l.ticket(0)))
+
+	low := l.ticket(1)
+	high := l.ticket(2)
+	canceled := l.ticket(3)
+
+	done := make(chan int, 3)
+	for _, ticket := range []*limiterTicket{low, high, canceled} {
+		go func(ticket *limiterTicket) {
+			if l.acquire(ticket) {
+				done <- ticket.priority
+				l.release()
+			} else {
+				done <- -1
+			}
+		}(ticket)
+	}
+
+	for {
+		l.lock.Lock()
+		n := len(l.queue)
+		l.lock.Unlock()
+		if n == 3 {
+			break
+		}
+	}
+
+	l.cancel(canceled)
+	assert.Equal(t, -1, <-done)
+
+	l.release()
+	assert.Equal(t, 2, <-done)
+	assert.Equal(t, 1, <-done)
+}
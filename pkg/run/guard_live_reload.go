@@ -0,0 +1,175 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/radovskyb/watcher"
+	"github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// LiveReload serves the live reload on the addr, such as "127.0.0.1:35729", add the
+// <script src="http://127.0.0.1:35729/livereload.js"></script> to the page. When a file that matches the
+// cssPatterns changes, the default is "**/*.css", the command won't rerun, the stylesheets are injected
+// into the page instead. Other changes reload the page after the command restarts and the page is up again.
+func (ctx *GuardContext) LiveReload(addr string, cssPatterns ...string) *GuardContext {
+	if len(cssPatterns) == 0 {
+		cssPatterns = []string{"**/*.css"}
+	}
+	ctx.liveReload = &guardLiveReload{addr: addr, patterns: cssPatterns, clients: map[chan []byte]utils.Nil{}}
+	return ctx
+}
+
+type guardLiveReload struct {
+	addr     string
+	patterns []string
+	matcher  *os.Matcher
+
+	lock    sync.Mutex
+	clients map[chan []byte]utils.Nil
+}
+
+type guardLiveReloadMsg struct {
+	Type string `json:"type"` // "css" or "reload"
+	Path string `json:"path,omitempty"`
+}
+
+func (ctx *GuardContext) serveLiveReload() error {
+	lr := ctx.liveReload
+	lr.matcher = os.NewMatcher(ctx.dir, lr.patterns)
+
+	l, err := net.Listen("tcp", lr.addr)
+	if err != nil {
+		return err
+	}
+
+	ctx.log("live reload listening on", l.Addr().String())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livereload.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		_, _ = w.Write([]byte(liveReloadJS))
+	})
+	mux.HandleFunc("/events", lr.serveEvents)
+
+	srv := &http.Server{Handler: mux}
+
+	go func() { _ = srv.Serve(l) }()
+	go func() {
+		<-ctx.watcher.Closed
+		_ = srv.Close()
+	}()
+
+	return nil
+}
+
+// the server-sent events to the pages
+func (lr *guardLiveReload) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := make(chan []byte, 16)
+	lr.lock.Lock()
+	lr.clients[ch] = utils.Nil{}
+	lr.lock.Unlock()
+
+	defer func() {
+		lr.lock.Lock()
+		delete(lr.clients, ch)
+		lr.lock.Unlock()
+	}()
+
+	for {
+		select {
+		case msg := <-ch:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", msg); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// the slow pages miss the message instead of blocking the guard
+func (lr *guardLiveReload) send(msg guardLiveReloadMsg) {
+	b, _ := json.Marshal(msg)
+
+	lr.lock.Lock()
+	defer lr.lock.Unlock()
+
+	for ch := range lr.clients {
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+}
+
+// returns true if the css is injected, then the change won't rerun the command
+func (ctx *GuardContext) injectCSS(e watcher.Event) bool {
+	if ctx.liveReload == nil || ctx.liveReload.matcher == nil || e.IsDir() ||
+		(e.Op != watcher.Write && e.Op != watcher.Create) {
+		return false
+	}
+
+	matched, _, err := ctx.liveReload.matcher.Match(e.Path, false)
+	if err != nil || !matched {
+		return false
+	}
+
+	ctx.liveReload.send(guardLiveReloadMsg{Type: "css", Path: ctx.relPath(e.Path)})
+	return true
+}
+
+// reload the pages when the command reruns
+func (ctx *GuardContext) reloadPages() {
+	if ctx.liveReload != nil {
+		ctx.liveReload.send(guardLiveReloadMsg{Type: "reload"})
+	}
+}
+
+// The stylesheets whose url has the same file name as the changed one are reloaded,
+// all of them are reloaded if none matches. The page reloads when it's reachable again.
+const liveReloadJS = `(function () {
+  var src = document.currentScript.src;
+  var events = new EventSource(src.replace(/livereload\.js.*$/, "events"));
+
+  function injectCSS(path) {
+    var name = path.split(/[\\/]/).pop();
+    var links = [].slice.call(document.querySelectorAll('link[rel="stylesheet"]'));
+    var matched = links.filter(function (l) { return new URL(l.href).pathname.split("/").pop() === name; });
+    (matched.length ? matched : links).forEach(function (l) {
+      var u = new URL(l.href);
+      u.searchParams.set("livereload", Date.now());
+      l.href = u.href;
+    });
+  }
+
+  function reload() {
+    fetch(location.href, { method: "HEAD", cache: "no-store" }).then(function (res) {
+      res.ok ? location.reload() : setTimeout(reload, 300);
+    }, function () { setTimeout(reload, 300); });
+  }
+
+  events.onmessage = function (e) {
+    var msg = JSON.parse(e.data);
+    if (msg.type === "css") injectCSS(msg.path);
+    else setTimeout(reload, 300);
+  };
+})();
+`
@@ -0,0 +1,54 @@
+package run
+
+import (
+	"fmt"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// GuardMaxFilesError is reported when the patterns of the Guard match more files than the MaxFiles
+type GuardMaxFilesError struct {
+	Max   int
+	Files int
+}
+
+func (e *GuardMaxFilesError) Error() string {
+	return fmt.Sprintf("the patterns matched %d files, more than the max %d", e.Files, e.Max)
+}
+
+// MaxFiles limits the number of the watched files, because large repos can silently hit the limits of polling.
+// When more than n files are matched, the dirs of the files are watched instead of each file, the changes are
+// still filtered by the patterns. The fn is optional, it's called with the *GuardMaxFilesError before the fallback,
+// if it returns an error on startup Do fails with it, later errors are logged.
+func (ctx *GuardContext) MaxFiles(n int, fn func(err *GuardMaxFilesError) error) *GuardContext {
+	ctx.maxFiles = n
+	ctx.onMaxFiles = fn
+	return ctx
+}
+
+// count the list and check the limit, returns true if only the dirs should be watched
+func (ctx *GuardContext) overMaxFiles(list []string) (bool, error) {
+	if ctx.maxFiles <= 0 {
+		return false, nil
+	}
+	if ctx.watchDirs {
+		return true, nil
+	}
+
+	ctx.watchedFiles += len(list)
+	if ctx.watchedFiles <= ctx.maxFiles {
+		return false, nil
+	}
+
+	limitErr := &GuardMaxFilesError{Max: ctx.maxFiles, Files: ctx.watchedFiles}
+
+	var err error
+	if ctx.onMaxFiles != nil {
+		err = ctx.onMaxFiles(limitErr)
+	}
+
+	ctx.watchDirs = true
+	ctx.log(utils.C(limitErr.Error()+", watch the dirs instead", "yellow"))
+
+	return true, err
+}
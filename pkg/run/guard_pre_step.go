@@ -0,0 +1,89 @@
+package run
+
+import (
+	"context"
+
+	"github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+type guardPreStep struct {
+	args     []string
+	patterns []string
+	matcher  *os.Matcher
+	pending  bool // a matched file changed since the step last succeeded
+}
+
+// mark the steps whose patterns match the changed file
+func (ctx *GuardContext) markPreSteps(p string) {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	for _, step := range ctx.preSteps {
+		matched, _, err := step.matcher.Match(p, false)
+		ctx.logErr(err)
+
+		if matched {
+			step.pending = true
+		}
+	}
+}
+
+// run the pending steps in order, then the steps, stop at the first failure.
+// If c isn't nil, the preCancel will be cleared when it's done, because the command is about to start.
+func (ctx *GuardContext) runPreSteps(c context.Context, id string, execCtx *ExecContext, e *GuardEvent, paths []string, runDir *guardRunDir) error {
+	stepCtx := c
+	if stepCtx == nil {
+		stepCtx = context.Background()
+	}
+
+	ctx.lock.Lock()
+	if err := stepCtx.Err(); err != nil {
+		ctx.markKilled()
+		ctx.lock.Unlock()
+		return err
+	}
+	steps := []*guardPreStep{}
+	for _, step := range ctx.preSteps {
+		if step.pending {
+			step.pending = false
+			steps = append(steps, step)
+		}
+	}
+	ctx.lock.Unlock()
+
+	var err error
+	for i, step := range steps {
+		ctx.log("pre-step", id, utils.C(ctx.formatArgs(step.args), "green"))
+
+		err = Exec(step.args...).Context(stepCtx).Dir(ctx.dir).Prefix(execCtx.prefix).Stdout(execCtx.stdout).Do()
+		if err != nil {
+			// the failed step and the rest will run in the next run
+			ctx.lock.Lock()
+			for _, s := range steps[i:] {
+				s.pending = true
+			}
+			ctx.lock.Unlock()
+			break
+		}
+	}
+
+	if err == nil {
+		err = ctx.runSteps(stepCtx, id, execCtx, e, paths, runDir)
+	}
+
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	if e := stepCtx.Err(); e != nil {
+		// canceled by a newer run, it's not a failure
+		ctx.markKilled()
+		return e
+	}
+
+	// the newer run should kill the command instead of canceling the run
+	if err == nil && c != nil {
+		ctx.preCancel = nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package run
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+func TestGuardTypingIdle(t *testing.T) {
+	p := "tmp/" + utils.RandString(10)
+	_ = os.OutputFile(p+"/f", "a", nil)
+
+	i := time.Millisecond
+	d := time.Duration(0)
+	var count int32
+
+	guard := Guard().Patterns(p + "/**").Interval(&i).Debounce(&d).NoInitRun().Stdout(&bytes.Buffer{}).
+		TypingIdle(300 * time.Millisecond).
+		Runner(func(e *GuardEvent) error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+	go guard.MustDo()
+	defer func() { atomic.StoreInt64(&lastKeystroke, 0) }()
+
+	time.Sleep(100 * time.Millisecond)
+
+	recordKeystroke()
+	_ = os.OutputFile(p+"/f", "b", nil)
+	time.Sleep(200 * time.Millisecond)
+	recordKeystroke()
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&count))
+
+	time.Sleep(400 * time.Millisecond)
+	guard.Stop()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestGuardLogLock(t *testing.T) {
+	buf := &bytes.Buffer{}
+	guard := Guard().Stdout(buf)
+
+	// the output of the command holds the lock
+	outputLock.Lock()
+	done := make(chan utils.Nil)
+	go func() {
+		guard.log("ok")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the log doesn't wait for the output")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, 0, buf.Len())
+
+	outputLock.Unlock()
+	<-done
+	assert.Contains(t, buf.String(), "ok")
+}
+
+func TestGuardLogSilence(t *testing.T) {
+	buf := &bytes.Buffer{}
+	guard := Guard().Stdout(buf)
+
+	utils.LogSilence("[guard]")
+	defer utils.LogRoute("[guard]", nil)
+
+	guard.log("ok")
+	assert.Empty(t, buf.String())
+}
+
+func TestGuardRunTail(t *testing.T) {
+	ctx := Guard("go", "version").ExecCtx(Exec().Tail(2))
+
+	// the concurrent runs of NoKill won't share the tail
+	a, b := ctx.newRun(), ctx.newRun()
+	assert.NotSame(t, a.tail, b.tail)
+	assert.Equal(t, 2, b.tail.n)
+
+	_, _ = a.tail.Write([]byte("a\n"))
+	_, _ = b.tail.Write([]byte("b\n"))
+	assert.Equal(t, "a", a.LastOutput())
+	assert.Equal(t, "b", b.LastOutput())
+
+	_, _, _ = ctx.recordDone(1, time.Second, nil, a.LastOutput())
+	assert.Equal(t, "a", ctx.Summary().LastOutput)
+}
@@ -0,0 +1,99 @@
+package run
+
+import (
+	"github.com/radovskyb/watcher"
+)
+
+// GuardQueuePolicy decides what happens to the changes that arrive while the command is running
+type GuardQueuePolicy int
+
+const (
+	// GuardQueueRestart kills the running command and runs it again, the default
+	GuardQueueRestart GuardQueuePolicy = iota
+
+	// GuardQueueOne waits for the running command to exit, then runs it once for all the changes during it
+	GuardQueueOne
+
+	// GuardQueueDrop ignores the changes while the command is running
+	GuardQueueDrop
+)
+
+// Queue sets the policy for the changes that arrive while the command is running, such as GuardQueueOne
+// for the batch jobs like tests, so the work won't be wasted by a kill. Restart follows the policy too.
+// It doesn't apply to NoKill.
+func (ctx *GuardContext) Queue(policy GuardQueuePolicy) *GuardContext {
+	ctx.queue = policy
+	return ctx
+}
+
+type guardQueued struct {
+	e     *watcher.Event
+	paths []string
+}
+
+const guardDequeue = "run the queued changes"
+
+// returns true if the run should wait or be dropped because the previous one is still running
+func (ctx *GuardContext) enqueue(e *watcher.Event, paths []string) bool {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	if !ctx.busy {
+		ctx.busy = true
+		return false
+	}
+
+	if ctx.queue == GuardQueueDrop {
+		ctx.log("dropped, the command is still running")
+		return true
+	}
+
+	if ctx.queued == nil {
+		ctx.queued = &guardQueued{}
+		ctx.log("queued, the command is still running")
+	}
+
+	if e != nil {
+		ctx.queued.e = e
+		if paths == nil {
+			paths = []string{ctx.relPath(e.Path)}
+		}
+	}
+	for _, p := range paths {
+		ctx.queued.paths = addPath(ctx.queued.paths, p)
+	}
+
+	return true
+}
+
+// the runs never overlap because of the enqueue, so there's nothing to kill
+func (ctx *GuardContext) runQueued(e *watcher.Event, paths []string) {
+	if ctx.limiter != nil {
+		ctx.limiter.acquire(ctx.limiter.ticket(ctx.priority))
+		defer ctx.limiter.release()
+	}
+	defer ctx.done()
+
+	ctx.exec(nil, ctx.newRun(), e, paths)
+}
+
+// the run is done, schedule the queued changes via the watch loop
+func (ctx *GuardContext) done() {
+	ctx.lock.Lock()
+	ctx.busy = false
+	queued := ctx.queued != nil
+	ctx.lock.Unlock()
+
+	if queued {
+		go ctx.trigger(guardDequeue)
+	}
+}
+
+func (ctx *GuardContext) dequeue() *guardQueued {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	q := ctx.queued
+	ctx.queued = nil
+	return q
+}
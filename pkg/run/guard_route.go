@@ -0,0 +1,71 @@
+package run
+
+import (
+	"github.com/ysmood/kit/pkg/os"
+)
+
+type guardRoute struct {
+	pattern string
+	execCtx *ExecContext
+	matcher *os.Matcher
+	guard   *GuardContext
+}
+
+// Route runs the c instead of the command of the Guard when a changed file matches the pattern, such as
+// Route("**/*.proto", Exec("make", "proto")). The first matched route wins, the files still need to match
+// the Patterns to be watched. Each route kills and reruns its own command, it only runs on the matched changes,
+// the Batch, StartupGrace, and TypingIdle don't apply to it. The args of c support the same go template as Guard.
+func (ctx *GuardContext) Route(pattern string, c *ExecContext) *GuardContext {
+	ctx.routes = append(ctx.routes, &guardRoute{pattern: pattern, execCtx: c})
+	return ctx
+}
+
+// create a guard for each route that shares the watcher and the settings of the ctx
+func (ctx *GuardContext) initRoutes() {
+	for _, r := range ctx.routes {
+		r.matcher = os.NewMatcher(ctx.dir, []string{r.pattern})
+
+		if ctx.stdout != nil && r.execCtx.stdout == nil {
+			r.execCtx.Stdout(ctx.stdout)
+		}
+		if r.execCtx.prefix == "" {
+			r.execCtx.Prefix(ctx.execCtx.prefix)
+		}
+
+		g := Guard(r.execCtx.args...)
+		g.dir = ctx.dir
+		g.execCtx = r.execCtx
+		g.stdout = ctx.stdout
+		g.prefix = ctx.prefix
+		g.clearScreen = ctx.clearScreen
+		g.clearMode = ctx.clearMode
+		g.stop = ctx.stop
+		g.maxFailures = ctx.maxFailures
+		g.limiter = ctx.limiter
+		g.priority = ctx.priority
+		g.onDone = ctx.onDone
+		g.onBeforeRun = ctx.onBeforeRun
+		g.onAfterRun = ctx.onAfterRun
+		g.watcher = ctx.watcher
+		g.context = ctx.context
+		g.jsonLog = ctx.jsonLog
+		g.xargs = ctx.xargs
+		g.xargsMax = ctx.xargsMax
+		g.liveReload = ctx.liveReload
+		g.dryRun = ctx.dryRun
+		r.guard = g
+	}
+}
+
+// returns the guard of the first route that matches the p
+func (ctx *GuardContext) route(p string) *GuardContext {
+	for _, r := range ctx.routes {
+		matched, _, err := r.matcher.Match(p, false)
+		ctx.logErr(err)
+
+		if matched {
+			return r.guard
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package run
+
+import (
+	"os"
+)
+
+// the scratch dir of a run for {{runDir}}, it's only created when the placeholder is used
+type guardRunDir struct {
+	path string
+}
+
+func (d *guardRunDir) get() (string, error) {
+	if d.path != "" {
+		return d.path, nil
+	}
+
+	p, err := os.MkdirTemp("", "guard-run-")
+	if err != nil {
+		return "", err
+	}
+	d.path = p
+	return p, nil
+}
+
+func (d *guardRunDir) remove() error {
+	if d.path == "" {
+		return nil
+	}
+	return os.RemoveAll(d.path)
+}
@@ -0,0 +1,42 @@
+package run
+
+import (
+	"time"
+)
+
+// ExecDelay delays the initial run by d, such as staggering the start of several guards.
+// The watching starts immediately, a file change before it runs the command as usual.
+func (ctx *GuardContext) ExecDelay(d time.Duration) *GuardContext {
+	ctx.execDelay = d
+	return ctx
+}
+
+// After holds the initial run until the initial runs of the guards have started, such as starting the
+// database before the api server. The ExecDelay counts after that, to give the dependencies time to get ready.
+// It waits forever if a guard never runs, such as the one with NoInitRun.
+func (ctx *GuardContext) After(guards ...*GuardContext) *GuardContext {
+	ctx.after = append(ctx.after, guards...)
+	return ctx
+}
+
+// the initial run waits for the dependencies and the delay, it's scheduled by the watch loop
+func (ctx *GuardContext) initRunLater() {
+	for _, g := range ctx.after {
+		select {
+		case <-g.started:
+		case <-ctx.watcher.Closed:
+			return
+		}
+	}
+
+	t := time.NewTimer(ctx.execDelay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.watcher.Closed:
+		return
+	}
+
+	ctx.trigger("delayed start")
+}
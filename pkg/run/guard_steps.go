@@ -0,0 +1,51 @@
+package run
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// Steps runs the commands in order on each run, a failed step skips the rest. The last step is the
+// long-running one that gets killed on change, the steps before it are canceled by a newer run instead.
+// The args of each step support the same go template as Guard. It overrides the args of Guard and the ExecCtx.
+func (ctx *GuardContext) Steps(steps ...*ExecContext) *GuardContext {
+	if len(steps) == 0 {
+		return ctx
+	}
+
+	last := steps[len(steps)-1]
+	ctx.steps = steps[:len(steps)-1]
+	ctx.args = last.args
+	ctx.execCtx = last
+	return ctx
+}
+
+// run the steps before the last one, the output settings of the last one are used if a step doesn't have its own
+func (ctx *GuardContext) runSteps(c context.Context, id string, execCtx *ExecContext, e *GuardEvent, paths []string, runDir *guardRunDir) error {
+	for i, step := range ctx.steps {
+		args, err := ctx.unescapeArgs(step.args, e, paths, runDir)
+		if err != nil {
+			return err
+		}
+
+		ctx.log("step", id, fmt.Sprintf("%d/%d", i+1, len(ctx.steps)+1), utils.C(ctx.formatArgs(args), "green"))
+
+		s := *step
+		if s.dir == "" {
+			s.dir = ctx.dir
+		}
+		if s.stdout == nil {
+			s.stdout = execCtx.stdout
+		}
+		if s.prefix == "" {
+			s.prefix = execCtx.prefix
+		}
+
+		if err := s.Context(c).Args(args).Do(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
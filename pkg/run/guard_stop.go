@@ -0,0 +1,79 @@
+package run
+
+import (
+	"os"
+	"os/exec"
+	"time"
+)
+
+type guardStop struct {
+	signal  os.Signal
+	timeout time.Duration
+}
+
+// StopSignal sets the signal sent to the process group of the command before the rerun,
+// such as os.Interrupt to let a server flush its state. The default is SIGTERM,
+// only os.Interrupt and os.Kill are supported on windows.
+func (ctx *GuardContext) StopSignal(sig os.Signal) *GuardContext {
+	ctx.stop.signal = sig
+	return ctx
+}
+
+// KillTimeout kills the command if it doesn't exit within d after the stop signal,
+// the default is 0, which waits until it exits.
+func (ctx *GuardContext) KillTimeout(d time.Duration) *GuardContext {
+	ctx.stop.timeout = d
+	return ctx
+}
+
+// send the stop signal to the command and wait for it to exit
+func (ctx *GuardContext) stopCurrent(pid int) {
+	ctx.signalStop(pid)
+
+	if ctx.stop.timeout <= 0 {
+		<-ctx.wait
+		return
+	}
+
+	select {
+	case <-ctx.wait:
+	case <-time.After(ctx.stop.timeout):
+		ctx.log("killed, the command didn't exit in", ctx.stop.timeout)
+		// the children that left the group are killed too
+		_ = KillTree(pid, os.Kill)
+		<-ctx.wait
+	}
+}
+
+func (ctx *GuardContext) signalStop(pid int) {
+	if ctx.stop.signal == nil {
+		_ = KillTree(pid)
+	} else if err := signalGroup(pid, ctx.stop.signal); err != nil {
+		ctx.logErr(err)
+		_ = KillTree(pid)
+	}
+}
+
+// stop the command the same way as a rerun when the context of the cmd is done
+func (ctx *GuardContext) cancelOn(cmd *exec.Cmd) {
+	if cmd == nil {
+		return
+	}
+
+	cmd.Cancel = func() error {
+		ctx.signalStop(cmd.Process.Pid)
+		return nil
+	}
+	cmd.WaitDelay = ctx.stop.timeout
+}
+
+// stop watching when the context is done, the watcher can only be closed after it starts
+func (ctx *GuardContext) stopOnDone() {
+	ctx.watcher.Wait()
+
+	select {
+	case <-ctx.context.Done():
+		ctx.Stop()
+	case <-ctx.watcher.Closed:
+	}
+}
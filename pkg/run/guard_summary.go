@@ -0,0 +1,271 @@
+package run
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// GuardFileCount how many times a file changed
+type GuardFileCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// GuardSummary the snapshot of the state and the statistics of a guard.
+// The durations are encoded as milliseconds in json, such as "avgDurationMs".
+type GuardSummary struct {
+	Running  bool `json:"running"` // the command is running
+	Failed   bool `json:"failed"`  // the last run of the command failed
+	Paused   bool `json:"paused"`  // the reruns are paused by MaxFailures
+	Runs     int  `json:"runs"`
+	Failures int  `json:"failures"`
+	Flaky    int  `json:"flaky"`    // the runs that passed without any change after a failure
+	ExitCode int  `json:"exitCode"` // the exit code of the last finished run, -1 if it isn't an exit error
+
+	AvgDuration  time.Duration `json:"-"`
+	LastDuration time.Duration `json:"-"`
+
+	WatchedFiles int              `json:"watchedFiles"`
+	Events       int              `json:"events"` // the file events that match the patterns
+	TopChanged   []GuardFileCount `json:"topChanged"`
+	LastOutput   string           `json:"lastOutput,omitempty"` // the lines kept by ExecContext.Tail of the last finished run
+}
+
+// MarshalJSON encodes the durations as milliseconds
+func (s GuardSummary) MarshalJSON() ([]byte, error) {
+	type summary GuardSummary
+	return json.Marshal(struct {
+		summary
+		AvgDuration  int64 `json:"avgDurationMs"`
+		LastDuration int64 `json:"lastDurationMs"`
+	}{summary(s), s.AvgDuration.Milliseconds(), s.LastDuration.Milliseconds()})
+}
+
+// String formats the summary as human readable lines
+func (s *GuardSummary) String() string {
+	head := fmt.Sprintf("runs: %d, failures: %d, avg duration: %v", s.Runs, s.Failures, s.AvgDuration)
+	if s.Flaky > 0 {
+		head += fmt.Sprintf(", flaky: %d", s.Flaky)
+	}
+	lines := []string{head}
+
+	if len(s.TopChanged) > 0 {
+		rows := [][]string{}
+		for _, f := range s.TopChanged {
+			rows = append(rows, []string{strconv.Itoa(f.Count), f.Path})
+		}
+		lines = append(lines, "most frequently changed files:", indent(utils.Table([]string{"count", "path"}, rows)))
+	}
+
+	if s.LastOutput != "" {
+		lines = append(lines, "last output:", indent(s.LastOutput))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
+
+// GuardResult the result of a run, it's passed to the OnDone callback
+type GuardResult struct {
+	Args     []string // the command, it's empty for the Runner
+	Path     string   // the changed file that triggers the run, empty for the initial and scheduled runs
+	Err      error
+	Failed   bool // it's false if the command is killed by guard
+	Flaky    bool // the run passed without any change after a failure
+	Duration time.Duration
+	Output   string // the lines kept by ExecContext.Tail
+}
+
+// the number of files in the GuardSummary.TopChanged
+const guardTopChanged = 5
+
+type guardStats struct {
+	runs     int
+	failures int
+	total    time.Duration
+	changed  map[string]int
+
+	started int // the number of the latest started run
+	running int // the number of runs in progress
+	killed  int // the number of the run killed by guard, it's not a failure
+	failed  bool
+
+	consecutive int // the number of consecutive failures
+	exitCode    int
+	last        time.Duration // the duration of the last finished run
+	output      string        // the lines kept by the Tail of the last finished run
+	paused      bool
+
+	flaky      int
+	changes    int  // the number of the file changes
+	runChanges int  // the changes when the latest run starts
+	unchanged  bool // no file changed between the latest run and the one before it
+}
+
+// Summary returns the snapshot of the guard, it's safe to call it concurrently
+func (ctx *GuardContext) Summary() *GuardSummary {
+	watched := 0
+	if ctx.watcher != nil {
+		for _, f := range ctx.watcher.WatchedFiles() {
+			if !f.IsDir() {
+				watched++
+			}
+		}
+	}
+
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	s := &GuardSummary{
+		Running:      ctx.stats.running > 0,
+		Failed:       ctx.stats.failed,
+		Paused:       ctx.stats.paused,
+		Runs:         ctx.stats.runs,
+		Failures:     ctx.stats.failures,
+		Flaky:        ctx.stats.flaky,
+		ExitCode:     ctx.stats.exitCode,
+		LastDuration: ctx.stats.last,
+		WatchedFiles: watched,
+		Events:       ctx.stats.changes,
+		TopChanged:   []GuardFileCount{},
+	}
+
+	s.LastOutput = ctx.stats.output
+
+	if s.Runs > 0 {
+		s.AvgDuration = ctx.stats.total / time.Duration(s.Runs)
+	}
+
+	for p, c := range ctx.stats.changed {
+		s.TopChanged = append(s.TopChanged, GuardFileCount{p, c})
+	}
+	sort.Slice(s.TopChanged, func(i, j int) bool {
+		a, b := s.TopChanged[i], s.TopChanged[j]
+		if a.Count == b.Count {
+			return a.Path < b.Path
+		}
+		return a.Count > b.Count
+	})
+	if len(s.TopChanged) > guardTopChanged {
+		s.TopChanged = s.TopChanged[:guardTopChanged]
+	}
+
+	return s
+}
+
+// returns the number of the run
+func (ctx *GuardContext) recordStart() int {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.stats.started++
+	ctx.stats.running++
+	ctx.stats.unchanged = ctx.stats.started > 1 && ctx.stats.changes == ctx.stats.runChanges
+	ctx.stats.runChanges = ctx.stats.changes
+	if ctx.stats.started == 1 {
+		close(ctx.started)
+	}
+	return ctx.stats.started
+}
+
+// returns if the run failed, if the run is flaky, and if the guard is paused by this run
+func (ctx *GuardContext) recordDone(n int, d time.Duration, err error, output string) (failed, flaky, paused bool) {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	// only the latest run is compared with the previous one
+	flaky = err == nil && n == ctx.stats.started && ctx.stats.failed && ctx.stats.unchanged
+	if flaky {
+		ctx.stats.flaky++
+	}
+
+	ctx.stats.runs++
+	ctx.stats.total += d
+	ctx.stats.exitCode = exitCode(err)
+	ctx.stats.last = d
+	ctx.stats.output = output
+	ctx.stats.failed = err != nil && ctx.stats.killed != n
+	if ctx.stats.failed {
+		ctx.stats.failures++
+		ctx.stats.consecutive++
+	} else if err == nil {
+		ctx.stats.consecutive = 0
+	}
+	ctx.stats.running--
+
+	if ctx.maxFailures > 0 && !ctx.stats.paused && ctx.stats.consecutive >= ctx.maxFailures {
+		ctx.stats.paused = true
+		return ctx.stats.failed, flaky, true
+	}
+	return ctx.stats.failed, flaky, false
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var e *exec.ExitError
+	if errors.As(err, &e) {
+		return e.ExitCode()
+	}
+	return -1
+}
+
+func (ctx *GuardContext) isPaused() bool {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	return ctx.stats.paused
+}
+
+func (ctx *GuardContext) resume() {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.stats.paused = false
+	ctx.stats.consecutive = 0
+}
+
+// the command to kill always belongs to the latest run
+func (ctx *GuardContext) recordKill() {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.markKilled()
+}
+
+// the same as recordKill, the caller should hold the lock
+func (ctx *GuardContext) markKilled() {
+	if ctx.stats.running > 0 {
+		ctx.stats.killed = ctx.stats.started
+	}
+}
+
+func (ctx *GuardContext) recordChange(p string) {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	ctx.stats.changed[ctx.relPath(p)]++
+	ctx.stats.changes++
+}
+
+// the path relative to the dir of the guard
+func (ctx *GuardContext) relPath(p string) string {
+	if rel, err := os.RelTo(ctx.dir, p); err == nil {
+		return rel
+	}
+	return p
+}
@@ -1,9 +1,23 @@
 package run_test
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/ysmood/kit"
 )
 
@@ -115,3 +129,1211 @@ func TestGuardRunErr(t *testing.T) {
 
 	guard.Stop()
 }
+
+func TestGuardEvery(t *testing.T) {
+	guard := kit.Guard("go", "version").Patterns("a").NoInitRun().Every(100 * time.Millisecond)
+	go guard.MustDo()
+
+	wait()
+
+	guard.Stop()
+}
+
+func TestGuardCron(t *testing.T) {
+	guard := kit.Guard("go", "version").Patterns("a").NoInitRun().Cron("@hourly")
+	go guard.MustDo()
+
+	wait()
+
+	guard.Stop()
+}
+
+func TestGuardCronErr(t *testing.T) {
+	err := kit.Guard("go", "version").Cron("a").Do()
+	assert.Error(t, err)
+}
+
+func TestGuardContainer(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	tmp := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "a", nil)
+	info, _ := os.Stat(p + "/f")
+
+	i := 10 * time.Millisecond
+
+	guard := kit.Guard().Patterns(p + "/**").Container().Interval(&i).Stdout(&lockedBuffer{})
+	events := guard.Events()
+	go guard.MustDo()
+
+	wait()
+
+	// replace the file with the same mtime, like some docker bind mounts
+	_ = kit.OutputFile(tmp, "b", nil)
+	_ = os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	_ = os.Rename(tmp, p+"/f")
+
+	assert.Equal(t, filepath.Join(p, "f"), (<-events).Path)
+
+	// the change of the mtime is left to the watcher, so it's reported only once
+	_ = kit.OutputFile(p+"/f", "c", nil)
+
+	assert.Equal(t, filepath.Join(p, "f"), (<-events).Path)
+
+	wait()
+
+	go guard.Stop()
+	count := 0
+	for range events {
+		count++
+	}
+	assert.Equal(t, 0, count)
+}
+
+func TestGuardSummary(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	i := 1 * time.Millisecond
+	d := 0 * time.Millisecond
+
+	guard := kit.Guard("go", "version").Patterns(p + "/**").Interval(&i).Debounce(&d)
+	go guard.MustDo()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = kit.OutputFile(p+"/f", "ok", nil)
+	}()
+
+	time.Sleep(time.Second)
+
+	guard.Stop()
+
+	s := guard.Summary()
+	assert.GreaterOrEqual(t, s.Runs, 1)
+	assert.Equal(t, 0, s.Failures)
+	assert.Equal(t, filepath.Join(p, "f"), s.TopChanged[0].Path)
+	assert.Regexp(t, "runs: \\d+, failures: 0", s.String())
+}
+
+func TestGuardSummaryFailures(t *testing.T) {
+	guard := kit.Guard("exitexit").Patterns("a")
+	go guard.MustDo()
+
+	wait()
+
+	guard.Stop()
+
+	assert.Equal(t, 1, guard.Summary().Failures)
+}
+
+func TestGuardReload(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/a/f", "ok", nil)
+	_ = kit.OutputFile(p+"/b/f", "ok", nil)
+
+	i := 1 * time.Millisecond
+	d := 0 * time.Millisecond
+
+	done := make(chan error, 10)
+	guard := kit.Guard("exitexit").Patterns(p + "/a/**").Interval(&i).Debounce(&d).
+		OnAfterRun(func(err error) { done <- err })
+	go guard.MustDo()
+
+	assert.Error(t, <-done)
+
+	guard.Reload([]string{"go", "version"}, p+"/b/**")
+
+	assert.NoError(t, <-done)
+
+	_ = kit.OutputFile(p+"/a/f", "changed", nil)
+	_ = kit.OutputFile(p+"/b/f", "changed", nil)
+
+	assert.NoError(t, <-done)
+
+	guard.Stop()
+
+	s := guard.Summary()
+	assert.Equal(t, 1, s.Failures)
+	assert.Equal(t, filepath.Join(p, "b", "f"), s.TopChanged[0].Path)
+	assert.Len(t, s.TopChanged, 1)
+}
+
+func TestGuardRestartAndStatus(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	buf := &bytes.Buffer{}
+	guard := kit.Guard("exitexit").Patterns(p + "/**").Stdout(buf)
+	go guard.MustDo()
+
+	wait()
+
+	s := guard.Summary()
+	assert.False(t, s.Running)
+	assert.True(t, s.Failed)
+	assert.Equal(t, 1, s.WatchedFiles)
+
+	guard.Restart()
+
+	wait()
+
+	guard.Stop()
+
+	assert.Equal(t, 2, guard.Summary().Runs)
+	assert.Contains(t, buf.String(), "restart")
+}
+
+func TestGuardSummaryCounters(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	i := 1 * time.Millisecond
+	d := 0 * time.Millisecond
+
+	guard := kit.Guard("go", "version").Patterns(p + "/**").Interval(&i).Debounce(&d).Stdout(&bytes.Buffer{})
+	go guard.MustDo()
+
+	wait()
+	_ = kit.OutputFile(p+"/f", "changed", nil)
+	wait()
+
+	s := guard.Summary()
+	guard.Stop()
+
+	assert.GreaterOrEqual(t, s.Runs, 2)
+	assert.NotZero(t, s.LastDuration)
+	assert.Equal(t, 0, s.ExitCode)
+	assert.Equal(t, 1, s.WatchedFiles)
+	assert.GreaterOrEqual(t, s.Events, 1)
+
+	b, err := json.Marshal(s)
+	kit.E(err)
+	assert.Contains(t, string(b), fmt.Sprintf(`"lastDurationMs":%d`, s.LastDuration.Milliseconds()))
+	assert.Contains(t, string(b), `"avgDurationMs":`)
+	assert.NotContains(t, string(b), `"lastDuration":`)
+}
+
+func TestGuardHTTPTrigger(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	guard := kit.Guard("exitexit").Patterns(p + "/**").Stdout(&bytes.Buffer{}).HTTPTrigger(addr)
+	go guard.MustDo()
+
+	wait()
+
+	s := kit.Req("http://" + addr).MustJSON()
+	assert.Equal(t, int64(1), s.Get("runs").Int())
+	assert.True(t, s.Get("failed").Bool())
+	assert.Equal(t, int64(-1), s.Get("exitCode").Int())
+
+	// the cross-origin requests from the browsers can't have the header
+	assert.Equal(t, 403, kit.Req("http://"+addr).Post().MustResponse().StatusCode)
+
+	kit.Req("http://"+addr).Post().Header(kit.GuardTriggerHeader, "1").MustDo()
+	wait()
+	assert.Equal(t, 2, guard.Summary().Runs)
+
+	assert.Equal(t, 405, kit.Req("http://"+addr).Method("PUT").MustResponse().StatusCode)
+
+	guard.Stop()
+
+	err := kit.Guard("go", "version").Patterns(p + "/**").HTTPTrigger("invalid").Do()
+	assert.Error(t, err)
+}
+
+func TestGuardOnDone(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	i := 1 * time.Millisecond
+	lock := sync.Mutex{}
+	results := []*kit.GuardResult{}
+
+	guard := kit.Guard("exitexit").Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).
+		OnDone(func(r *kit.GuardResult) {
+			lock.Lock()
+			defer lock.Unlock()
+			results = append(results, r)
+		})
+	go guard.MustDo()
+
+	wait()
+	_ = kit.OutputFile(p+"/f", "changed", nil)
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.GreaterOrEqual(t, len(results), 2)
+	assert.Equal(t, "", results[0].Path)
+	assert.True(t, results[0].Failed)
+	assert.Equal(t, []string{"exitexit"}, results[0].Args)
+	assert.Equal(t, filepath.Join(p, "f"), results[len(results)-1].Path)
+}
+
+func TestGuardHooks(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	lock := sync.Mutex{}
+	events := []string{}
+	add := func(s string) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, s)
+	}
+
+	guard := kit.Guard("exitexit").Patterns(p + "/**").Stdout(&bytes.Buffer{}).
+		OnWatchReady(func(files []string) {
+			add(fmt.Sprint("ready ", len(files)))
+		}).
+		OnBeforeRun(func(e *kit.GuardEvent) {
+			add(fmt.Sprint("before ", e == nil))
+		}).
+		OnAfterRun(func(err error) {
+			add(fmt.Sprint("after ", err != nil))
+		})
+	go guard.MustDo()
+
+	wait()
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.ElementsMatch(t, []string{"ready 1", "before true", "after true"}, events)
+}
+
+func TestGuardBatch(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/a", "", nil)
+	_ = kit.OutputFile(p+"/b", "", nil)
+
+	i := 1 * time.Millisecond
+	lock := sync.Mutex{}
+	list := [][]string{}
+
+	guard := kit.Guard("exitexit", "{{paths}}", "-{{paths}}").Patterns(p + "/*").Interval(&i).NoInitRun().
+		Batch(300 * time.Millisecond).Stdout(&bytes.Buffer{}).
+		OnDone(func(r *kit.GuardResult) {
+			lock.Lock()
+			defer lock.Unlock()
+			list = append(list, r.Args)
+		})
+	go guard.MustDo()
+
+	time.Sleep(100 * time.Millisecond)
+	_ = kit.OutputFile(p+"/a", "1", nil)
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.OutputFile(p+"/b", "1", nil)
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	a, b := filepath.Join(p, "a"), filepath.Join(p, "b")
+	assert.Equal(t, [][]string{{"exitexit", a, b, "-" + a + " " + b}}, list)
+}
+
+func TestGuardXargs(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	for _, f := range []string{"a", "b", "c", "d"} {
+		_ = kit.OutputFile(p+"/"+f, "", nil)
+	}
+
+	i := 1 * time.Millisecond
+	lock := sync.Mutex{}
+	list := [][]string{}
+
+	guard := kit.Guard("exitexit", "--w").Patterns(p + "/*").Interval(&i).Xargs(2).
+		Batch(300 * time.Millisecond).Stdout(&lockedBuffer{}).
+		OnDone(func(r *kit.GuardResult) {
+			lock.Lock()
+			defer lock.Unlock()
+			list = append(list, r.Args)
+		})
+	go guard.MustDo()
+
+	time.Sleep(100 * time.Millisecond)
+	_ = kit.OutputFile(p+"/a", "1", nil)
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.OutputFile(p+"/b", "1", nil)
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.Remove(p + "/d")
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.OutputFile(p+"/c", "1", nil)
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// the initial run is skipped, the removed d is dropped, and the failed chunk skips the c
+	a, b := filepath.Join(p, "a"), filepath.Join(p, "b")
+	assert.Equal(t, [][]string{{"exitexit", "--w", a, b}}, list)
+}
+
+func TestGuardMaxFiles(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	for _, f := range []string{"a", "b", "c"} {
+		_ = kit.OutputFile(p+"/"+f, "", nil)
+	}
+
+	i := 1 * time.Millisecond
+	var limitErr *kit.GuardMaxFilesError
+
+	guard := kit.Guard().Patterns(p+"/*").Interval(&i).Stdout(&lockedBuffer{}).
+		MaxFiles(2, func(err *kit.GuardMaxFilesError) error {
+			limitErr = err
+			return nil
+		})
+	events := guard.Events()
+	go guard.MustDo()
+
+	wait()
+	_ = kit.OutputFile(p+"/b", "1", nil)
+
+	// the change is still detected via the dir
+	assert.Equal(t, filepath.Join(p, "b"), (<-events).Path)
+	go guard.Stop()
+	for range events {
+	}
+
+	assert.Equal(t, &kit.GuardMaxFilesError{Max: 2, Files: 3}, limitErr)
+
+	err := kit.Guard().Patterns(p+"/*").Stdout(&lockedBuffer{}).
+		MaxFiles(2, func(err *kit.GuardMaxFilesError) error { return err }).Do()
+	assert.EqualError(t, err, "the patterns matched 3 files, more than the max 2")
+}
+
+func TestGuardGoWork(t *testing.T) {
+	t.Setenv("GOWORK", "")
+
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/go.work", "go 1.18\n\nuse (\n\t./main\n\t./dep // the dependency\n)\n", nil)
+	_ = kit.OutputFile(p+"/main/a.txt", "", nil)
+	_ = kit.OutputFile(p+"/dep/b.txt", "", nil)
+	_ = kit.OutputFile(p+"/dep/c.txt", "", nil)
+	_ = kit.OutputFile(p+"/dep/.gitignore", "c.txt", nil)
+
+	i := 1 * time.Millisecond
+	ready := make(chan []string, 1)
+	guard := kit.Guard().Dir(p+"/main").Patterns("**/*.txt", kit.WalkGitIgnore).Interval(&i).
+		Stdout(&lockedBuffer{}).OnWatchReady(func(files []string) { ready <- files })
+	events := guard.Events()
+	go guard.MustDo()
+
+	files := <-ready
+	assert.Contains(t, files, kit.MustAbs(p+"/dep/b.txt"))
+	assert.NotContains(t, files, kit.MustAbs(p+"/dep/c.txt"))
+
+	wait()
+	_ = kit.OutputFile(p+"/dep/b.txt", "1", nil)
+
+	assert.Equal(t, filepath.Join("..", "dep", "b.txt"), (<-events).Path)
+	go guard.Stop()
+	for range events {
+	}
+
+	guard = kit.Guard().Dir(p + "/main").Patterns("**/*.txt").Interval(&i).
+		Stdout(&lockedBuffer{}).NoGoWork().OnWatchReady(func(files []string) { ready <- files })
+	go guard.MustDo()
+	assert.Equal(t, []string{kit.MustAbs(p + "/main/a.txt")}, <-ready)
+	guard.Stop()
+}
+
+func TestGuardLiveReload(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/a.css", "", nil)
+	_ = kit.OutputFile(p+"/a.txt", "", nil)
+
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	i := 1 * time.Millisecond
+	var runs int32
+	guard := kit.Guard().Patterns(p + "/*").Interval(&i).Stdout(&lockedBuffer{}).LiveReload(addr).
+		Runner(func(e *kit.GuardEvent) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		})
+	go guard.MustDo()
+	defer guard.Stop()
+
+	wait()
+
+	assert.Contains(t, kit.Req("http://"+addr+"/livereload.js").MustString(), "EventSource")
+
+	res := kit.Req("http://" + addr + "/events").MustResponse()
+	defer func() { _ = res.Body.Close() }()
+	msgs := make(chan string, 100)
+	go func() {
+		r := bufio.NewReader(res.Body)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(line, "data: ") {
+				msgs <- strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+			}
+		}
+	}()
+
+	_ = kit.OutputFile(p+"/a.css", "a {}", nil)
+	assert.Equal(t, `{"type":"css","path":"`+filepath.Join(p, "a.css")+`"}`, <-msgs)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&runs))
+
+	_ = kit.OutputFile(p+"/a.txt", "1", nil)
+	for msg := range msgs {
+		if msg == `{"type":"reload"}` {
+			break
+		}
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestGuardDryRun(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/a", "", nil)
+	_ = kit.OutputFile(p+"/b", "", nil)
+
+	i := 1 * time.Millisecond
+	out := &lockedBuffer{}
+	var runs int32
+	guard := kit.Guard("exitexit", "{{op}}", "a b").Patterns(p+"/*").Interval(&i).Stdout(out).
+		Steps(kit.Exec("exitexit", "step"), kit.Exec("exitexit", "{{op}}", "a b")).
+		Xargs(1).Batch(100 * time.Millisecond).NoInitRun().DryRun().
+		OnAfterRun(func(error) { atomic.AddInt32(&runs, 1) })
+	go guard.MustDo()
+
+	wait()
+	_ = kit.OutputFile(p+"/a", "1", nil)
+	_ = kit.OutputFile(p+"/b", "1", nil)
+	wait()
+	guard.Stop()
+
+	s := kit.StripANSI(out.String())
+	assert.Contains(t, s, "dry-run")
+	assert.Contains(t, s, kit.QuoteArgs([]string{"exitexit", "step"}))
+	assert.Contains(t, s, kit.QuoteArgs([]string{"exitexit", "WRITE", "a b", filepath.Join(p, "a")}))
+	assert.Contains(t, s, kit.QuoteArgs([]string{"exitexit", "WRITE", "a b", filepath.Join(p, "b")}))
+	assert.EqualValues(t, 0, atomic.LoadInt32(&runs))
+}
+
+func TestGuardEvents(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "", nil)
+
+	i := 1 * time.Millisecond
+	guard := kit.Guard().Patterns(p+"/**", "!**/*.log").Interval(&i).Stdout(&bytes.Buffer{})
+	events := guard.Events()
+	go guard.MustDo()
+
+	time.Sleep(100 * time.Millisecond)
+	_ = kit.OutputFile(p+"/a.log", "", nil)
+	_ = kit.OutputFile(p+"/f", "1", nil)
+
+	e := <-events
+	assert.Equal(t, filepath.Join(p, "f"), e.Path)
+	assert.Equal(t, "WRITE", e.Op.String())
+	assert.Equal(t, filepath.FromSlash(p+"/**"), e.Pattern)
+	assert.WithinDuration(t, time.Now(), e.Time, time.Second)
+
+	guard.Stop()
+	for range events {
+	}
+	assert.Equal(t, 0, guard.Summary().Runs)
+}
+
+func TestGuardFollowSymlinks(t *testing.T) {
+	root := "tmp/" + kit.RandString(10)
+	p := root + "/p"
+	_ = kit.OutputFile(root+"/ext/sub/f", "", nil)
+	_ = kit.OutputFile(p+"/a", "", nil)
+	ext, _ := filepath.Abs(root + "/ext")
+	if err := os.Symlink(ext, p+"/link"); err != nil {
+		t.Skip("symlink not supported", err)
+	}
+
+	i := 1 * time.Millisecond
+	guard := kit.Guard().Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).FollowSymlinks()
+	events := guard.Events()
+	go guard.MustDo()
+
+	time.Sleep(100 * time.Millisecond)
+	_ = kit.OutputFile(root+"/ext/sub/f", "1", nil)
+
+	timeout := time.After(3 * time.Second)
+	for found := false; !found; {
+		select {
+		case e := <-events:
+			found = e.Path == filepath.Join(p, "link", "sub", "f")
+		case <-timeout:
+			t.Fatal("no event from the file in the linked dir")
+		}
+	}
+
+	guard.Stop()
+	for range events {
+	}
+}
+
+func TestGuardStartupGrace(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	i := 1 * time.Millisecond
+	d := 0 * time.Millisecond
+
+	buf := &bytes.Buffer{}
+	guard := kit.Guard("go", "version").Patterns(p + "/**").Interval(&i).Debounce(&d).
+		StartupGrace(time.Second).Stdout(buf)
+	go guard.MustDo()
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(100 * time.Millisecond)
+			_ = kit.OutputFile(p+"/f", kit.RandString(5), nil)
+		}
+	}()
+
+	time.Sleep(1500 * time.Millisecond)
+
+	guard.Stop()
+
+	assert.Equal(t, 2, guard.Summary().Runs)
+	assert.Contains(t, buf.String(), "queued")
+}
+
+func TestGuardNoKill(t *testing.T) {
+	buf := &bytes.Buffer{}
+	guard := kit.Guard("go", "run", "./fixtures/sleep").Patterns("a").NoInitRun().NoKill(2).Stdout(buf)
+	go guard.MustDo()
+
+	wait()
+
+	for i := 0; i < 3; i++ {
+		guard.Restart()
+	}
+
+	time.Sleep(2 * time.Second)
+
+	guard.Stop()
+
+	assert.True(t, guard.Summary().Running)
+	assert.Equal(t, 0, guard.Summary().Runs)
+	assert.Equal(t, 2, strings.Count(buf.String(), " run "))
+}
+
+func TestGuardContext(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	guard := kit.Guard("go", "run", "./fixtures/sleep").Patterns("a").Context(c).Stdout(&bytes.Buffer{}).
+		OnAfterRun(func(err error) { errs <- err })
+
+	done := make(chan error)
+	go func() { done <- guard.Do() }()
+
+	time.Sleep(time.Second)
+	start := time.Now()
+	cancel()
+
+	assert.Nil(t, <-done)
+	assert.Error(t, <-errs)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// the canceled context returns immediately
+	assert.Nil(t, kit.Guard("go", "version").Context(c).Do())
+}
+
+func TestGuardRetry(t *testing.T) {
+	bin := "tmp/" + kit.RandString(10) + "/sleep"
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	kit.Exec("go", "build", "-o", bin, "./fixtures/sleep").MustDo()
+
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 10 * time.Millisecond
+	errs := make(chan error, 10)
+
+	guard := kit.Guard(bin).Patterns(p + "/**").Interval(&i).Stdout(&lockedBuffer{}).
+		ExecCtx(kit.Exec().Retry(3, 10*time.Millisecond)).
+		OnAfterRun(func(err error) { errs <- err })
+	go guard.MustDo()
+
+	wait()
+
+	// the command stopped by the rerun won't be retried, or guard will wait for it forever
+	_ = kit.OutputFile(p+"/f", "b", nil)
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stopped command is retried")
+	}
+
+	guard.Stop()
+}
+
+func TestGuardSummaryTail(t *testing.T) {
+	guard := kit.Guard("go", "version").Patterns("a").ExecCtx(kit.Exec().Tail(1).Stdout(&bytes.Buffer{}))
+	go guard.MustDo()
+
+	wait()
+
+	guard.Stop()
+
+	s := guard.Summary()
+	assert.Regexp(t, `^go version`, s.LastOutput)
+	assert.Contains(t, s.String(), "last output:\n  go version")
+}
+
+func TestGuardRunner(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "ok", nil)
+
+	d := 0 * time.Millisecond
+	i := 1 * time.Millisecond
+
+	lock := sync.Mutex{}
+	paths := []string{}
+
+	guard := kit.Guard().
+		Patterns(p + "/**").
+		Debounce(&d).
+		Interval(&i).
+		Stdout(&bytes.Buffer{}).
+		Runner(func(e *kit.GuardEvent) error {
+			lock.Lock()
+			defer lock.Unlock()
+			if e == nil {
+				paths = append(paths, "")
+			} else {
+				paths = append(paths, filepath.Base(e.Path))
+			}
+			return errors.New("err")
+		})
+
+	go guard.MustDo()
+
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.OutputFile(p+"/f", "changed", nil)
+
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, "", paths[0])
+	assert.Contains(t, paths[1:], "f")
+	assert.Equal(t, len(paths), guard.Summary().Failures)
+}
+
+func TestGuardLimiter(t *testing.T) {
+	lock := sync.Mutex{}
+	list := []string{}
+	limiter := kit.NewGuardLimiter(1)
+
+	newGuard := func(name string, priority int) *kit.GuardContext {
+		return kit.Guard().Patterns("a").Stdout(&bytes.Buffer{}).Limiter(limiter, priority).
+			Runner(func(e *kit.GuardEvent) error {
+				time.Sleep(100 * time.Millisecond)
+				lock.Lock()
+				defer lock.Unlock()
+				list = append(list, name)
+				return nil
+			})
+	}
+
+	first := newGuard("first", 0)
+	go first.MustDo()
+	time.Sleep(10 * time.Millisecond)
+
+	low := newGuard("low", 1)
+	go low.MustDo()
+	time.Sleep(10 * time.Millisecond)
+
+	high := newGuard("high", 2)
+	go high.MustDo()
+
+	wait()
+
+	first.Stop()
+	low.Stop()
+	high.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"first", "high", "low"}, list)
+}
+
+func TestGuardMaxFailures(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lock := sync.Mutex{}
+	count := 0
+
+	guard := kit.Guard().Patterns("a").Stdout(buf).Every(50 * time.Millisecond).MaxFailures(2).
+		Runner(func(e *kit.GuardEvent) error {
+			lock.Lock()
+			defer lock.Unlock()
+			count++
+			return errors.New("err")
+		})
+	go guard.MustDo()
+
+	wait()
+
+	lock.Lock()
+	assert.Equal(t, 2, count)
+	lock.Unlock()
+	assert.True(t, guard.Summary().Paused)
+
+	guard.Restart()
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 4, count)
+	assert.Equal(t, 2, strings.Count(buf.String(), "paused after 2 consecutive failures"))
+}
+
+func TestGuardMaxFailuresLastOutput(t *testing.T) {
+	buf := &lockedBuffer{}
+	guard := kit.Guard("go", "env", "-unknown-flag").Patterns("a").Stdout(buf).MaxFailures(1).
+		ExecCtx(kit.Exec().Tail(5))
+	go guard.MustDo()
+
+	wait()
+
+	guard.Stop()
+
+	// the output of the run that trips the MaxFailures
+	assert.Regexp(t, `last output:\n.*unknown-flag`, buf.String())
+}
+
+func TestGuardFlaky(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 1 * time.Millisecond
+	lock := sync.Mutex{}
+	count := 0
+
+	guard := kit.Guard().Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).
+		Runner(func(e *kit.GuardEvent) error {
+			lock.Lock()
+			defer lock.Unlock()
+			count++
+			if count%2 == 1 {
+				return errors.New("err")
+			}
+			return nil
+		})
+	go guard.MustDo()
+
+	wait()
+	guard.Restart() // passes without any change
+	wait()
+	guard.Restart()
+	wait()
+	_ = kit.OutputFile(p+"/f", "b", nil) // passes with a change
+	wait()
+
+	guard.Stop()
+
+	s := guard.Summary()
+	assert.Equal(t, 4, s.Runs)
+	assert.Equal(t, 1, s.Flaky)
+	assert.Contains(t, s.String(), "flaky: 1")
+}
+
+func TestGuardPreStep(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/go.mod", "a", nil)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	d := 0 * time.Millisecond
+	i := 1 * time.Millisecond
+
+	lock := sync.Mutex{}
+	count := 0
+	runner := func(e *kit.GuardEvent) error {
+		lock.Lock()
+		defer lock.Unlock()
+		count++
+		return nil
+	}
+
+	guard := kit.Guard().Patterns(p+"/**").Debounce(&d).Interval(&i).Stdout(&bytes.Buffer{}).
+		PreStep([]string{"go", "version"}, p+"/go.mod").
+		PreStep([]string{"exitexit"}, p+"/f").
+		Runner(runner)
+	go guard.MustDo()
+
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.OutputFile(p+"/go.mod", "b", nil)
+	wait()
+
+	lock.Lock()
+	ran := count
+	lock.Unlock()
+	assert.Greater(t, ran, 1)
+	assert.Equal(t, 0, guard.Summary().Failures)
+
+	// the failed step blocks the runner
+	_ = kit.OutputFile(p+"/f", "b", nil)
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, ran, count)
+	assert.NotZero(t, guard.Summary().Failures)
+}
+
+func TestGuardSteps(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 1 * time.Millisecond
+
+	steps := func(steps ...*kit.ExecContext) *kit.GuardResult {
+		res := make(chan *kit.GuardResult, 1)
+		guard := kit.Guard().Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).
+			Steps(steps...).
+			OnDone(func(r *kit.GuardResult) { res <- r })
+		go guard.MustDo()
+		defer guard.Stop()
+		return <-res
+	}
+
+	r := steps(kit.Exec("go", "version"), kit.Exec("go", "env", "GOOS").Tail(5))
+	assert.Nil(t, r.Err)
+	assert.Equal(t, []string{"go", "env", "GOOS"}, r.Args)
+	assert.Contains(t, r.Output, runtime.GOOS)
+
+	// the failed step skips the rest
+	r = steps(kit.Exec("exitexit"), kit.Exec("go", "version"), kit.Exec("go", "env", "GOOS").Tail(5))
+	assert.NotNil(t, r.Err)
+	assert.Equal(t, "", r.Output)
+}
+
+func TestGuardRoute(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 1 * time.Millisecond
+	res := make(chan *kit.GuardResult, 3)
+
+	guard := kit.Guard("go", "version").Patterns(p+"/**").Interval(&i).Stdout(&bytes.Buffer{}).
+		Route(p+"/*.proto", kit.Exec("go", "env", "GOOS")).
+		OnDone(func(r *kit.GuardResult) { res <- r })
+	go guard.MustDo()
+	defer guard.Stop()
+
+	assert.Equal(t, []string{"go", "version"}, (<-res).Args)
+
+	_ = kit.OutputFile(p+"/a.proto", "a", nil)
+	assert.Equal(t, []string{"go", "env", "GOOS"}, (<-res).Args)
+
+	_ = kit.OutputFile(p+"/f", "b", nil)
+	assert.Equal(t, []string{"go", "version"}, (<-res).Args)
+}
+
+func TestGuardExplain(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/a.txt", "", nil)
+	_ = kit.OutputFile(p+"/b.log", "", nil)
+	_ = kit.OutputFile(p+"/gen/c.txt", "", nil)
+
+	list := kit.Guard().Dir(p).Patterns("**", "!*.log", "!gen").MustExplain()
+
+	assert.Len(t, list, 3)
+	assert.Equal(t, kit.GuardExplanation{Path: "a.txt", Watched: true, Rule: "**"}, *list[0])
+	assert.Equal(t, kit.GuardExplanation{Path: "b.log", Rule: "!*.log"}, *list[1])
+	assert.Equal(t, kit.GuardExplanation{Path: "gen", IsDir: true, Rule: "!gen"}, *list[2])
+
+	assert.Contains(t, kit.StripANSI(list.String()), "excluded by !gen")
+
+	list = kit.Guard().Dir(p).Patterns("**", "!*.log", "!gen").MustExplain("a.txt", "gen/c.txt", "gen/new.txt", "../x")
+
+	assert.Len(t, list, 4)
+	assert.Equal(t, kit.GuardExplanation{Path: "a.txt", Watched: true, Rule: "**"}, *list[0])
+	assert.Equal(t, kit.GuardExplanation{Path: filepath.FromSlash("gen/c.txt"), Rule: "!gen", Parent: "gen"}, *list[1])
+	assert.Equal(t, kit.GuardExplanation{Path: filepath.FromSlash("gen/new.txt"), Rule: "!gen", Parent: "gen"}, *list[2])
+	assert.Equal(t, kit.GuardExplanation{Path: filepath.FromSlash("../x")}, *list[3])
+
+	out := kit.StripANSI(list.String())
+	assert.Contains(t, out, "the parent dir gen is excluded by !gen")
+	assert.Contains(t, out, "outside of the dir")
+}
+
+func TestGuardArgsTemplate(t *testing.T) {
+	t.Setenv("GUARD_TEST_ENV", "ok")
+
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/a/b.txt", "", nil)
+
+	i := 1 * time.Millisecond
+	res := make(chan *kit.GuardResult, 1)
+
+	guard := kit.Guard("go", "version", "{{dir}}|{{base}}|{{ext}}|{{env.GUARD_TEST_ENV}}|{{env.GUARD_NOPE}}", "{{timestamp}}").
+		Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).NoInitRun().
+		OnDone(func(r *kit.GuardResult) { res <- r })
+	go guard.MustDo()
+	defer guard.Stop()
+
+	wait()
+	_ = kit.OutputFile(p+"/a/b.txt", "ok", nil)
+
+	r := <-res
+	assert.Equal(t, filepath.FromSlash(p+"/a")+"|b.txt|.txt|ok|", r.Args[2])
+	assert.Regexp(t, `^\d{8}-\d{6}$`, r.Args[3])
+}
+
+func TestGuardRunDir(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 1 * time.Millisecond
+	res := make(chan *kit.GuardResult, 1)
+
+	// "go version" fails if the dir doesn't exist
+	guard := kit.Guard("go", "version", "{{runDir}}").Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).
+		OnDone(func(r *kit.GuardResult) { res <- r })
+	go guard.MustDo()
+	defer guard.Stop()
+
+	r := <-res
+	assert.Nil(t, r.Err)
+	assert.NotEmpty(t, r.Args[2])
+
+	wait()
+	assert.False(t, kit.Exists(r.Args[2]))
+}
+
+func TestGuardRestartOnExit(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 1 * time.Millisecond
+
+	guard := kit.Guard("go", "version").Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).
+		RestartOnExit(10 * time.Millisecond)
+	go guard.MustDo()
+
+	time.Sleep(time.Second)
+	guard.Stop()
+
+	assert.Greater(t, guard.Summary().Runs, 2)
+}
+
+func TestGuardExecDelay(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/f", "a", nil)
+
+	i := 1 * time.Millisecond
+	start := time.Now()
+	times := make(chan time.Duration, 2)
+
+	newGuard := func() *kit.GuardContext {
+		return kit.Guard().Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).
+			Runner(func(*kit.GuardEvent) error {
+				times <- time.Since(start)
+				return nil
+			})
+	}
+
+	db := newGuard().ExecDelay(100 * time.Millisecond)
+	api := newGuard().ExecDelay(50 * time.Millisecond).After(db)
+
+	go api.MustDo()
+	go db.MustDo()
+	defer api.Stop()
+	defer db.Stop()
+
+	dbStarted, apiStarted := <-times, <-times
+	assert.GreaterOrEqual(t, dbStarted, 100*time.Millisecond)
+	assert.GreaterOrEqual(t, apiStarted-dbStarted, 50*time.Millisecond)
+}
+
+func TestGuardQueue(t *testing.T) {
+	i := 1 * time.Millisecond
+
+	runs := func(policy kit.GuardQueuePolicy) int32 {
+		p := "tmp/" + kit.RandString(10)
+		_ = kit.OutputFile(p+"/f", "a", nil)
+
+		var count int32
+		release := make(chan int)
+		guard := kit.Guard().Patterns(p + "/**").Interval(&i).Stdout(&bytes.Buffer{}).Queue(policy).
+			Runner(func(*kit.GuardEvent) error {
+				if atomic.AddInt32(&count, 1) == 1 {
+					<-release
+				}
+				return nil
+			})
+		go guard.MustDo()
+		defer guard.Stop()
+
+		time.Sleep(100 * time.Millisecond)
+		_ = kit.OutputFile(p+"/f", "b", nil)
+		time.Sleep(100 * time.Millisecond)
+		_ = kit.OutputFile(p+"/g", "b", nil)
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		time.Sleep(100 * time.Millisecond)
+
+		return atomic.LoadInt32(&count)
+	}
+
+	assert.EqualValues(t, 2, runs(kit.GuardQueueOne))
+	assert.EqualValues(t, 1, runs(kit.GuardQueueDrop))
+}
+
+func TestGuardIgnoreFile(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	_ = kit.OutputFile(p+"/"+kit.GuardIgnoreFile, "ignored\n", nil)
+	_ = kit.OutputFile(p+"/ignored", "a", nil)
+
+	i := 1 * time.Millisecond
+
+	lock := sync.Mutex{}
+	count := 0
+
+	guard := kit.Guard().Dir(p).Patterns("**").Interval(&i).Stdout(&bytes.Buffer{}).
+		Runner(func(e *kit.GuardEvent) error {
+			lock.Lock()
+			defer lock.Unlock()
+			count++
+			return nil
+		})
+	go guard.MustDo()
+
+	time.Sleep(50 * time.Millisecond)
+	_ = kit.OutputFile(p+"/ignored", "b", nil)
+	wait()
+
+	guard.Stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, 1, count)
+}
+
+func TestGuardReloadMidCycle(t *testing.T) {
+	p := "tmp/" + kit.RandString(10)
+	for i := 0; i < 20; i++ {
+		_ = kit.OutputFile(fmt.Sprintf("%s/%d", p, i), "a", nil)
+	}
+	_ = kit.OutputFile(p+"/"+kit.GuardIgnoreFile, "", nil)
+
+	i := 100 * time.Millisecond
+
+	guard := kit.Guard().Dir(p).Patterns("**").Interval(&i).Stdout(&lockedBuffer{}).
+		Runner(func(*kit.GuardEvent) error { return nil })
+	go guard.MustDo()
+
+	wait()
+
+	// the ignore file reloads the watcher while the other events of the same cycle are pending
+	for i := 0; i < 20; i++ {
+		_ = kit.OutputFile(fmt.Sprintf("%s/%d", p, i), "b", nil)
+	}
+	_ = kit.OutputFile(p+"/"+kit.GuardIgnoreFile, "none\n", nil)
+
+	wait()
+
+	stopped := make(chan struct{})
+	go func() {
+		guard.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(3 * time.Second):
+		t.Fatal("the watcher is deadlocked")
+	}
+}
+
+type lockedBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestGuardJSONLog(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	out := &lockedBuffer{}
+	ran := make(chan kit.Nil, 1)
+
+	guard := kit.Guard().Patterns("a").Context(c).Stdout(out).JSONLog().
+		Runner(func(e *kit.GuardEvent) error {
+			ran <- kit.Nil{}
+			return errors.New("err")
+		})
+
+	done := make(chan error)
+	go func() { done <- guard.Do() }()
+
+	<-ran
+	wait()
+	cancel()
+	assert.Nil(t, <-done)
+
+	types := map[string]map[string]interface{}{}
+	for _, l := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var m map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(l), &m), l)
+		assert.NotEmpty(t, m["time"])
+		types[m["type"].(string)] = m
+	}
+
+	assert.Equal(t, true, types["run"]["runner"])
+	assert.Equal(t, "err", types["done"]["error"])
+	assert.Equal(t, types["run"]["id"], types["done"]["id"])
+	assert.Contains(t, types, "log")
+}
@@ -0,0 +1,40 @@
+// +build !windows
+
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardKillTimeout(t *testing.T) {
+	// the summary only has the output of the finished runs, so the output of the running one is piped
+	r, w := io.Pipe()
+	execCtx := Exec().Stdout(w)
+	guard := Guard("sh", "-c", `trap "" INT; echo ready; while true; do sleep 0.1; done`).
+		Patterns("a").ExecCtx(execCtx).Stdout(&bytes.Buffer{}).
+		StopSignal(os.Interrupt).KillTimeout(300 * time.Millisecond)
+	go guard.MustDo()
+
+	line, _ := bufio.NewReader(r).ReadString('\n')
+	assert.Equal(t, "ready", strings.TrimSpace(line))
+	go func() { _, _ = io.Copy(io.Discard, r) }()
+
+	start := time.Now()
+	guard.Restart()
+
+	for guard.Summary().Runs < 1 {
+		time.Sleep(50 * time.Millisecond)
+	}
+	guard.Stop()
+
+	assert.Greater(t, time.Since(start), 300*time.Millisecond)
+	assert.Equal(t, 0, guard.Summary().Failures)
+}
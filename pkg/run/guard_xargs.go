@@ -0,0 +1,68 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/radovskyb/watcher"
+	"github.com/ysmood/kit/pkg/os"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// Xargs appends the relative paths of the changed files to the args like xargs, such as
+// Guard("prettier", "--write").Xargs(0) formats exactly the files that changed. The removed files are dropped,
+// and a run without any changed file is skipped, such as the initial run. If max > 0, the command runs once
+// for every max paths in order, a failed one skips the rest. Use it with Batch to collect the files saved together.
+func (ctx *GuardContext) Xargs(max int) *GuardContext {
+	ctx.xargs = true
+	ctx.xargsMax = max
+	return ctx
+}
+
+// the changed files that still exist, split into chunks of ctx.xargsMax
+func (ctx *GuardContext) xargsChunks(e *watcher.Event, paths []string) [][]string {
+	if paths == nil && e != nil {
+		paths = []string{ctx.relPath(e.Path)}
+	}
+
+	list := []string{}
+	dict := map[string]utils.Nil{}
+	for _, p := range paths {
+		if _, has := dict[p]; has || !os.FileExists(filepath.Join(ctx.dir, p)) {
+			continue
+		}
+		dict[p] = utils.Nil{}
+		list = append(list, p)
+	}
+
+	chunks := [][]string{}
+	for len(list) > 0 {
+		n := len(list)
+		if ctx.xargsMax > 0 && n > ctx.xargsMax {
+			n = ctx.xargsMax
+		}
+		chunks = append(chunks, list[:n])
+		list = list[n:]
+	}
+	return chunks
+}
+
+// run the chunks before the last one like the Steps, returns the args for the last chunk or the failed one
+func (ctx *GuardContext) runXargs(c context.Context, id string, execCtx *ExecContext, args []string, chunks [][]string) ([]string, error) {
+	withPaths := func(chunk []string) []string {
+		return append(append([]string{}, args...), chunk...)
+	}
+
+	for i, chunk := range chunks[:len(chunks)-1] {
+		a := withPaths(chunk)
+		ctx.log("xargs", id, fmt.Sprintf("%d/%d", i+1, len(chunks)), utils.C(ctx.formatArgs(a), "green"))
+
+		s := *execCtx
+		if err := s.Context(c).Dir(ctx.dir).Args(a).Do(); err != nil {
+			return a, err
+		}
+	}
+
+	return withPaths(chunks[len(chunks)-1]), nil
+}
@@ -0,0 +1,157 @@
+package run
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+// the max size of a line to hold, longer line will be written in parts
+const maxLineSize = 64 * 1024
+
+// all the line writers share the same lock, so the lines won't interleave
+var outputLock = sync.Mutex{}
+var lastOutput interface{}
+
+type lineWriter struct {
+	out    io.Writer
+	prefix []byte
+	title  string
+	pane   interface{} // the writers of the same pane don't print the separator between them, the default is the writer
+	buf    []byte
+}
+
+// if title is not empty, a separator will be written when the output switches to this writer
+func newLineWriter(out io.Writer, prefix, title string) *lineWriter {
+	return &lineWriter{
+		out:    out,
+		prefix: []byte(prefix),
+		title:  title,
+	}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	i := bytes.LastIndexByte(w.buf, '\n')
+	if i == -1 {
+		if len(w.buf) < maxLineSize {
+			return len(p), nil
+		}
+		i = len(w.buf) - 1
+	}
+
+	w.flush(w.buf[:i+1])
+	w.buf = append([]byte{}, w.buf[i+1:]...)
+
+	return len(p), nil
+}
+
+// Close flushes the incomplete line
+func (w *lineWriter) Close() {
+	if len(w.buf) > 0 {
+		w.flush(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) flush(data []byte) {
+	out := []byte{}
+	for _, line := range bytes.SplitAfter(data, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		out = append(out, w.prefix...)
+		out = append(out, line...)
+	}
+
+	outputLock.Lock()
+	defer outputLock.Unlock()
+
+	var pane interface{} = w
+	if w.pane != nil {
+		pane = w.pane
+	}
+
+	if w.title != "" && lastOutput != pane {
+		_, _ = w.out.Write([]byte(utils.C("──── "+w.title+" ────", "cyan") + "\n"))
+	}
+	lastOutput = pane
+
+	_, _ = w.out.Write(out)
+}
+
+// lockedWriter shares the lock with the line writers
+type lockedWriter struct {
+	w io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	return w.w.Write(p)
+}
+
+// tailWriter keeps the last n lines written to it
+type tailWriter struct {
+	lock  sync.Mutex
+	n     int
+	lines []string
+	last  []byte // the incomplete line
+}
+
+func newTailWriter(n int) *tailWriter {
+	return &tailWriter{n: n}
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.last = append(w.last, p...)
+
+	for {
+		i := bytes.IndexByte(w.last, '\n')
+		if i == -1 {
+			break
+		}
+		w.lines = append(w.lines, string(bytes.TrimSuffix(w.last[:i], []byte{'\r'})))
+		w.last = w.last[i+1:]
+	}
+
+	if len(w.lines) > w.n {
+		w.lines = append([]string{}, w.lines[len(w.lines)-w.n:]...)
+	}
+
+	if len(w.last) > maxLineSize {
+		w.last = w.last[len(w.last)-maxLineSize:]
+	}
+
+	return len(p), nil
+}
+
+func (w *tailWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	lines := append([]string{}, w.lines...)
+	if len(w.last) > 0 && w.n > 0 {
+		lines = append(lines, string(w.last))
+		if len(lines) > w.n {
+			lines = lines[1:]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (w *tailWriter) reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lines = nil
+	w.last = nil
+}
@@ -0,0 +1,74 @@
+package run
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit/pkg/utils"
+)
+
+func TestLineWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	old := utils.Stdout
+	utils.Stdout = buf
+	defer func() { utils.Stdout = old }()
+
+	a := newLineWriter(utils.Stdout, "a | ", "")
+	b := newLineWriter(utils.Stdout, "b | ", "")
+
+	_, _ = a.Write([]byte("1"))
+	_, _ = b.Write([]byte("x\ny"))
+	_, _ = a.Write([]byte("2\n3\n"))
+	a.Close()
+	b.Close()
+
+	assert.Equal(t, "b | x\na | 12\na | 3\nb | y\n", buf.String())
+}
+
+func TestLineWriterLongLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	old := utils.Stdout
+	utils.Stdout = buf
+	defer func() { utils.Stdout = old }()
+
+	w := newLineWriter(utils.Stdout, "", "")
+	_, _ = w.Write([]byte(strings.Repeat("a", maxLineSize)))
+
+	assert.Equal(t, maxLineSize, buf.Len())
+}
+
+func TestLineWriterPane(t *testing.T) {
+	buf := &bytes.Buffer{}
+	old := utils.Stdout
+	utils.Stdout = buf
+	defer func() { utils.Stdout = old }()
+
+	a := newLineWriter(utils.Stdout, "", "a")
+	b := newLineWriter(utils.Stdout, "", "b")
+
+	_, _ = a.Write([]byte("1\n"))
+	_, _ = a.Write([]byte("2\n"))
+	_, _ = b.Write([]byte("3\n"))
+
+	assert.Regexp(t, "──── a ────.*\n1\n2\n.*──── b ────.*\n3\n", buf.String())
+}
+
+func TestPaneTitle(t *testing.T) {
+	assert.Equal(t, "server", Exec().Prefix("server | @red").paneTitle())
+	assert.Equal(t, "go version", Exec("go", "version").paneTitle())
+}
+
+func TestTailWriter(t *testing.T) {
+	w := newTailWriter(2)
+
+	_, _ = w.Write([]byte("a\r\nb\nc"))
+	assert.Equal(t, "b\nc", w.String())
+
+	_, _ = w.Write([]byte("d\ne\n"))
+	assert.Equal(t, "cd\ne", w.String())
+
+	w.reset()
+	assert.Equal(t, "", w.String())
+}
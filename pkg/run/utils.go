@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	os_path "path/filepath"
 	"strings"
+	"sync"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
 	gos "github.com/ysmood/kit/pkg/os"
@@ -14,30 +15,30 @@ import (
 )
 
 var goPathCache string
+var goPathOnce sync.Once
 
 // GoPath gets the current GOPATH properly
 func GoPath() string {
-	if goPathCache != "" {
-		return goPathCache
-	}
-	path, _ := exec.Command("go", "env", "GOPATH").CombinedOutput()
-	goPathCache = strings.TrimSpace(string(path))
+	goPathOnce.Do(func() {
+		path, _ := exec.Command("go", "env", "GOPATH").CombinedOutput()
+		goPathCache = strings.TrimSpace(string(path))
+	})
 	return goPathCache
 }
 
 var goBinCache string
+var goBinOnce sync.Once
 
 // GoBin gets the current GOBIN properly
 func GoBin() string {
-	if goBinCache != "" {
-		return goBinCache
-	}
-	path, _ := exec.Command("go", "env", "GOBIN").CombinedOutput()
-	goBinCache = strings.TrimSpace(string(path))
+	goBinOnce.Do(func() {
+		path, _ := exec.Command("go", "env", "GOBIN").CombinedOutput()
+		goBinCache = strings.TrimSpace(string(path))
 
-	if goBinCache == "" {
-		goBinCache = os_path.Join(GoPath(), "bin")
-	}
+		if goBinCache == "" {
+			goBinCache = os_path.Join(GoPath(), "bin")
+		}
+	})
 	return goBinCache
 }
 
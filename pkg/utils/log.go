@@ -3,15 +3,21 @@ package utils
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"regexp"
 	"runtime"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/k0kubun/pp"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
 	"github.com/mgutz/ansi"
+	"golang.org/x/term"
 )
 
 var goos = runtime.GOOS
@@ -34,29 +40,176 @@ func Sdump(val interface{}) string {
 
 // Log log to stdout with timestamp
 func Log(v ...interface{}) {
+	LogTo(Stdout, v...)
+}
+
+// LogTo log to the writer with timestamp, the routes of LogRoute apply the same as Log
+func LogTo(w io.Writer, v ...interface{}) {
+	w = logWriter(w, v)
 	t := time.Now().Format("[2006-01-02 15:04:05]")
 	v = append([]interface{}{C(t, "7")}, v...)
-	E(fmt.Fprintln(Stdout, v...))
+	E(fmt.Fprintln(w, v...))
 }
 
 // Err log to stderr with timestamp and stack trace
 func Err(v ...interface{}) {
+	w := logWriter(Stderr, v)
 	t := time.Now().Format("[2006-01-02 15:04:05]")
 	v = append(v, "\n"+string(debug.Stack()))
 	v = append([]interface{}{C(t, "7")}, v...)
 
-	E(fmt.Fprintln(Stderr, v...))
+	E(fmt.Fprintln(w, v...))
+}
+
+// LogSilenceEnv the env var to silence the log prefixes, separated by comma,
+// such as KIT_LOG_SILENCE="[guard]"
+const LogSilenceEnv = "KIT_LOG_SILENCE"
+
+type logRoute struct {
+	prefix string
+	writer io.Writer
+}
+
+var logRoutes = []logRoute{}
+var logLock = sync.Mutex{}
+
+var regANSI = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// StripANSI removes the color codes from the string
+func StripANSI(s string) string {
+	return regANSI.ReplaceAllString(s, "")
+}
+
+func init() {
+	if s := os.Getenv(LogSilenceEnv); s != "" {
+		LogSilence(strings.Split(s, ",")...)
+	}
+}
+
+// LogRoute routes the Log and Err whose first value starts with the prefix to the writer,
+// the color of the value is ignored. The longest matched prefix wins.
+// Set the writer to nil to remove the route.
+func LogRoute(prefix string, w io.Writer) {
+	logLock.Lock()
+	defer logLock.Unlock()
+
+	list := []logRoute{}
+	for _, r := range logRoutes {
+		if r.prefix != prefix {
+			list = append(list, r)
+		}
+	}
+	if w != nil {
+		list = append(list, logRoute{prefix, w})
+	}
+	logRoutes = list
+}
+
+// LogSilence discards the Log and Err whose first value starts with any of the prefixes
+func LogSilence(prefixes ...string) {
+	for _, p := range prefixes {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			LogRoute(p, ioutil.Discard)
+		}
+	}
 }
 
-// ClearScreen ...
+func logWriter(w io.Writer, v []interface{}) io.Writer {
+	logLock.Lock()
+	defer logLock.Unlock()
+
+	if len(v) == 0 || len(logRoutes) == 0 {
+		return w
+	}
+
+	first := regANSI.ReplaceAllString(fmt.Sprint(v[0]), "")
+
+	matched := ""
+	for _, r := range logRoutes {
+		if strings.HasPrefix(first, r.prefix) && len(r.prefix) >= len(matched) {
+			matched = r.prefix
+			w = r.writer
+		}
+	}
+
+	return w
+}
+
+// ClearMode how the screen is cleared
+type ClearMode int
+
+const (
+	// ClearWipe wipes the screen and the scrollback
+	ClearWipe ClearMode = iota
+	// ClearScrollback pushes the previous output into the scrollback, then clears the screen
+	ClearScrollback
+	// ClearBanner keeps the previous output, prints a separator banner instead
+	ClearBanner
+)
+
+var clearModeNames = []string{"wipe", "scrollback", "banner"}
+
+// ClearModeNames the names of the clear modes for ParseClearMode
+func ClearModeNames() []string {
+	return append([]string{}, clearModeNames...)
+}
+
+// ParseClearMode parses the name of the clear mode, such as "scrollback"
+func ParseClearMode(name string) (ClearMode, error) {
+	for i, n := range clearModeNames {
+		if n == name {
+			return ClearMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown clear mode %q, should be one of %s", name, strings.Join(clearModeNames, ", "))
+}
+
+func (m ClearMode) String() string {
+	if int(m) < len(clearModeNames) {
+		return clearModeNames[m]
+	}
+	return strconv.Itoa(int(m))
+}
+
+// ClearScreen wipes the screen and the scrollback
 func ClearScreen() error {
-	if goos == "windows" {
-		_, err := os.Stdout.WriteString("\n\n\n\n\n")
-		return err
+	return ClearScreenTo(os.Stdout, ClearWipe)
+}
+
+// ClearScreenTo clears the screen of the terminal that w writes to by the mode.
+// Some terminals move the screen into the scrollback on ED 2, some erase it,
+// so the scrollback mode scrolls the screen up by newlines to be consistent.
+// The legacy Windows console doesn't support ANSI, it only gets the newlines.
+func ClearScreenTo(w io.Writer, mode ClearMode) error {
+	var err error
+
+	switch {
+	case mode == ClearBanner:
+		banner := "──── " + time.Now().Format("15:04:05") + " " + strings.Repeat("─", 40)
+		_, err = fmt.Fprintln(w, C(banner, "cyan"))
+
+	case goos == "windows":
+		_, err = io.WriteString(w, "\n\n\n\n\n")
+
+	case mode == ClearScrollback:
+		_, err = io.WriteString(w, strings.Repeat("\n", terminalRows(w))+"\033[H\033[2J")
+
+	default:
+		_, err = io.WriteString(w, "\033[H\033[2J\033[3J")
 	}
 
-	print("\033[H\033[2J")
-	return nil
+	return err
+}
+
+// the number of rows of the terminal, or a reasonable default if w isn't a terminal
+func terminalRows(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if _, h, err := term.GetSize(int(f.Fd())); err == nil && h > 0 {
+			return h
+		}
+	}
+	return 50
 }
 
 // C color terminal string
@@ -1,8 +1,11 @@
 package utils_test
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/ysmood/kit"
 )
 
@@ -13,3 +16,63 @@ func TestLog(t *testing.T) {
 	kit.Sdump("ok")
 	kit.E(kit.ClearScreen())
 }
+
+func TestClearScreenTo(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	kit.E(kit.ClearScreenTo(buf, kit.ClearWipe))
+	assert.Equal(t, "\033[H\033[2J\033[3J", buf.String())
+
+	buf.Reset()
+	kit.E(kit.ClearScreenTo(buf, kit.ClearScrollback))
+	assert.Equal(t, strings.Repeat("\n", 50)+"\033[H\033[2J", buf.String())
+
+	buf.Reset()
+	kit.E(kit.ClearScreenTo(buf, kit.ClearBanner))
+	assert.Contains(t, buf.String(), "────")
+
+	mode, err := kit.ParseClearMode("scrollback")
+	kit.E(err)
+	assert.Equal(t, kit.ClearScrollback, mode)
+	assert.Equal(t, "scrollback", mode.String())
+
+	_, err = kit.ParseClearMode("x")
+	assert.EqualError(t, err, `unknown clear mode "x", should be one of wipe, scrollback, banner`)
+}
+
+func TestLogRoute(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sub := &bytes.Buffer{}
+	kit.LogRoute("[a]", buf)
+	kit.LogRoute("[a] sub", sub)
+	defer kit.LogRoute("[a]", nil)
+	defer kit.LogRoute("[a] sub", nil)
+
+	kit.Log(kit.C("[a]", "red"), "ok")
+	kit.Err("[a]", "err")
+	kit.Log("[a] sub", "ok")
+	kit.Log("[b]", "ok")
+
+	assert.Regexp(t, `\[a\].+ok`, buf.String())
+	assert.Contains(t, buf.String(), "err")
+	assert.NotContains(t, buf.String(), "sub")
+	assert.Contains(t, sub.String(), "[a] sub ok")
+}
+
+func TestLogSilence(t *testing.T) {
+	buf := &bytes.Buffer{}
+	kit.LogRoute("[a]", buf)
+	kit.LogSilence("[a]", "")
+	defer kit.LogRoute("[a]", nil)
+
+	kit.Log("[a]", "ok")
+
+	// the writer of LogTo is replaced too
+	out := &bytes.Buffer{}
+	kit.LogTo(out, "[a]", "ok")
+	kit.LogTo(out, "[b]", "ok")
+
+	assert.Empty(t, buf.String())
+	assert.NotContains(t, out.String(), "[a]")
+	assert.Contains(t, out.String(), "[b] ok")
+}
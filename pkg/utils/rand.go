@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"sync"
+)
+
+var randLock = sync.Mutex{}
+var randReader io.Reader = rand.Reader
+
+// SeedRand makes the RandBytes, RandString, UUID, and NanoID deterministic by the seed, such as
+// for the snapshot tests. Call the returned func to restore the crypto random source.
+func SeedRand(seed int64) func() {
+	randLock.Lock()
+	defer randLock.Unlock()
+
+	old := randReader
+	randReader = mrand.New(mrand.NewSource(seed))
+
+	return func() {
+		randLock.Lock()
+		defer randLock.Unlock()
+		randReader = old
+	}
+}
+
+// RandBytes generate random bytes with specified byte length
+func RandBytes(len int) []byte {
+	b := make([]byte, len)
+
+	randLock.Lock()
+	defer randLock.Unlock()
+	_, _ = io.ReadFull(randReader, b)
+
+	return b
+}
+
+// RandString generate random string with specified string length
+func RandString(len int) string {
+	b := RandBytes(len)
+	return hex.EncodeToString(b)
+}
+
+// UUID generates a random version 4 UUID, such as "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+func UUID() string {
+	b := RandBytes(16)
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+const nanoIDAlphabet = "useandom-26T198340PX75pxJACKVERYMINDBUSHWOLF_GQZbfghjklqvwyzrict"
+
+// NanoID generates a url-safe random id with 21 chars, it has about the same collision
+// probability as UUID
+func NanoID() string {
+	b := RandBytes(21)
+	for i := range b {
+		b[i] = nanoIDAlphabet[b[i]&63]
+	}
+	return string(b)
+}
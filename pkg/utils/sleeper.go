@@ -106,6 +106,55 @@ func Sleep(seconds float64) {
 	time.Sleep(d)
 }
 
+// SleepContext sleeps for d, it wakes early with the ctx.Err() if the ctx is done.
+// The Sleep can't be canceled, so the loops should use this one.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Every calls fn every d until the ctx is done or fn returns an error, the first call is after d.
+// It returns the error of fn or the ctx.Err().
+func Every(ctx context.Context, d time.Duration, fn func() error) error {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Timeout calls fn with a ctx that is done after d, it returns context.DeadlineExceeded after d even if
+// fn ignores the ctx, the fn keeps running in the background then.
+func Timeout(d time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Retry fn and sleeper until fn returns true or s returns error
 func Retry(ctx context.Context, s Sleeper, fn func() (stop bool, err error)) error {
 	for {
@@ -65,3 +65,40 @@ func TestCountSleeperCancel(t *T) {
 	s := utils.CountSleeper(5)
 	assert.Errorf(t, s(ctx), context.Canceled.Error())
 }
+
+func TestSleepContext(t *T) {
+	assert.Nil(t, utils.SleepContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, utils.SleepContext(ctx, time.Hour))
+}
+
+func TestEvery(t *T) {
+	count := 0
+	err := utils.Every(context.Background(), time.Millisecond, func() error {
+		count++
+		if count == 3 {
+			return io.EOF
+		}
+		return nil
+	})
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 3, count)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, utils.Every(ctx, time.Hour, func() error { return nil }))
+}
+
+func TestTimeout(t *T) {
+	assert.Equal(t, io.EOF, utils.Timeout(time.Hour, func(ctx context.Context) error { return io.EOF }))
+
+	start := time.Now()
+	err := utils.Timeout(10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// the width of the terminal, 0 means no limit
+var consoleWidth = func() int {
+	w, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return w
+}
+
+// Table renders the rows as left aligned columns with the headers in bold, the cells can be colored.
+// The last column will be truncated to fit the width of the terminal.
+func Table(headers []string, rows [][]string) string {
+	all := append([][]string{headers}, rows...)
+
+	widths := []int{}
+	for _, row := range all {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := visibleLen(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	lines := []string{}
+	for r, row := range all {
+		cells := []string{}
+		for i, cell := range row {
+			if i < len(row)-1 {
+				cell += strings.Repeat(" ", widths[i]-visibleLen(cell))
+			}
+			if r == 0 {
+				cell = C(cell, "default+b")
+			}
+			cells = append(cells, cell)
+		}
+		lines = append(lines, fitWidth(strings.Join(cells, "  ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// KV renders the pairs as "key: value" lines with the values aligned, such as KV("runs", 3, "failures", 0)
+func KV(pairs ...interface{}) string {
+	width := 0
+	for i := 0; i < len(pairs); i += 2 {
+		if w := visibleLen(fmt.Sprint(pairs[i])); w > width {
+			width = w
+		}
+	}
+
+	lines := []string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		k := fmt.Sprint(pairs[i])
+		pad := strings.Repeat(" ", width-visibleLen(k))
+		lines = append(lines, fitWidth(C(k+":", "cyan")+pad+" "+fmt.Sprint(pairs[i+1])))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(regANSI.ReplaceAllString(s, ""))
+}
+
+// truncate the line to the width of the terminal, the colors of a truncated line will be removed
+func fitWidth(line string) string {
+	width := consoleWidth()
+	if width <= 0 || visibleLen(line) <= width {
+		return line
+	}
+
+	runes := []rune(regANSI.ReplaceAllString(line, ""))
+	return string(runes[:width-1]) + "…"
+}
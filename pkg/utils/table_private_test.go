@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stripANSI(s string) string {
+	return regANSI.ReplaceAllString(s, "")
+}
+
+func TestTable(t *testing.T) {
+	s := Table([]string{"count", "path"}, [][]string{
+		{"1", C("a.go", "green")},
+		{"123", "dir/b.go"},
+	})
+	assert.Contains(t, s, C("count", "default+b"))
+	assert.Equal(t, "count  path\n1      a.go\n123    dir/b.go", stripANSI(s))
+
+	s = KV("runs", 3, "avg duration", "1s")
+	assert.Equal(t, "runs:         3\navg duration: 1s", stripANSI(s))
+}
+
+func TestTableTruncate(t *testing.T) {
+	old := consoleWidth
+	consoleWidth = func() int { return 8 }
+	defer func() { consoleWidth = old }()
+
+	s := Table([]string{"a", "b"}, [][]string{{"1", C("0123456789", "red")}})
+	assert.Equal(t, "a  b\n1  0123…", stripANSI(s))
+}
@@ -2,8 +2,6 @@ package utils
 
 import (
 	"bytes"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
 	"reflect"
 	"sync"
@@ -118,19 +116,6 @@ func All(actions ...func()) func() {
 	return wg.Wait
 }
 
-// RandBytes generate random bytes with specified byte length
-func RandBytes(len int) []byte {
-	b := make([]byte, len)
-	_, _ = rand.Read(b)
-	return b
-}
-
-// RandString generate random string with specified string length
-func RandString(len int) string {
-	b := RandBytes(len)
-	return hex.EncodeToString(b)
-}
-
 // Try try fn with recover, return the panic as value
 func Try(fn func()) (err interface{}) {
 	defer func() {
@@ -81,6 +81,26 @@ func TestGenerateRandomString(t *T) {
 	assert.Len(t, raw, 10)
 }
 
+func TestUUID(t *T) {
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, kit.UUID())
+	assert.NotEqual(t, kit.UUID(), kit.UUID())
+}
+
+func TestNanoID(t *T) {
+	assert.Regexp(t, `^[\w-]{21}$`, kit.NanoID())
+}
+
+func TestSeedRand(t *T) {
+	restore := kit.SeedRand(1)
+	a := []string{kit.RandString(4), kit.UUID(), kit.NanoID()}
+	restore()
+
+	defer kit.SeedRand(1)()
+	b := []string{kit.RandString(4), kit.UUID(), kit.NanoID()}
+
+	assert.Equal(t, a, b)
+}
+
 func TestSTemplate(t *T) {
 	out := kit.S(
 		"{{.a}} {{.b}} {{.c.A}} {{d}}",
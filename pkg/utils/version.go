@@ -1,4 +1,85 @@
 package utils
 
-// Version version the project
-const Version = "v0.25.12"
+import (
+	"runtime/debug"
+	"strings"
+)
+
+const modulePath = "github.com/ysmood/kit"
+
+// the version when the build info doesn't have one, such as building from a local checkout
+const defaultVersion = "v0.25.12"
+
+// Version version the project, it's read from the build info, so it's accurate when installed via "go install"
+var Version = BuildInfo().Version
+
+// BuildMeta the build metadata of the project
+type BuildMeta struct {
+	Version string
+	// Commit is the vcs revision, it's empty if the binary isn't built from a vcs checkout
+	Commit string
+	// Dirty is true if the checkout has uncommitted changes
+	Dirty bool
+}
+
+// String such as "v0.25.12 (5e4c1d2, dirty)"
+func (b BuildMeta) String() string {
+	extra := []string{}
+	if b.Commit != "" {
+		commit := b.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		extra = append(extra, commit)
+	}
+	if b.Dirty {
+		extra = append(extra, "dirty")
+	}
+
+	if len(extra) == 0 {
+		return b.Version
+	}
+	return b.Version + " (" + strings.Join(extra, ", ") + ")"
+}
+
+// BuildInfo reads the build metadata from runtime/debug
+func BuildInfo() BuildMeta {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return BuildMeta{Version: defaultVersion}
+	}
+	return buildMeta(info)
+}
+
+func buildMeta(info *debug.BuildInfo) BuildMeta {
+	meta := BuildMeta{Version: defaultVersion}
+
+	// kit is used as a lib, the vcs settings belong to the main module
+	if info.Main.Path != modulePath {
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath && validVersion(dep.Version) {
+				meta.Version = dep.Version
+			}
+		}
+		return meta
+	}
+
+	if validVersion(info.Main.Version) {
+		meta.Version = info.Main.Version
+	}
+
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			meta.Commit = s.Value
+		case "vcs.modified":
+			meta.Dirty = s.Value == "true"
+		}
+	}
+
+	return meta
+}
+
+func validVersion(v string) bool {
+	return v != "" && v != "(devel)"
+}
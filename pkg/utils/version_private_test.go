@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMeta(t *testing.T) {
+	meta := buildMeta(&debug.BuildInfo{
+		Main: debug.Module{Path: modulePath, Version: "v1.0.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "5e4c1d2a8b"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	})
+	assert.Equal(t, BuildMeta{"v1.0.0", "5e4c1d2a8b", true}, meta)
+	assert.Equal(t, "v1.0.0 (5e4c1d2, dirty)", meta.String())
+
+	meta = buildMeta(&debug.BuildInfo{
+		Main: debug.Module{Path: modulePath, Version: "(devel)"},
+	})
+	assert.Equal(t, defaultVersion, meta.String())
+
+	meta = buildMeta(&debug.BuildInfo{
+		Main:     debug.Module{Path: "example.com/app"},
+		Deps:     []*debug.Module{{Path: modulePath, Version: "v1.2.0"}},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc"}},
+	})
+	assert.Equal(t, BuildMeta{Version: "v1.2.0"}, meta)
+}